@@ -2,21 +2,33 @@ package main
 
 import (
 	"log"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/mlm-app/backend/internal/config"
 	"github.com/mlm-app/backend/internal/controller"
+	"github.com/mlm-app/backend/internal/domain"
 	"github.com/mlm-app/backend/internal/middleware"
 	"github.com/mlm-app/backend/internal/repository"
 	"github.com/mlm-app/backend/internal/service"
 	"github.com/mlm-app/backend/pkg/database"
+	"github.com/mlm-app/backend/pkg/jwtkeys"
+	"github.com/mlm-app/backend/pkg/mailer"
+	"github.com/mlm-app/backend/pkg/ratelimit"
+	"github.com/mlm-app/backend/pkg/storage"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.Load()
 	
+	// Load token signing keys; this refuses the placeholder secret in release mode
+	keys, err := jwtkeys.Load(cfg)
+	if err != nil {
+		log.Fatal("Failed to load JWT keys:", err)
+	}
+	
 	// Initialize database
 	db, err := database.InitDB(cfg)
 	if err != nil {
@@ -33,30 +45,147 @@ func main() {
 		log.Println("Warning: Failed to seed data:", err)
 	}
 	
+	// Initialize mailer
+	mail, err := mailer.New(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize mailer:", err)
+	}
+	
+	// Initialize file storage for uploaded documents
+	store, err := storage.New(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize storage:", err)
+	}
+	
+	// Login throttling counters; swap in a shared store when running several instances
+	rateStore := ratelimit.NewMemoryStore()
+	
+	// Lets services commit a change and its audit entry together
+	transactor := repository.NewTransactor(db)
+	
 	// Initialize repositories
 	distributorRepo := repository.NewDistributorRepository(db)
-	// orderRepo := repository.NewOrderRepository(db) // TODO: Add order controller
-	// commissionRepo := repository.NewCommissionRepository(db) // TODO: Add commission controller
+	orderRepo := repository.NewOrderRepository(db)
+	commissionRepo := repository.NewCommissionRepository(db)
 	rankRepo := repository.NewRankRepository(db)
+	breakawayRepo := repository.NewBreakawayRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	terminationRepo := repository.NewTerminationRepository(db)
+	snapshotRepo := repository.NewSnapshotRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	accountTokenRepo := repository.NewAccountTokenRepository(db)
+	recoveryCodeRepo := repository.NewRecoveryCodeRepository(db)
+	loginAttemptRepo := repository.NewLoginAttemptRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	productRepo := repository.NewProductRepository(db)
+	statusChangeRepo := repository.NewStatusChangeRepository(db)
+	packageRepo := repository.NewPackageRepository(db)
+	kycRepo := repository.NewKYCRepository(db)
 	
 	// Initialize services
-	treeService := service.NewTreeService(distributorRepo)
-	distributorService := service.NewDistributorService(distributorRepo, rankRepo, treeService)
-	// commissionService := service.NewCommissionService(commissionRepo, distributorRepo, treeService, cfg) // TODO: Add commission controller
+	treeService := service.NewTreeService(distributorRepo, breakawayRepo, auditRepo, transactor, cfg)
+	distributorService := service.NewDistributorService(distributorRepo, rankRepo, terminationRepo, statusChangeRepo, auditRepo, treeService, transactor, cfg)
+	integrityService := service.NewIntegrityService(distributorRepo, auditRepo, treeService, transactor, cfg)
+	exportService := service.NewExportService(distributorRepo, rankRepo)
+	snapshotService := service.NewSnapshotService(snapshotRepo, distributorRepo)
+	authService := service.NewAuthService(distributorRepo, refreshTokenRepo, auditRepo, keys, cfg)
+	accountService := service.NewAccountService(distributorRepo, accountTokenRepo, authService, mail, cfg)
+	loginSecurityService := service.NewLoginSecurityService(rateStore, distributorRepo, loginAttemptRepo, auditRepo, transactor, cfg)
+	twoFactorService := service.NewTwoFactorService(distributorRepo, recoveryCodeRepo, authService, loginSecurityService, rateStore, keys, cfg)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, auditRepo, transactor)
+	auditService := service.NewAuditService(auditRepo)
+	inventoryService := service.NewInventoryService(productRepo)
+	statusService := service.NewStatusService(distributorRepo, statusChangeRepo, auditRepo, transactor, cfg)
+	packageService := service.NewPackageService(packageRepo, orderRepo, distributorRepo, auditRepo, transactor, cfg)
+	commissionService := service.NewCommissionService(commissionRepo, distributorRepo, auditRepo, treeService, transactor, cfg)
+	orderService := service.NewOrderService(orderRepo, auditRepo, packageService, statusService, commissionService, transactor)
+	kycService := service.NewKYCService(kycRepo, distributorRepo, auditRepo, transactor, store, cfg)
 	
 	// Initialize controllers
-	distributorController := controller.NewDistributorController(distributorService, cfg)
+	distributorController := controller.NewDistributorController(distributorService, snapshotService, authService, accountService, loginSecurityService, packageService, treeService, cfg)
+	genealogyController := controller.NewGenealogyController(integrityService, exportService, treeService)
+	snapshotController := controller.NewSnapshotController(snapshotService)
+	authController := controller.NewAuthController(authService, accountService, twoFactorService, keys)
+	apiKeyController := controller.NewAPIKeyController(apiKeyService)
+	orderController := controller.NewOrderController(orderService)
+	inventoryController := controller.NewInventoryController(inventoryService)
+	auditController := controller.NewAuditController(auditService)
+	statusController := controller.NewStatusController(statusService)
+	packageController := controller.NewPackageController(packageService)
+	kycController := controller.NewKYCController(kycService, cfg)
+	commissionController := controller.NewCommissionController(commissionService)
+	
+	// Auto-place holding tank members whose window has expired
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			placed, err := treeService.AutoPlaceExpired()
+			if err != nil {
+				log.Println("Holding tank auto-placement failed:", err)
+			}
+			if placed > 0 {
+				log.Printf("Auto-placed %d holding tank members", placed)
+			}
+			<-ticker.C
+		}
+	}()
+	
+	// Snapshot the genealogy once a commission period has closed
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			if snapshot, err := snapshotService.CloseDuePeriod(); err != nil {
+				log.Println("Period close snapshot failed:", err)
+			} else if snapshot != nil {
+				log.Printf("Snapshotted %d distributors for period %s", snapshot.MemberCount, snapshot.Period)
+			}
+			<-ticker.C
+		}
+	}()
+	
+	// Make distributors without a recent qualifying purchase inactive
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			changed, err := statusService.ApplyInactivity()
+			if err != nil {
+				log.Println("Inactivity check failed:", err)
+			}
+			if changed > 0 {
+				log.Printf("Marked %d distributors inactive", changed)
+			}
+			<-ticker.C
+		}
+	}()
+	
+	// Move members whose package lapsed without renewal to the default package
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			if lapsed, err := packageService.ExpireLapsed(); err != nil {
+				log.Println("Package lapse check failed:", err)
+			} else if lapsed > 0 {
+				log.Printf("Moved %d lapsed members to the default package", lapsed)
+			}
+			<-ticker.C
+		}
+	}()
 	
 	// Setup Gin router
 	gin.SetMode(cfg.Server.GinMode)
 	router := gin.Default()
+	router.Use(middleware.RequestID())
 	
 	// CORS middleware
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     cfg.CORS.Origins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-API-Key", middleware.RequestIDHeader},
+		ExposeHeaders:    []string{"Content-Length", "Retry-After", middleware.RequestIDHeader},
 		AllowCredentials: true,
 	}))
 	
@@ -65,28 +194,123 @@ func main() {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 	
+	// Verification keys for services that accept our access tokens
+	router.GET("/.well-known/jwks.json", authController.JWKS)
+	
 	// API v1 routes
 	v1 := router.Group("/api/v1")
 	{
+		// Per-IP limit shared by every endpoint that checks a credential
+		loginLimit := middleware.RateLimit(rateStore, "login", cfg.Auth.LoginIPLimit, cfg.Auth.LoginIPWindow)
+		
 		// Public routes
 		distributors := v1.Group("/distributors")
 		{
 			distributors.POST("/register", distributorController.Register)
-			distributors.POST("/login", distributorController.Login)
+			distributors.POST("/login", loginLimit, distributorController.Login)
+		}
+		
+		v1.GET("/packages", packageController.ListActive)
+		
+		auth := v1.Group("/auth")
+		{
+			auth.POST("/refresh", authController.Refresh)
+			auth.POST("/logout", authController.Logout)
+			auth.POST("/verify-email", authController.VerifyEmail)
+			auth.POST("/verify-email/resend", loginLimit, authController.ResendVerification)
+			auth.POST("/password/forgot", loginLimit, authController.ForgotPassword)
+			auth.POST("/password/reset", loginLimit, authController.ResetPassword)
+			auth.POST("/2fa/verify", loginLimit, authController.VerifyTwoFactor)
 		}
 		
 		// Protected routes
 		protected := v1.Group("")
-		protected.Use(middleware.AuthMiddleware(cfg))
+		protected.Use(middleware.AuthMiddleware(keys, authService, nil))
 		{
+			protected.POST("/auth/logout-all", authController.LogoutAll)
+			protected.POST("/auth/password/change", authController.ChangePassword)
+			protected.POST("/auth/2fa/setup", authController.SetupTwoFactor)
+			protected.POST("/auth/2fa/enable", authController.EnableTwoFactor)
+			protected.POST("/auth/2fa/disable", authController.DisableTwoFactor)
+			protected.POST("/auth/2fa/recovery-codes", authController.RegenerateRecoveryCodes)
+			
 			// Distributor routes
 			protected.GET("/distributors/profile", distributorController.GetProfile)
 			protected.PUT("/distributors/profile", distributorController.Update)
 			protected.GET("/distributors/:id", distributorController.GetByID)
 			protected.GET("/distributors", distributorController.List)
 			protected.GET("/distributors/:id/downlines", distributorController.GetDownlines)
+			protected.GET("/distributors/:id/downlines/search", genealogyController.Search)
 			protected.GET("/distributors/:id/tree", distributorController.GetTreeStructure)
+			protected.GET("/distributors/:id/stats", distributorController.GetStats)
+			protected.GET("/distributors/:id/children", genealogyController.GetChildren)
+			protected.GET("/distributors/:id/path", genealogyController.GetPath)
+			protected.GET("/distributors/:id/breakaways", distributorController.GetBreakaways)
+			protected.GET("/distributors/:id/export", genealogyController.Export)
 			protected.POST("/distributors/add-member", distributorController.AddMemberToTree)
+			protected.GET("/distributors/holding-tank", distributorController.GetHoldingTank)
+			protected.POST("/distributors/holding-tank/:id/place", distributorController.PlaceFromHoldingTank)
+			
+			// Package routes
+			protected.POST("/packages/:id/purchase", packageController.Purchase)
+			protected.POST("/packages/renew", packageController.Renew)
+			
+			// KYC routes
+			protected.GET("/kyc", kycController.Status)
+			protected.POST("/kyc/documents", kycController.UploadDocument)
+			protected.POST("/kyc/submit", kycController.Submit)
+			
+			// Commission routes
+			protected.GET("/commissions", commissionController.Mine)
+		}
+		
+		// Integration routes, callable with a scoped API key or by an admin
+		integration := v1.Group("")
+		integration.Use(middleware.AuthMiddleware(keys, authService, apiKeyService))
+		{
+			integration.GET("/orders", middleware.RequireScope(domain.ScopeOrdersRead), orderController.List)
+			integration.GET("/orders/:id", middleware.RequireScope(domain.ScopeOrdersRead), orderController.GetByID)
+			integration.POST("/orders/:id/payment", middleware.RequireScope(domain.ScopeOrdersWrite), orderController.RecordPayment)
+			integration.GET("/inventory", middleware.RequireScope(domain.ScopeInventoryRead), inventoryController.List)
+			integration.PUT("/inventory/:sku", middleware.RequireScope(domain.ScopeInventoryWrite), inventoryController.SetStock)
+		}
+		
+		// Admin routes
+		admin := v1.Group("/admin")
+		admin.Use(middleware.AuthMiddleware(keys, authService, nil), middleware.RequireRole("admin"), middleware.RequireTwoFactor())
+		{
+			admin.POST("/distributors/:id/move", distributorController.MoveMember)
+			admin.POST("/distributors/:id/terminate", distributorController.Terminate)
+			admin.POST("/distributors/:id/reinstate", distributorController.Reinstate)
+			admin.POST("/distributors/:id/suspend", statusController.Suspend)
+			admin.POST("/distributors/:id/unsuspend", statusController.Unsuspend)
+			admin.GET("/distributors/:id/status-history", statusController.History)
+			admin.POST("/distributors/:id/unlock", distributorController.Unlock)
+			admin.POST("/distributors/:id/impersonate", distributorController.Impersonate)
+			admin.GET("/login-attempts", distributorController.ListLoginAttempts)
+			admin.GET("/api-keys", apiKeyController.List)
+			admin.POST("/api-keys", apiKeyController.Create)
+			admin.DELETE("/api-keys/:id", apiKeyController.Revoke)
+			admin.GET("/audit-logs", auditController.List)
+			admin.GET("/packages", packageController.List)
+			admin.POST("/packages", packageController.Create)
+			admin.GET("/packages/:id", packageController.GetByID)
+			admin.PUT("/packages/:id", packageController.Update)
+			admin.DELETE("/packages/:id", packageController.Delete)
+			admin.GET("/packages/:id/versions", packageController.Versions)
+			admin.PUT("/packages/:id/active", packageController.SetActive)
+			admin.GET("/kyc", kycController.List)
+			admin.GET("/kyc/:id", kycController.Get)
+			admin.GET("/kyc/:id/documents/:documentId", kycController.Document)
+			admin.POST("/kyc/:id/approve", kycController.Approve)
+			admin.POST("/kyc/:id/reject", kycController.Reject)
+			admin.GET("/commissions", commissionController.List)
+			admin.POST("/commissions/:id/approve", commissionController.Approve)
+			admin.POST("/commissions/:id/pay", commissionController.Pay)
+			admin.GET("/genealogy/check", genealogyController.Check)
+			admin.POST("/genealogy/repair", genealogyController.Repair)
+			admin.GET("/snapshots", snapshotController.List)
+			admin.POST("/snapshots", snapshotController.ClosePeriod)
 		}
 	}
 	
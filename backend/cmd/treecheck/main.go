@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/mlm-app/backend/internal/config"
+	"github.com/mlm-app/backend/internal/domain"
+	"github.com/mlm-app/backend/internal/repository"
+	"github.com/mlm-app/backend/internal/service"
+	"github.com/mlm-app/backend/pkg/database"
+)
+
+// treecheck scans the genealogy for cycles, level mismatches, orphans and
+// position conflicts. With -repair it also fixes the safe ones.
+// It exits with status 1 while unrepaired violations remain.
+func main() {
+	repair := flag.Bool("repair", false, "auto-repair safe violations")
+	asJSON := flag.Bool("json", false, "print the full report as JSON")
+	flag.Parse()
+	
+	// Load configuration
+	cfg := config.Load()
+	
+	// Initialize database
+	db, err := database.InitDB(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize database:", err)
+	}
+	
+	// Initialize repositories and services
+	distributorRepo := repository.NewDistributorRepository(db)
+	breakawayRepo := repository.NewBreakawayRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	transactor := repository.NewTransactor(db)
+	treeService := service.NewTreeService(distributorRepo, breakawayRepo, auditRepo, transactor, cfg)
+	integrityService := service.NewIntegrityService(distributorRepo, auditRepo, treeService, transactor, cfg)
+	
+	var report *domain.IntegrityReport
+	if *repair {
+		// Command-line repairs have no acting admin and are audited as
+		// automatic changes
+		report, err = integrityService.Repair(service.AuditMeta{})
+	} else {
+		report, err = integrityService.Check()
+	}
+	if err != nil {
+		log.Fatal("Genealogy check failed:", err)
+	}
+	
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		fmt.Printf("Scanned %d distributors, found %d violations\n", report.Scanned, len(report.Violations))
+		
+		types := make([]string, 0, len(report.Counts))
+		for violationType := range report.Counts {
+			types = append(types, violationType)
+		}
+		sort.Strings(types)
+		for _, violationType := range types {
+			fmt.Printf("  %-20s %d\n", violationType, report.Counts[violationType])
+		}
+		
+		for _, v := range report.Violations {
+			status := ""
+			if v.Repaired {
+				status = " [repaired]"
+			} else if v.Repairable {
+				status = " [repairable]"
+			}
+			fmt.Printf("%-20s #%-8d %s%s\n", v.Type, v.DistributorID, v.Details, status)
+		}
+	}
+	
+	for _, v := range report.Violations {
+		if !v.Repaired {
+			os.Exit(1)
+		}
+	}
+}
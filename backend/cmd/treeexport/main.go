@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/mlm-app/backend/internal/config"
+	"github.com/mlm-app/backend/internal/repository"
+	"github.com/mlm-app/backend/internal/service"
+	"github.com/mlm-app/backend/pkg/database"
+)
+
+// treeexport writes the full downline under a distributor as CSV, GraphML
+// or Graphviz DOT, to a file or stdout.
+func main() {
+	rootID := flag.Uint("root", 0, "distributor ID at the top of the exported subtree")
+	format := flag.String("format", service.ExportFormatCSV, "export format: csv, graphml or dot")
+	output := flag.String("out", "", "output file (default stdout)")
+	flag.Parse()
+	
+	if *rootID == 0 {
+		log.Fatal("-root is required")
+	}
+	
+	// Load configuration
+	cfg := config.Load()
+	
+	// Initialize database
+	db, err := database.InitDB(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize database:", err)
+	}
+	
+	exportService := service.NewExportService(
+		repository.NewDistributorRepository(db),
+		repository.NewRankRepository(db),
+	)
+	
+	out := os.Stdout
+	if *output != "" {
+		out, err = os.Create(*output)
+		if err != nil {
+			log.Fatal("Failed to create output file:", err)
+		}
+		defer out.Close()
+	}
+	
+	if err := exportService.ExportGenealogy(*rootID, *format, out); err != nil {
+		log.Fatal("Export failed:", err)
+	}
+}
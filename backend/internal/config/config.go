@@ -14,7 +14,11 @@ type Config struct {
 	Server   ServerConfig
 	Database DatabaseConfig
 	JWT      JWTConfig
+	Auth     AuthConfig
+	Mail     MailConfig
 	CORS     CORSConfig
+	Storage  StorageConfig
+	KYC      KYCConfig
 	MLM      MLMConfig
 }
 
@@ -32,14 +36,62 @@ type DatabaseConfig struct {
 }
 
 type JWTConfig struct {
-	Secret string
-	Expiry time.Duration
+	Secret        string        // HS256 fallback used only when no signing key file is set
+	Expiry        time.Duration // Access token lifetime
+	RefreshExpiry time.Duration
+
+	// Asymmetric signing: a PEM RSA (RS256) or Ed25519 (EdDSA) private key
+	// signs new tokens; verification key files keep retired keys valid
+	// during rotation and are published in the JWKS.
+	SigningKeyFile       string
+	VerificationKeyFiles []string
+}
+
+type AuthConfig struct {
+	RequireEmailVerification bool // Refuse logins until the email address is verified
+	EmailVerificationExpiry  time.Duration
+	PasswordResetExpiry      time.Duration
+	TwoFactorIssuer          string // Account name shown in authenticator apps
+	LoginChallengeExpiry     time.Duration
+	ImpersonationExpiry      time.Duration // Lifetime of an admin's login-as-distributor token
+
+	// Login throttling: requests per IP, progressive delays after repeated
+	// failures for one account, and a temporary lockout after LockoutThreshold
+	LoginIPLimit       int
+	LoginIPWindow      time.Duration
+	LoginFailureWindow time.Duration
+	LoginDelayAfter    int
+	LoginMaxDelay      time.Duration
+	LockoutThreshold   int
+	LockoutDuration    time.Duration
+}
+
+type MailConfig struct {
+	Driver       string // smtp, or log to write messages to LogDir for development
+	From         string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	LogDir       string
+	AppURL       string // Base URL of the frontend, used in emailed links
 }
 
 type CORSConfig struct {
 	Origins []string
 }
 
+type StorageConfig struct {
+	Driver    string // local keeps uploaded files under LocalPath
+	LocalPath string
+}
+
+type KYCConfig struct {
+	Required        bool   // Commissions cannot be approved or paid until the earner's KYC is approved
+	MaxDocumentSize int64  // Upload limit per identity document, in bytes
+	TaxIDHashKey    string // Keys the tax ID hash so stored hashes cannot be brute-forced on their own
+}
+
 type MLMConfig struct {
 	DefaultTreeType           string
 	BinaryMaxWidth            int
@@ -48,6 +100,45 @@ type MLMConfig struct {
 	DirectReferralCommission  float64
 	LevelCommissionPercentage float64
 	MaxCommissionLevels       int
+
+	// Compression passes a level commission up past inactive or unqualified
+	// uplines instead of dropping it.
+	CompressionEnabled         bool
+	MinQualifyingPersonalSales float64
+
+	// Generation bonuses pay each generation of uplines holding at least
+	// GenerationRankLevel, with one percentage per generation.
+	GenerationBonusEnabled     bool
+	GenerationRankLevel        int
+	GenerationBonusPercentages []float64
+
+	// Breakaway plans split a downline's group off from the sponsor's once
+	// they reach BreakawayRankLevel; the sponsor then earns an override on
+	// each broken-away generation instead of level commission.
+	BreakawayRankLevel           int
+	BreakawayOverridePercentages []float64
+
+	// Terminated distributors' children roll up to the next active upline,
+	// or to the house account when TerminationRollUpToHouse is set or no
+	// active upline exists. Terminations can be reversed within the grace
+	// period.
+	HouseAccountID           uint
+	TerminationRollUpToHouse bool
+	TerminationGracePeriod   time.Duration
+
+	// New binary, matrix and hybrid enrollees wait this many days in their
+	// sponsor's holding tank before being auto-placed; 0 places immediately
+	HoldingTankDays int
+
+	// Active distributors without a purchase of at least
+	// QualifyingPurchaseVolume for InactivityDays become inactive; their
+	// next qualifying purchase reactivates them. 0 days disables the rule.
+	InactivityDays           int
+	QualifyingPurchaseVolume float64
+
+	// New distributors and members whose package lapses without renewal get
+	// DefaultPackageID; 0 leaves them without a package
+	DefaultPackageID uint
 }
 
 func Load() *Config {
@@ -56,9 +147,9 @@ func Load() *Config {
 		log.Println("No .env file found, using environment variables")
 	}
 
-	jwtExpiry, err := time.ParseDuration(getEnv("JWT_EXPIRY", "24h"))
+	jwtExpiry, err := time.ParseDuration(getEnv("JWT_EXPIRY", "15m"))
 	if err != nil {
-		jwtExpiry = 24 * time.Hour
+		jwtExpiry = 15 * time.Minute
 	}
 
 	return &Config{
@@ -74,12 +165,51 @@ func Load() *Config {
 			DBName:   getEnv("DB_NAME", "mlm_app"),
 		},
 		JWT: JWTConfig{
-			Secret: getEnv("JWT_SECRET", "your-secret-key"),
-			Expiry: jwtExpiry,
+			Secret:        getEnv("JWT_SECRET", "your-secret-key"),
+			Expiry:        jwtExpiry,
+			RefreshExpiry: getEnvAsDuration("JWT_REFRESH_EXPIRY", 30*24*time.Hour),
+
+			SigningKeyFile:       getEnv("JWT_SIGNING_KEY_FILE", ""),
+			VerificationKeyFiles: getEnvAsSlice("JWT_VERIFICATION_KEY_FILES", nil),
+		},
+		Auth: AuthConfig{
+			RequireEmailVerification: getEnvAsBool("REQUIRE_EMAIL_VERIFICATION", true),
+			EmailVerificationExpiry:  getEnvAsDuration("EMAIL_VERIFICATION_EXPIRY", 48*time.Hour),
+			PasswordResetExpiry:      getEnvAsDuration("PASSWORD_RESET_EXPIRY", time.Hour),
+			TwoFactorIssuer:          getEnv("TWO_FACTOR_ISSUER", "MLM App"),
+			LoginChallengeExpiry:     getEnvAsDuration("LOGIN_CHALLENGE_EXPIRY", 5*time.Minute),
+			ImpersonationExpiry:      getEnvAsDuration("IMPERSONATION_EXPIRY", 30*time.Minute),
+
+			LoginIPLimit:       getEnvAsInt("LOGIN_IP_LIMIT", 20),
+			LoginIPWindow:      getEnvAsDuration("LOGIN_IP_WINDOW", 15*time.Minute),
+			LoginFailureWindow: getEnvAsDuration("LOGIN_FAILURE_WINDOW", time.Hour),
+			LoginDelayAfter:    getEnvAsInt("LOGIN_DELAY_AFTER", 3),
+			LoginMaxDelay:      getEnvAsDuration("LOGIN_MAX_DELAY", 30*time.Second),
+			LockoutThreshold:   getEnvAsInt("LOCKOUT_THRESHOLD", 10),
+			LockoutDuration:    getEnvAsDuration("LOCKOUT_DURATION", 15*time.Minute),
+		},
+		Mail: MailConfig{
+			Driver:       getEnv("MAIL_DRIVER", "log"),
+			From:         getEnv("MAIL_FROM", "no-reply@localhost"),
+			SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+			SMTPPort:     getEnv("SMTP_PORT", "587"),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			LogDir:       getEnv("MAIL_LOG_DIR", ""),
+			AppURL:       getEnv("APP_URL", "http://localhost:3000"),
 		},
 		CORS: CORSConfig{
 			Origins: strings.Split(getEnv("CORS_ORIGINS", "http://localhost:3000"), ","),
 		},
+		Storage: StorageConfig{
+			Driver:    getEnv("STORAGE_DRIVER", "local"),
+			LocalPath: getEnv("STORAGE_LOCAL_PATH", "./storage"),
+		},
+		KYC: KYCConfig{
+			Required:        getEnvAsBool("KYC_REQUIRED", true),
+			MaxDocumentSize: int64(getEnvAsInt("KYC_MAX_DOCUMENT_SIZE", 10<<20)),
+			TaxIDHashKey:    getEnv("KYC_TAX_ID_HASH_KEY", "your-tax-id-hash-key"),
+		},
 		MLM: MLMConfig{
 			DefaultTreeType:           getEnv("DEFAULT_TREE_TYPE", "binary"),
 			BinaryMaxWidth:            getEnvAsInt("BINARY_MAX_WIDTH", 2),
@@ -88,6 +218,26 @@ func Load() *Config {
 			DirectReferralCommission:  getEnvAsFloat("DIRECT_REFERRAL_COMMISSION", 10.0),
 			LevelCommissionPercentage: getEnvAsFloat("LEVEL_COMMISSION_PERCENTAGE", 5.0),
 			MaxCommissionLevels:       getEnvAsInt("MAX_COMMISSION_LEVELS", 10),
+
+			CompressionEnabled:         getEnvAsBool("COMMISSION_COMPRESSION", false),
+			MinQualifyingPersonalSales: getEnvAsFloat("MIN_QUALIFYING_PERSONAL_SALES", 0),
+			GenerationBonusEnabled:     getEnvAsBool("GENERATION_BONUS_ENABLED", false),
+			GenerationRankLevel:        getEnvAsInt("GENERATION_RANK_LEVEL", 3),
+			GenerationBonusPercentages: getEnvAsFloatSlice("GENERATION_BONUS_PERCENTAGES", []float64{5, 3, 2}),
+
+			BreakawayRankLevel:           getEnvAsInt("BREAKAWAY_RANK_LEVEL", 3),
+			BreakawayOverridePercentages: getEnvAsFloatSlice("BREAKAWAY_OVERRIDE_PERCENTAGES", []float64{5, 3, 1}),
+
+			HouseAccountID:           uint(getEnvAsInt("HOUSE_ACCOUNT_ID", 0)),
+			TerminationRollUpToHouse: getEnvAsBool("TERMINATION_ROLL_UP_TO_HOUSE", false),
+			TerminationGracePeriod:   getEnvAsDuration("TERMINATION_GRACE_PERIOD", 30*24*time.Hour),
+
+			HoldingTankDays: getEnvAsInt("HOLDING_TANK_DAYS", 0),
+
+			InactivityDays:           getEnvAsInt("INACTIVITY_DAYS", 0),
+			QualifyingPurchaseVolume: getEnvAsFloat("QUALIFYING_PURCHASE_VOLUME", 0),
+
+			DefaultPackageID: uint(getEnvAsInt("DEFAULT_PACKAGE_ID", 0)),
 		},
 	}
 }
@@ -114,3 +264,51 @@ func getEnvAsFloat(key string, defaultValue float64) float64 {
 	}
 	return defaultValue
 }
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvAsFloatSlice(key string, defaultValue []float64) []float64 {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	var values []float64
+	for _, part := range strings.Split(valueStr, ",") {
+		value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return defaultValue
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+func getEnvAsSlice(key string, defaultValue []string) []string {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	var values []string
+	for _, part := range strings.Split(valueStr, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	return values
+}
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	valueStr := getEnv(key, "")
+	if value, err := time.ParseDuration(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
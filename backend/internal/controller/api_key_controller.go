@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mlm-app/backend/internal/service"
+)
+
+type APIKeyController struct {
+	apiKeyService service.APIKeyService
+}
+
+func NewAPIKeyController(apiKeyService service.APIKeyService) *APIKeyController {
+	return &APIKeyController{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// Create godoc
+// @Summary Create an API key for another system (admin)
+// @Description The key is only returned in this response; store it securely.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateAPIKeyRequest true "Key name, scopes and optional expiry"
+// @Success 201 {object} domain.NewAPIKey
+// @Router /api/v1/admin/api-keys [post]
+func (ctrl *APIKeyController) Create(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	created, err := ctrl.apiKeyService.Create(req.Name, req.Scopes, req.ExpiresAt, auditMeta(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusCreated, created)
+}
+
+// List godoc
+// @Summary List API keys (admin)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} []domain.APIKey
+// @Router /api/v1/admin/api-keys [get]
+func (ctrl *APIKeyController) List(c *gin.Context) {
+	keys, err := ctrl.apiKeyService.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, keys)
+}
+
+// Revoke godoc
+// @Summary Revoke an API key (admin)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "API key ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/api-keys/{id} [delete]
+func (ctrl *APIKeyController) Revoke(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	if err := ctrl.apiKeyService.Revoke(uint(id), auditMeta(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
+
+// Request DTOs
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
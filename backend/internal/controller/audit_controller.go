@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mlm-app/backend/internal/repository"
+	"github.com/mlm-app/backend/internal/service"
+)
+
+type AuditController struct {
+	auditService service.AuditService
+}
+
+func NewAuditController(auditService service.AuditService) *AuditController {
+	return &AuditController{
+		auditService: auditService,
+	}
+}
+
+// List godoc
+// @Summary Query the audit log by entity or actor (admin)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param entity_type query string false "Entity type, e.g. distributor or commission"
+// @Param entity_id query int false "Entity ID"
+// @Param actor_id query int false "Distributor who made the change"
+// @Param action query string false "Action, e.g. distributor.update"
+// @Param request_id query string false "Request ID from the X-Request-ID header"
+// @Param from query string false "Earliest entry (RFC 3339)"
+// @Param to query string false "Entries before this time (RFC 3339)"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(50)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/audit-logs [get]
+func (ctrl *AuditController) List(c *gin.Context) {
+	var req AuditLogQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	filter := repository.AuditFilter{
+		EntityType: req.EntityType,
+		EntityID:   req.EntityID,
+		ActorID:    req.ActorID,
+		Action:     req.Action,
+		RequestID:  req.RequestID,
+		From:       req.From,
+		To:         req.To,
+	}
+	
+	entries, total, err := ctrl.auditService.List(filter, (req.Page-1)*req.Limit, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data":  entries,
+		"total": total,
+		"page":  req.Page,
+		"limit": req.Limit,
+	})
+}
+
+// auditMeta collects who is making the request and from where, for the
+// audit entries written by the service layer
+func auditMeta(c *gin.Context) service.AuditMeta {
+	return service.AuditMeta{
+		ActorID:        c.GetUint("distributor_id"),
+		ImpersonatorID: c.GetUint("impersonator_id"),
+		APIKeyID:       c.GetUint("api_key_id"),
+		IPAddress:      c.ClientIP(),
+		RequestID:      c.GetString("request_id"),
+	}
+}
+
+// Request DTOs
+
+type AuditLogQuery struct {
+	EntityType string     `form:"entity_type"`
+	EntityID   *uint      `form:"entity_id"`
+	ActorID    *uint      `form:"actor_id"`
+	Action     string     `form:"action"`
+	RequestID  string     `form:"request_id"`
+	From       *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To         *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Page       int        `form:"page,default=1" binding:"min=1"`
+	Limit      int        `form:"limit,default=50" binding:"min=1,max=200"`
+}
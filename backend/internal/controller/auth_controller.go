@@ -0,0 +1,387 @@
+package controller
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mlm-app/backend/internal/service"
+	"github.com/mlm-app/backend/pkg/jwtkeys"
+)
+
+type AuthController struct {
+	authService      service.AuthService
+	accountService   service.AccountService
+	twoFactorService service.TwoFactorService
+	keys             *jwtkeys.KeySet
+}
+
+func NewAuthController(authService service.AuthService, accountService service.AccountService, twoFactorService service.TwoFactorService, keys *jwtkeys.KeySet) *AuthController {
+	return &AuthController{
+		authService:      authService,
+		accountService:   accountService,
+		twoFactorService: twoFactorService,
+		keys:             keys,
+	}
+}
+
+// JWKS godoc
+// @Summary Public keys for verifying access tokens
+// @Tags auth
+// @Produce json
+// @Success 200 {object} jwtkeys.JWKSet
+// @Router /.well-known/jwks.json [get]
+func (ctrl *AuthController) JWKS(c *gin.Context) {
+	// Short cache so a rotated-in key is picked up quickly by verifiers
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, ctrl.keys.JWKS())
+}
+
+// Refresh godoc
+// @Summary Exchange a refresh token for a new access and refresh token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} domain.TokenPair
+// @Router /api/v1/auth/refresh [post]
+func (ctrl *AuthController) Refresh(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	tokens, err := ctrl.authService.Refresh(req.RefreshToken, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout godoc
+// @Summary End the session a refresh token belongs to
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/auth/logout [post]
+func (ctrl *AuthController) Logout(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	if err := ctrl.authService.Logout(req.RefreshToken); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// LogoutAll godoc
+// @Summary Log out of every session and revoke all issued tokens
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/auth/logout-all [post]
+func (ctrl *AuthController) LogoutAll(c *gin.Context) {
+	if err := ctrl.authService.LogoutAll(c.GetUint("distributor_id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out of all sessions"})
+}
+
+// VerifyEmail godoc
+// @Summary Confirm an email address with the emailed token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body TokenRequest true "Verification token"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/auth/verify-email [post]
+func (ctrl *AuthController) VerifyEmail(c *gin.Context) {
+	var req TokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	if err := ctrl.accountService.VerifyEmail(req.Token); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"message": "Email address verified"})
+}
+
+// ResendVerification godoc
+// @Summary Send a new email verification link
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body EmailRequest true "Email address"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/auth/verify-email/resend [post]
+func (ctrl *AuthController) ResendVerification(c *gin.Context) {
+	var req EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	if err := ctrl.accountService.ResendVerification(req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+	
+	// Same answer whether or not the address exists
+	c.JSON(http.StatusOK, gin.H{"message": "If the address is registered and unverified, a new link has been sent"})
+}
+
+// ForgotPassword godoc
+// @Summary Email a password reset link
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body EmailRequest true "Email address"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/auth/password/forgot [post]
+func (ctrl *AuthController) ForgotPassword(c *gin.Context) {
+	var req EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	if err := ctrl.accountService.RequestPasswordReset(req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send password reset email"})
+		return
+	}
+	
+	// Same answer whether or not the address exists
+	c.JSON(http.StatusOK, gin.H{"message": "If the address is registered, a reset link has been sent"})
+}
+
+// ResetPassword godoc
+// @Summary Set a new password with an emailed reset token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/auth/password/reset [post]
+func (ctrl *AuthController) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	if err := ctrl.accountService.ResetPassword(req.Token, req.NewPassword); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset, please log in"})
+}
+
+// ChangePassword godoc
+// @Summary Change the password of the signed-in distributor
+// @Description Every session, including the current one, is signed out
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/auth/password/change [post]
+func (ctrl *AuthController) ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	if err := ctrl.accountService.ChangePassword(c.GetUint("distributor_id"), req.CurrentPassword, req.NewPassword); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed, please log in again"})
+}
+
+// VerifyTwoFactor godoc
+// @Summary Complete a two-step login with a TOTP or recovery code
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body TwoFactorLoginRequest true "Challenge token and code"
+// @Success 200 {object} domain.TokenPair
+// @Router /api/v1/auth/2fa/verify [post]
+func (ctrl *AuthController) VerifyTwoFactor(c *gin.Context) {
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	tokens, err := ctrl.twoFactorService.CompleteLogin(req.ChallengeToken, req.Code, req.RecoveryCode, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		var throttled *service.LoginThrottledError
+		switch {
+		case errors.As(err, &throttled):
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrAccountLocked):
+			c.JSON(http.StatusLocked, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	
+	c.JSON(http.StatusOK, tokens)
+}
+
+// SetupTwoFactor godoc
+// @Summary Start TOTP enrollment and get the provisioning URI
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} domain.TwoFactorSetup
+// @Router /api/v1/auth/2fa/setup [post]
+func (ctrl *AuthController) SetupTwoFactor(c *gin.Context) {
+	setup, err := ctrl.twoFactorService.Setup(c.GetUint("distributor_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, setup)
+}
+
+// EnableTwoFactor godoc
+// @Summary Confirm TOTP enrollment with a code and get recovery codes
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body TwoFactorCodeRequest true "Code from the authenticator app"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/auth/2fa/enable [post]
+func (ctrl *AuthController) EnableTwoFactor(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	codes, err := ctrl.twoFactorService.Enable(c.GetUint("distributor_id"), req.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled, log in again to use it",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor godoc
+// @Summary Turn off TOTP two-factor authentication (not allowed for admins)
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body DisableTwoFactorRequest true "Password and current code"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/auth/2fa/disable [post]
+func (ctrl *AuthController) DisableTwoFactor(c *gin.Context) {
+	var req DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	if err := ctrl.twoFactorService.Disable(c.GetUint("distributor_id"), req.Password, req.Code); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Replace all recovery codes
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body TwoFactorCodeRequest true "Code from the authenticator app"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/auth/2fa/recovery-codes [post]
+func (ctrl *AuthController) RegenerateRecoveryCodes(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	codes, err := ctrl.twoFactorService.RegenerateRecoveryCodes(c.GetUint("distributor_id"), req.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// Request DTOs
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type TokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type EmailRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mlm-app/backend/internal/service"
+)
+
+type CommissionController struct {
+	commissionService service.CommissionService
+}
+
+func NewCommissionController(commissionService service.CommissionService) *CommissionController {
+	return &CommissionController{
+		commissionService: commissionService,
+	}
+}
+
+// Mine godoc
+// @Summary List the current distributor's commissions
+// @Tags commissions
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(50)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/commissions [get]
+func (ctrl *CommissionController) Mine(c *gin.Context) {
+	var req PageQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	commissions, total, err := ctrl.commissionService.GetDistributorCommissions(c.GetUint("distributor_id"), (req.Page-1)*req.Limit, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data":  commissions,
+		"total": total,
+		"page":  req.Page,
+		"limit": req.Limit,
+	})
+}
+
+// List godoc
+// @Summary List all commissions (admin)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(50)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/commissions [get]
+func (ctrl *CommissionController) List(c *gin.Context) {
+	var req PageQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	commissions, total, err := ctrl.commissionService.List((req.Page-1)*req.Limit, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data":  commissions,
+		"total": total,
+		"page":  req.Page,
+		"limit": req.Limit,
+	})
+}
+
+// Approve godoc
+// @Summary Approve a pending commission (admin)
+// @Description Refused while the earner's KYC verification is not approved
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Commission ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/commissions/{id}/approve [post]
+func (ctrl *CommissionController) Approve(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	if err := ctrl.commissionService.ApproveCommission(uint(id), auditMeta(c)); err != nil {
+		c.JSON(commissionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"message": "Commission approved"})
+}
+
+// Pay godoc
+// @Summary Mark an approved commission as paid (admin)
+// @Description Refused while the earner's KYC verification is not approved
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Commission ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/commissions/{id}/pay [post]
+func (ctrl *CommissionController) Pay(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	if err := ctrl.commissionService.PayCommission(uint(id), auditMeta(c)); err != nil {
+		c.JSON(commissionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"message": "Commission paid"})
+}
+
+// commissionErrorStatus maps an approval or payment error to a status code
+func commissionErrorStatus(err error) int {
+	if errors.Is(err, service.ErrKYCNotApproved) {
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
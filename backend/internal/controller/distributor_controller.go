@@ -1,24 +1,39 @@
 package controller
 
 import (
+	"errors"
+	"log"
+	"math"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mlm-app/backend/internal/config"
 	"github.com/mlm-app/backend/internal/domain"
-	"github.com/mlm-app/backend/internal/middleware"
+	"github.com/mlm-app/backend/internal/repository"
 	"github.com/mlm-app/backend/internal/service"
 )
 
 type DistributorController struct {
 	distributorService service.DistributorService
+	snapshotService    service.SnapshotService
+	authService        service.AuthService
+	accountService     service.AccountService
+	loginSecurity      service.LoginSecurityService
+	packageService     service.PackageService
+	treeService        service.TreeService
 	config             *config.Config
 }
 
-func NewDistributorController(distributorService service.DistributorService, cfg *config.Config) *DistributorController {
+func NewDistributorController(distributorService service.DistributorService, snapshotService service.SnapshotService, authService service.AuthService, accountService service.AccountService, loginSecurity service.LoginSecurityService, packageService service.PackageService, treeService service.TreeService, cfg *config.Config) *DistributorController {
 	return &DistributorController{
 		distributorService: distributorService,
+		snapshotService:    snapshotService,
+		authService:        authService,
+		accountService:     accountService,
+		loginSecurity:      loginSecurity,
+		packageService:     packageService,
+		treeService:        treeService,
 		config:             cfg,
 	}
 }
@@ -51,7 +66,6 @@ func (ctrl *DistributorController) Register(c *gin.Context) {
 		SponsorID:   req.SponsorID,
 		TreeType:    req.TreeType,
 		Position:    req.Position,
-		PackageID:   req.PackageID,
 	}
 	
 	if err := ctrl.distributorService.Register(distributor, req.Password); err != nil {
@@ -59,17 +73,47 @@ func (ctrl *DistributorController) Register(c *gin.Context) {
 		return
 	}
 	
-	// Generate JWT token
-	token, err := middleware.GenerateToken(distributor.ID, distributor.Email, distributor.Role, ctrl.config)
+	// A mail failure should not undo the registration; the link can be resent
+	if err := ctrl.accountService.SendVerificationEmail(distributor); err != nil {
+		log.Printf("Failed to send verification email to distributor %d: %v", distributor.ID, err)
+	}
+	
+	// The chosen package is only granted once this order is paid
+	var packageOrder *domain.Order
+	if req.PackageID != nil {
+		meta := auditMeta(c)
+		meta.ActorID = distributor.ID
+		order, err := ctrl.packageService.Purchase(distributor.ID, *req.PackageID, meta)
+		if err != nil {
+			log.Printf("Failed to create package order for distributor %d: %v", distributor.ID, err)
+		}
+		packageOrder = order
+	}
+	
+	if ctrl.config.Auth.RequireEmailVerification {
+		c.JSON(http.StatusCreated, gin.H{
+			"message":       "Registration successful, check your email to verify your address",
+			"distributor":   distributor,
+			"package_order": packageOrder,
+		})
+		return
+	}
+	
+	// Generate access and refresh tokens
+	tokens, err := ctrl.authService.IssueTokens(distributor, false, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 	
 	c.JSON(http.StatusCreated, gin.H{
-		"message":     "Registration successful",
-		"distributor": distributor,
-		"token":       token,
+		"message":            "Registration successful",
+		"distributor":        distributor,
+		"token":              tokens.AccessToken,
+		"expires_at":         tokens.ExpiresAt,
+		"refresh_token":      tokens.RefreshToken,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
+		"package_order":      packageOrder,
 	})
 }
 
@@ -88,23 +132,74 @@ func (ctrl *DistributorController) Login(c *gin.Context) {
 		return
 	}
 	
+	ipAddress, userAgent := c.ClientIP(), c.Request.UserAgent()
+	
+	// The attempt is counted before the password is checked; repeated
+	// failures slow down further attempts on the same account
+	if err := ctrl.loginSecurity.BeginAttempt(req.Email); err != nil {
+		var throttled *service.LoginThrottledError
+		if errors.As(err, &throttled) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		}
+		ctrl.loginSecurity.RecordFailure(req.Email, ipAddress, userAgent, domain.LoginResultThrottled)
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	
 	distributor, err := ctrl.distributorService.Login(req.Email, req.Password)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, service.ErrAccountSuspended):
+			ctrl.loginSecurity.RecordFailure(req.Email, ipAddress, userAgent, domain.LoginResultRejected)
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrAccountLocked):
+			ctrl.loginSecurity.RecordFailure(req.Email, ipAddress, userAgent, domain.LoginResultLocked)
+			c.JSON(http.StatusLocked, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrInvalidCredentials):
+			ctrl.loginSecurity.RecordFailure(req.Email, ipAddress, userAgent, domain.LoginResultInvalidCredentials)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		default:
+			ctrl.loginSecurity.RecordFailure(req.Email, ipAddress, userAgent, domain.LoginResultRejected)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	
+	// With 2FA enabled the password only earns a challenge for the second step
+	if distributor.TwoFactorEnabledAt != nil {
+		challenge, err := ctrl.authService.IssueChallenge(distributor)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+		ctrl.loginSecurity.RecordSuccess(distributor, ipAddress, userAgent, domain.LoginResultChallengeIssued)
+		
+		c.JSON(http.StatusOK, gin.H{
+			"message":             "Two-factor code required",
+			"two_factor_required": true,
+			"challenge_token":     challenge.ChallengeToken,
+			"expires_at":          challenge.ExpiresAt,
+		})
 		return
 	}
 	
-	// Generate JWT token
-	token, err := middleware.GenerateToken(distributor.ID, distributor.Email, distributor.Role, ctrl.config)
+	// Generate access and refresh tokens
+	tokens, err := ctrl.authService.IssueTokens(distributor, false, userAgent, ipAddress)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
+	ctrl.loginSecurity.RecordSuccess(distributor, ipAddress, userAgent, domain.LoginResultSuccess)
 	
 	c.JSON(http.StatusOK, gin.H{
-		"message":     "Login successful",
-		"distributor": distributor,
-		"token":       token,
+		"message":            "Login successful",
+		"distributor":        distributor,
+		"token":              tokens.AccessToken,
+		"expires_at":         tokens.ExpiresAt,
+		"refresh_token":      tokens.RefreshToken,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
+		// Admin routes stay closed until the admin enrolls in 2FA and logs in with it
+		"two_factor_setup_required": distributor.Role == "admin",
 	})
 }
 
@@ -201,7 +296,7 @@ func (ctrl *DistributorController) Update(c *gin.Context) {
 		distributor.ZipCode = req.ZipCode
 	}
 	
-	if err := ctrl.distributorService.Update(distributor); err != nil {
+	if err := ctrl.distributorService.Update(distributor, auditMeta(c)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -269,6 +364,8 @@ func (ctrl *DistributorController) GetDownlines(c *gin.Context) {
 // @Security BearerAuth
 // @Param id path int true "Distributor ID"
 // @Param depth query int false "Tree depth" default(3)
+// @Param view query string false "enrollment (sponsor) or placement (hybrid binary)" default(enrollment)
+// @Param as_of query string false "Answer from the period snapshot containing this date (YYYY-MM-DD)"
 // @Success 200 {object} domain.TreeNode
 // @Router /api/v1/distributors/{id}/tree [get]
 func (ctrl *DistributorController) GetTreeStructure(c *gin.Context) {
@@ -278,9 +375,39 @@ func (ctrl *DistributorController) GetTreeStructure(c *gin.Context) {
 		return
 	}
 	
+	if !authorizeDownline(c, ctrl.treeService, uint(id)) {
+		return
+	}
+	
 	depth, _ := strconv.Atoi(c.DefaultQuery("depth", "3"))
 	
-	tree, err := ctrl.distributorService.GetTreeStructure(uint(id), depth)
+	asOf, ok := parseAsOf(c)
+	if !ok {
+		return
+	}
+	
+	view := c.DefaultQuery("view", "enrollment")
+	if view != "enrollment" && view != "placement" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "view must be 'enrollment' or 'placement'"})
+		return
+	}
+	
+	if asOf != nil {
+		tree, err := ctrl.snapshotService.GetTreeAsOf(uint(id), depth, view == "placement", *asOf)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, tree)
+		return
+	}
+	
+	var tree *domain.TreeNode
+	if view == "placement" {
+		tree, err = ctrl.distributorService.GetPlacementTreeStructure(uint(id), depth)
+	} else {
+		tree, err = ctrl.distributorService.GetTreeStructure(uint(id), depth)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -289,6 +416,321 @@ func (ctrl *DistributorController) GetTreeStructure(c *gin.Context) {
 	c.JSON(http.StatusOK, tree)
 }
 
+// GetStats godoc
+// @Summary Get distributor volume and downline stats
+// @Tags distributor
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Distributor ID"
+// @Param as_of query string false "Answer from the period snapshot containing this date (YYYY-MM-DD)"
+// @Success 200 {object} domain.DistributorStats
+// @Router /api/v1/distributors/{id}/stats [get]
+func (ctrl *DistributorController) GetStats(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	if !authorizeDownline(c, ctrl.treeService, uint(id)) {
+		return
+	}
+	
+	asOf, ok := parseAsOf(c)
+	if !ok {
+		return
+	}
+	
+	var stats *domain.DistributorStats
+	if asOf != nil {
+		stats, err = ctrl.snapshotService.GetStatsAsOf(uint(id), *asOf)
+	} else {
+		stats, err = ctrl.snapshotService.GetStats(uint(id))
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetBreakaways godoc
+// @Summary Get groups that broke away from a distributor
+// @Tags distributor
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Distributor ID"
+// @Success 200 {object} []domain.BreakawayEvent
+// @Router /api/v1/distributors/{id}/breakaways [get]
+func (ctrl *DistributorController) GetBreakaways(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	events, err := ctrl.distributorService.GetBreakaways(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, events)
+}
+
+// GetHoldingTank godoc
+// @Summary List enrollees waiting in the caller's holding tank
+// @Tags distributor
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} []domain.Distributor
+// @Router /api/v1/distributors/holding-tank [get]
+func (ctrl *DistributorController) GetHoldingTank(c *gin.Context) {
+	distributorID := c.GetUint("distributor_id")
+	
+	members, err := ctrl.distributorService.GetHoldingTank(distributorID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, members)
+}
+
+// PlaceFromHoldingTank godoc
+// @Summary Place a holding tank member in the caller's tree
+// @Tags distributor
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Holding tank member ID"
+// @Param placement body PlaceMemberRequest true "Placement parent and position"
+// @Success 200 {object} domain.Distributor
+// @Router /api/v1/distributors/holding-tank/{id}/place [post]
+func (ctrl *DistributorController) PlaceFromHoldingTank(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	var req PlaceMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	sponsorID := c.GetUint("distributor_id")
+	if err := ctrl.distributorService.PlaceFromHoldingTank(uint(id), sponsorID, req.PlacementID, req.Position, auditMeta(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	member, err := ctrl.distributorService.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, member)
+}
+
+// MoveMember godoc
+// @Summary Move a distributor to a new sponsor (admin)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Distributor ID"
+// @Param move body MoveMemberRequest true "Target sponsor and position"
+// @Success 200 {object} domain.Distributor
+// @Router /api/v1/admin/distributors/{id}/move [post]
+func (ctrl *DistributorController) MoveMember(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	var req MoveMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	if err := ctrl.distributorService.MoveMember(uint(id), req.SponsorID, req.Position, req.WithSubtree, auditMeta(c), req.Reason); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	distributor, err := ctrl.distributorService.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, distributor)
+}
+
+// Terminate godoc
+// @Summary Terminate a distributor and roll up their downline (admin)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Distributor ID"
+// @Param termination body TerminateRequest true "Reason code and explanation"
+// @Success 200 {object} domain.Termination
+// @Router /api/v1/admin/distributors/{id}/terminate [post]
+func (ctrl *DistributorController) Terminate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	var req TerminateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	termination, err := ctrl.distributorService.Terminate(uint(id), auditMeta(c), req.ReasonCode, req.Reason)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, termination)
+}
+
+// Impersonate godoc
+// @Summary Get a read-only token acting as a distributor, for support (admin)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Distributor ID"
+// @Param request body ImpersonateRequest true "Why the session is needed"
+// @Success 200 {object} domain.ImpersonationToken
+// @Router /api/v1/admin/distributors/{id}/impersonate [post]
+func (ctrl *DistributorController) Impersonate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	var req ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	token, err := ctrl.authService.Impersonate(uint(id), auditMeta(c), req.Reason)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, token)
+}
+
+// Unlock godoc
+// @Summary Lift a failed-login lockout (admin)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Distributor ID"
+// @Success 200 {object} domain.Distributor
+// @Router /api/v1/admin/distributors/{id}/unlock [post]
+func (ctrl *DistributorController) Unlock(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	distributor, err := ctrl.loginSecurity.Unlock(uint(id), auditMeta(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, distributor)
+}
+
+// ListLoginAttempts godoc
+// @Summary List recorded login attempts for security review (admin)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param email query string false "Filter by email"
+// @Param distributor_id query int false "Filter by distributor"
+// @Param ip_address query string false "Filter by client IP"
+// @Param success query bool false "Filter by outcome"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(50)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/login-attempts [get]
+func (ctrl *DistributorController) ListLoginAttempts(c *gin.Context) {
+	var req LoginAttemptQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	filter := repository.LoginAttemptFilter{
+		Email:         req.Email,
+		DistributorID: req.DistributorID,
+		IPAddress:     req.IPAddress,
+		Success:       req.Success,
+	}
+	
+	attempts, total, err := ctrl.loginSecurity.ListAttempts(filter, (req.Page-1)*req.Limit, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data":  attempts,
+		"total": total,
+		"page":  req.Page,
+		"limit": req.Limit,
+	})
+}
+
+// Reinstate godoc
+// @Summary Reverse a termination within its grace period (admin)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Distributor ID"
+// @Success 200 {object} domain.Distributor
+// @Router /api/v1/admin/distributors/{id}/reinstate [post]
+func (ctrl *DistributorController) Reinstate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	if err := ctrl.distributorService.Reinstate(uint(id), auditMeta(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	distributor, err := ctrl.distributorService.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, distributor)
+}
+
 // AddMemberToTree godoc
 // @Summary Add member to tree
 // @Tags distributor
@@ -310,7 +752,6 @@ func (ctrl *DistributorController) AddMemberToTree(c *gin.Context) {
 		LastName:  req.LastName,
 		Email:     req.Email,
 		Phone:     req.Phone,
-		PackageID: req.PackageID,
 	}
 	
 	if err := ctrl.distributorService.AddMemberToTree(member, req.SponsorID, req.Position); err != nil {
@@ -336,7 +777,7 @@ type RegisterRequest struct {
 	SponsorID *uint             `json:"sponsor_id"`
 	TreeType  domain.TreeType   `json:"tree_type"`
 	Position  string            `json:"position"`
-	PackageID *uint             `json:"package_id"`
+	PackageID *uint             `json:"package_id"` // Creates an order for the package; it activates once paid
 }
 
 type LoginRequest struct {
@@ -362,5 +803,34 @@ type AddMemberRequest struct {
 	Phone     string `json:"phone"`
 	SponsorID uint   `json:"sponsor_id" binding:"required"`
 	Position  string `json:"position" binding:"required"`
-	PackageID *uint  `json:"package_id"`
+}
+
+type PlaceMemberRequest struct {
+	PlacementID *uint  `json:"placement_id"`
+	Position    string `json:"position"`
+}
+
+type MoveMemberRequest struct {
+	SponsorID   uint   `json:"sponsor_id" binding:"required"`
+	Position    string `json:"position"`
+	WithSubtree bool   `json:"with_subtree"`
+	Reason      string `json:"reason" binding:"required"`
+}
+
+type TerminateRequest struct {
+	ReasonCode string `json:"reason_code" binding:"required,oneof=policy_violation fraud chargeback compliance requested other"`
+	Reason     string `json:"reason" binding:"required"`
+}
+
+type ImpersonateRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+type LoginAttemptQuery struct {
+	Email         string `form:"email"`
+	DistributorID *uint  `form:"distributor_id"`
+	IPAddress     string `form:"ip_address"`
+	Success       *bool  `form:"success"`
+	Page          int    `form:"page,default=1" binding:"min=1"`
+	Limit         int    `form:"limit,default=50" binding:"min=1,max=200"`
 }
@@ -0,0 +1,348 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mlm-app/backend/internal/domain"
+	"github.com/mlm-app/backend/internal/service"
+)
+
+type GenealogyController struct {
+	integrityService service.IntegrityService
+	exportService    service.ExportService
+	treeService      service.TreeService
+}
+
+func NewGenealogyController(
+	integrityService service.IntegrityService,
+	exportService service.ExportService,
+	treeService service.TreeService,
+) *GenealogyController {
+	return &GenealogyController{
+		integrityService: integrityService,
+		exportService:    exportService,
+		treeService:      treeService,
+	}
+}
+
+// exportContentTypes maps each export format to its response content type
+var exportContentTypes = map[string]string{
+	service.ExportFormatCSV:     "text/csv",
+	service.ExportFormatGraphML: "application/graphml+xml",
+	service.ExportFormatDOT:     "text/vnd.graphviz",
+}
+
+// Check godoc
+// @Summary Scan the genealogy for integrity violations (admin)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} domain.IntegrityReport
+// @Router /api/v1/admin/genealogy/check [get]
+func (ctrl *GenealogyController) Check(c *gin.Context) {
+	report, err := ctrl.integrityService.Check()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, report)
+}
+
+// Repair godoc
+// @Summary Auto-repair safe genealogy violations (admin)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} domain.IntegrityReport
+// @Router /api/v1/admin/genealogy/repair [post]
+func (ctrl *GenealogyController) Repair(c *gin.Context) {
+	report, err := ctrl.integrityService.Repair(auditMeta(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, report)
+}
+
+// Export godoc
+// @Summary Export the downline under a distributor
+// @Tags distributor
+// @Produce text/csv
+// @Produce application/graphml+xml
+// @Produce text/vnd.graphviz
+// @Security BearerAuth
+// @Param id path int true "Distributor ID"
+// @Param format query string false "csv, graphml or dot" default(csv)
+// @Success 200 {file} file
+// @Router /api/v1/distributors/{id}/export [get]
+func (ctrl *GenealogyController) Export(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	format := c.DefaultQuery("format", service.ExportFormatCSV)
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": service.ErrUnsupportedExportFormat.Error()})
+		return
+	}
+	
+	if !ctrl.authorizeDownline(c, uint(id)) {
+		return
+	}
+	
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=genealogy-%d.%s", id, format))
+	c.Status(http.StatusOK)
+	
+	// Headers are already sent once streaming starts, so a failure can only
+	// cut the response short
+	if err := ctrl.exportService.ExportGenealogy(uint(id), format, c.Writer); err != nil {
+		log.Printf("Genealogy export for distributor %d failed: %v", id, err)
+		c.Abort()
+	}
+}
+
+// Search godoc
+// @Summary Search a distributor's downline
+// @Tags distributor
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Distributor ID"
+// @Param q query string false "Name or email contains"
+// @Param rank_id query int false "Rank ID"
+// @Param status query string false "Status"
+// @Param joined_from query string false "Joined on or after (YYYY-MM-DD)"
+// @Param joined_to query string false "Joined before (YYYY-MM-DD)"
+// @Param min_depth query int false "Minimum depth below the distributor"
+// @Param max_depth query int false "Maximum depth below the distributor"
+// @Param leg query string false "left or right"
+// @Param min_personal_sales query number false "Minimum personal sales"
+// @Param max_personal_sales query number false "Maximum personal sales"
+// @Param min_team_sales query number false "Minimum team sales"
+// @Param max_team_sales query number false "Maximum team sales"
+// @Param sort query string false "name, email, joined, depth, rank, personal_sales, team_sales or total_sales" default(depth)
+// @Param order query string false "asc or desc" default(asc)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/distributors/{id}/downlines/search [get]
+func (ctrl *GenealogyController) Search(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	var req DownlineSearchRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	if !ctrl.authorizeDownline(c, uint(id)) {
+		return
+	}
+	
+	filter := domain.DownlineFilter{
+		Query:            req.Query,
+		RankID:           req.RankID,
+		Status:           req.Status,
+		JoinedFrom:       req.JoinedFrom,
+		MinDepth:         req.MinDepth,
+		MaxDepth:         req.MaxDepth,
+		Leg:              req.Leg,
+		MinPersonalSales: req.MinPersonalSales,
+		MaxPersonalSales: req.MaxPersonalSales,
+		MinTeamSales:     req.MinTeamSales,
+		MaxTeamSales:     req.MaxTeamSales,
+		SortBy:           req.Sort,
+		SortDesc:         req.Order == "desc",
+		Offset:           (req.Page - 1) * req.Limit,
+		Limit:            req.Limit,
+	}
+	if req.JoinedTo != nil {
+		// joined_to is a date, so include the whole day
+		joinedTo := req.JoinedTo.AddDate(0, 0, 1)
+		filter.JoinedTo = &joinedTo
+	}
+	
+	members, total, err := ctrl.treeService.SearchDownline(uint(id), filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data":  members,
+		"total": total,
+		"page":  req.Page,
+		"limit": req.Limit,
+	})
+}
+
+// GetChildren godoc
+// @Summary Get one page of a node's direct children
+// @Description Lazy-loading tree API: each child carries child_count so clients can expand nodes on demand
+// @Tags distributor
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Distributor ID"
+// @Param view query string false "enrollment or placement" default(enrollment)
+// @Param cursor query int false "next_cursor from the previous page"
+// @Param limit query int false "Items per page" default(50)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/distributors/{id}/children [get]
+func (ctrl *GenealogyController) GetChildren(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	placement, ok := parseTreeView(c)
+	if !ok {
+		return
+	}
+	
+	cursor, err := strconv.ParseUint(c.DefaultQuery("cursor", "0"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	
+	if !ctrl.authorizeDownline(c, uint(id)) {
+		return
+	}
+	
+	children, next, err := ctrl.treeService.GetChildren(uint(id), placement, uint(cursor), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data":        children,
+		"next_cursor": next,
+	})
+}
+
+// GetPath godoc
+// @Summary Get the path from the top of the caller's organization to a node
+// @Tags distributor
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Distributor ID"
+// @Param view query string false "enrollment or placement" default(enrollment)
+// @Param from query int false "Root of the path; defaults to the caller, or the top of the tree for admins"
+// @Success 200 {object} []domain.TreeNode
+// @Router /api/v1/distributors/{id}/path [get]
+func (ctrl *GenealogyController) GetPath(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	placement, ok := parseTreeView(c)
+	if !ok {
+		return
+	}
+	
+	var rootID uint
+	if !isAdmin(c) {
+		rootID = c.GetUint("distributor_id")
+	}
+	if from := c.Query("from"); from != "" {
+		fromID, err := strconv.ParseUint(from, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from ID"})
+			return
+		}
+		if !ctrl.authorizeDownline(c, uint(fromID)) {
+			return
+		}
+		rootID = uint(fromID)
+	}
+	
+	path, err := ctrl.treeService.GetPathToNode(rootID, uint(id), placement)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, path)
+}
+
+// parseTreeView reads the view query parameter, reporting whether the
+// placement tree was requested, and writes the error response if it is invalid
+func parseTreeView(c *gin.Context) (bool, bool) {
+	switch c.DefaultQuery("view", "enrollment") {
+	case "enrollment":
+		return false, true
+	case "placement":
+		return true, true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "view must be 'enrollment' or 'placement'"})
+	return false, false
+}
+
+// authorizeDownline checks access to distributorID's organization
+func (ctrl *GenealogyController) authorizeDownline(c *gin.Context, distributorID uint) bool {
+	return authorizeDownline(c, ctrl.treeService, distributorID)
+}
+
+// authorizeDownline lets admins through and limits distributors to their own
+// organization, writing the error response when access is refused
+func authorizeDownline(c *gin.Context, treeService service.TreeService, distributorID uint) bool {
+	if isAdmin(c) {
+		return true
+	}
+	
+	inDownline, err := treeService.IsInDownline(distributorID, c.GetUint("distributor_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return false
+	}
+	if !inDownline {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Distributor is not in your downline"})
+		return false
+	}
+	return true
+}
+
+// isAdmin reports whether the caller may use admin privileges, which like
+// the admin routes needs a login that passed two-factor authentication
+func isAdmin(c *gin.Context) bool {
+	return c.GetString("role") == "admin" && c.GetBool("two_factor")
+}
+
+// Request DTOs
+type DownlineSearchRequest struct {
+	Query            string     `form:"q"`
+	RankID           *uint      `form:"rank_id"`
+	Status           string     `form:"status" binding:"omitempty,oneof=active inactive suspended terminated"`
+	JoinedFrom       *time.Time `form:"joined_from" time_format:"2006-01-02"`
+	JoinedTo         *time.Time `form:"joined_to" time_format:"2006-01-02"`
+	MinDepth         int        `form:"min_depth" binding:"min=0"`
+	MaxDepth         int        `form:"max_depth" binding:"min=0"`
+	Leg              string     `form:"leg" binding:"omitempty,oneof=left right"`
+	MinPersonalSales *float64   `form:"min_personal_sales"`
+	MaxPersonalSales *float64   `form:"max_personal_sales"`
+	MinTeamSales     *float64   `form:"min_team_sales"`
+	MaxTeamSales     *float64   `form:"max_team_sales"`
+	Sort             string     `form:"sort" binding:"omitempty,oneof=name email joined depth rank personal_sales team_sales total_sales"`
+	Order            string     `form:"order" binding:"omitempty,oneof=asc desc"`
+	Page             int        `form:"page,default=1" binding:"min=1"`
+	Limit            int        `form:"limit,default=10" binding:"min=1,max=100"`
+}
@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mlm-app/backend/internal/service"
+)
+
+type InventoryController struct {
+	inventoryService service.InventoryService
+}
+
+func NewInventoryController(inventoryService service.InventoryService) *InventoryController {
+	return &InventoryController{
+		inventoryService: inventoryService,
+	}
+}
+
+// List godoc
+// @Summary List product stock levels (inventory:read API key or admin)
+// @Tags integration
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(50)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/inventory [get]
+func (ctrl *InventoryController) List(c *gin.Context) {
+	var req PageQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	products, total, err := ctrl.inventoryService.List((req.Page-1)*req.Limit, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data":  products,
+		"total": total,
+		"page":  req.Page,
+		"limit": req.Limit,
+	})
+}
+
+// SetStock godoc
+// @Summary Set a product's stock level (inventory:write API key or admin)
+// @Tags integration
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param sku path string true "Product SKU"
+// @Param request body SetStockRequest true "New stock level"
+// @Success 200 {object} domain.Product
+// @Router /api/v1/inventory/{sku} [put]
+func (ctrl *InventoryController) SetStock(c *gin.Context) {
+	var req SetStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	product, err := ctrl.inventoryService.SetStock(c.Param("sku"), *req.Stock)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, product)
+}
+
+// Request DTOs
+
+type SetStockRequest struct {
+	Stock *int `json:"stock" binding:"required,min=0"`
+}
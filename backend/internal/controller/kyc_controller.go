@@ -0,0 +1,286 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mlm-app/backend/internal/config"
+	"github.com/mlm-app/backend/internal/domain"
+	"github.com/mlm-app/backend/internal/service"
+)
+
+type KYCController struct {
+	kycService service.KYCService
+	config     *config.Config
+}
+
+func NewKYCController(kycService service.KYCService, cfg *config.Config) *KYCController {
+	return &KYCController{
+		kycService: kycService,
+		config:     cfg,
+	}
+}
+
+// Status godoc
+// @Summary Get the current user's KYC status and latest submission
+// @Tags kyc
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/kyc [get]
+func (ctrl *KYCController) Status(c *gin.Context) {
+	submission, err := ctrl.kycService.Current(c.GetUint("distributor_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	status := domain.KYCStatusNone
+	if submission != nil {
+		status = submission.Status
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"status":     status,
+		"required":   ctrl.config.KYC.Required,
+		"submission": submission,
+	})
+}
+
+// UploadDocument godoc
+// @Summary Upload an identity document (PDF, JPEG or PNG)
+// @Tags kyc
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param type formData string true "passport, national_id, drivers_license, proof_of_address or tax_form"
+// @Param file formData file true "Document"
+// @Success 201 {object} domain.KYCDocument
+// @Router /api/v1/kyc/documents [post]
+func (ctrl *KYCController) UploadDocument(c *gin.Context) {
+	// Leave room for the multipart headers around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, ctrl.config.KYC.MaxDocumentSize+1<<20)
+	
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A document file of at most %d bytes is required", ctrl.config.KYC.MaxDocumentSize)})
+		return
+	}
+	defer file.Close()
+	
+	document, err := ctrl.kycService.UploadDocument(c.GetUint("distributor_id"), c.PostForm("type"), header.Filename, file, auditMeta(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusCreated, document)
+}
+
+// Submit godoc
+// @Summary Submit uploaded documents and tax details for review
+// @Tags kyc
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body SubmitKYCRequest true "Tax details"
+// @Success 200 {object} domain.KYCSubmission
+// @Router /api/v1/kyc/submit [post]
+func (ctrl *KYCController) Submit(c *gin.Context) {
+	var req SubmitKYCRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	submission, err := ctrl.kycService.Submit(c.GetUint("distributor_id"), service.KYCDetails{
+		LegalName:  req.LegalName,
+		TaxCountry: req.TaxCountry,
+		TaxIDType:  req.TaxIDType,
+		TaxID:      req.TaxID,
+	}, auditMeta(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, submission)
+}
+
+// List godoc
+// @Summary List KYC submissions for review (admin)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "pending, approved or rejected; defaults to all submitted"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(50)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/kyc [get]
+func (ctrl *KYCController) List(c *gin.Context) {
+	var req KYCQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	submissions, total, err := ctrl.kycService.List(req.Status, (req.Page-1)*req.Limit, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data":  submissions,
+		"total": total,
+		"page":  req.Page,
+		"limit": req.Limit,
+	})
+}
+
+// Get godoc
+// @Summary Get a KYC submission with its documents (admin)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Submission ID"
+// @Success 200 {object} domain.KYCSubmission
+// @Router /api/v1/admin/kyc/{id} [get]
+func (ctrl *KYCController) Get(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	submission, err := ctrl.kycService.GetSubmission(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, submission)
+}
+
+// Document godoc
+// @Summary Download a KYC document (admin)
+// @Tags admin
+// @Produce octet-stream
+// @Security BearerAuth
+// @Param id path int true "Submission ID"
+// @Param documentId path int true "Document ID"
+// @Success 200 {file} file
+// @Router /api/v1/admin/kyc/{id}/documents/{documentId} [get]
+func (ctrl *KYCController) Document(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	documentID, err := strconv.ParseUint(c.Param("documentId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+	
+	document, file, err := ctrl.kycService.OpenDocument(uint(id), uint(documentID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+	
+	c.DataFromReader(http.StatusOK, document.Size, document.ContentType, file, map[string]string{
+		"Content-Disposition":    fmt.Sprintf("attachment; filename=kyc-%d-%d%s", id, documentID, kycFileExtension(document.ContentType)),
+		"X-Content-Type-Options": "nosniff",
+		"Cache-Control":          "no-store",
+	})
+}
+
+// Approve godoc
+// @Summary Approve a KYC submission (admin)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Submission ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/kyc/{id}/approve [post]
+func (ctrl *KYCController) Approve(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	if err := ctrl.kycService.Approve(uint(id), auditMeta(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"message": "KYC submission approved"})
+}
+
+// Reject godoc
+// @Summary Reject a KYC submission (admin)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Submission ID"
+// @Param request body RejectKYCRequest true "Reason shown to the distributor"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/kyc/{id}/reject [post]
+func (ctrl *KYCController) Reject(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	var req RejectKYCRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	if err := ctrl.kycService.Reject(uint(id), req.Reason, auditMeta(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"message": "KYC submission rejected"})
+}
+
+// kycFileExtension names downloads after their detected content type
+func kycFileExtension(contentType string) string {
+	switch contentType {
+	case "application/pdf":
+		return ".pdf"
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	default:
+		return ""
+	}
+}
+
+// Request DTOs
+
+type SubmitKYCRequest struct {
+	LegalName  string `json:"legal_name" binding:"required,max=200"`
+	TaxCountry string `json:"tax_country" binding:"required,len=2,alpha"`
+	TaxIDType  string `json:"tax_id_type" binding:"required,max=20"`
+	TaxID      string `json:"tax_id" binding:"required,min=4,max=50"`
+}
+
+type RejectKYCRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+type KYCQuery struct {
+	PageQuery
+	Status string `form:"status" binding:"omitempty,oneof=pending approved rejected"`
+}
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mlm-app/backend/internal/service"
+)
+
+type OrderController struct {
+	orderService service.OrderService
+}
+
+func NewOrderController(orderService service.OrderService) *OrderController {
+	return &OrderController{
+		orderService: orderService,
+	}
+}
+
+// List godoc
+// @Summary List orders (orders:read API key or admin)
+// @Tags integration
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(50)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/orders [get]
+func (ctrl *OrderController) List(c *gin.Context) {
+	var req PageQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	orders, total, err := ctrl.orderService.List((req.Page-1)*req.Limit, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data":  orders,
+		"total": total,
+		"page":  req.Page,
+		"limit": req.Limit,
+	})
+}
+
+// GetByID godoc
+// @Summary Get an order with its items (orders:read API key or admin)
+// @Tags integration
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Success 200 {object} domain.Order
+// @Router /api/v1/orders/{id} [get]
+func (ctrl *OrderController) GetByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	order, err := ctrl.orderService.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, order)
+}
+
+// RecordPayment godoc
+// @Summary Record the outcome of an order's payment (orders:write API key or admin)
+// @Description Paying a package order activates the package
+// @Tags integration
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Param request body RecordPaymentRequest true "Payment outcome"
+// @Success 200 {object} domain.Order
+// @Router /api/v1/orders/{id}/payment [post]
+func (ctrl *OrderController) RecordPayment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	var req RecordPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	order, err := ctrl.orderService.RecordPayment(uint(id), req.PaymentStatus, req.PaymentMethod, auditMeta(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, order)
+}
+
+// Request DTOs
+
+type PageQuery struct {
+	Page  int `form:"page,default=1" binding:"min=1"`
+	Limit int `form:"limit,default=50" binding:"min=1,max=200"`
+}
+
+type RecordPaymentRequest struct {
+	PaymentStatus string `json:"payment_status" binding:"required,oneof=paid failed"`
+	PaymentMethod string `json:"payment_method" binding:"max=50"`
+}
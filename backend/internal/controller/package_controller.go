@@ -0,0 +1,305 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mlm-app/backend/internal/domain"
+	"github.com/mlm-app/backend/internal/service"
+)
+
+type PackageController struct {
+	packageService service.PackageService
+}
+
+func NewPackageController(packageService service.PackageService) *PackageController {
+	return &PackageController{
+		packageService: packageService,
+	}
+}
+
+// ListActive godoc
+// @Summary List the packages on sale
+// @Tags package
+// @Produce json
+// @Success 200 {array} domain.Package
+// @Router /api/v1/packages [get]
+func (ctrl *PackageController) ListActive(c *gin.Context) {
+	packages, err := ctrl.packageService.ListActive()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, packages)
+}
+
+// Purchase godoc
+// @Summary Order a package, or an upgrade to it at a prorated price
+// @Description The package is activated once the order is paid
+// @Tags package
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Package ID"
+// @Success 201 {object} domain.Order
+// @Router /api/v1/packages/{id}/purchase [post]
+func (ctrl *PackageController) Purchase(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	order, err := ctrl.packageService.Purchase(c.GetUint("distributor_id"), uint(id), auditMeta(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusCreated, order)
+}
+
+// Renew godoc
+// @Summary Order a renewal of the current package for another term
+// @Tags package
+// @Produce json
+// @Security BearerAuth
+// @Success 201 {object} domain.Order
+// @Router /api/v1/packages/renew [post]
+func (ctrl *PackageController) Renew(c *gin.Context) {
+	order, err := ctrl.packageService.Renew(c.GetUint("distributor_id"), auditMeta(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusCreated, order)
+}
+
+// List godoc
+// @Summary List the current version of every package, including inactive ones (admin)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} domain.Package
+// @Router /api/v1/admin/packages [get]
+func (ctrl *PackageController) List(c *gin.Context) {
+	packages, err := ctrl.packageService.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, packages)
+}
+
+// GetByID godoc
+// @Summary Get a package version (admin)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Package ID"
+// @Success 200 {object} domain.Package
+// @Router /api/v1/admin/packages/{id} [get]
+func (ctrl *PackageController) GetByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	pkg, err := ctrl.packageService.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, pkg)
+}
+
+// Versions godoc
+// @Summary List every version of a package, newest first (admin)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID of any version of the package"
+// @Success 200 {array} domain.Package
+// @Router /api/v1/admin/packages/{id}/versions [get]
+func (ctrl *PackageController) Versions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	versions, err := ctrl.packageService.Versions(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, versions)
+}
+
+// Create godoc
+// @Summary Create a package (admin)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreatePackageRequest true "Package"
+// @Success 201 {object} domain.Package
+// @Router /api/v1/admin/packages [post]
+func (ctrl *PackageController) Create(c *gin.Context) {
+	var req CreatePackageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	pkg := packageFromRequest(req.PackageRequest)
+	pkg.Code = req.Code
+	pkg.IsActive = req.IsActive == nil || *req.IsActive
+	
+	if err := ctrl.packageService.Create(pkg, auditMeta(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusCreated, pkg)
+}
+
+// Update godoc
+// @Summary Update a package (admin)
+// @Description Changing price, commission rate, levels, term or features creates a new version; members keep the version they bought
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Package ID"
+// @Param request body PackageRequest true "Package"
+// @Success 200 {object} domain.Package
+// @Router /api/v1/admin/packages/{id} [put]
+func (ctrl *PackageController) Update(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	var req PackageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	pkg, err := ctrl.packageService.Update(uint(id), packageFromRequest(req), auditMeta(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, pkg)
+}
+
+// SetActive godoc
+// @Summary Put a package on sale or take it off (admin)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Package ID"
+// @Param request body SetPackageActiveRequest true "Whether the package is sold"
+// @Success 200 {object} domain.Package
+// @Router /api/v1/admin/packages/{id}/active [put]
+func (ctrl *PackageController) SetActive(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	var req SetPackageActiveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	pkg, err := ctrl.packageService.SetActive(uint(id), *req.IsActive, auditMeta(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, pkg)
+}
+
+// Delete godoc
+// @Summary Delete an unused package with all its versions (admin)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Package ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/packages/{id} [delete]
+func (ctrl *PackageController) Delete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	if err := ctrl.packageService.Delete(uint(id), auditMeta(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"message": "Package deleted"})
+}
+
+// packageFromRequest maps the admin-editable fields onto a package
+func packageFromRequest(r PackageRequest) *domain.Package {
+	features := make([]domain.PackageFeature, 0, len(r.Features))
+	for _, feature := range r.Features {
+		features = append(features, domain.PackageFeature{Code: feature.Code, Label: feature.Label, Value: feature.Value})
+	}
+	
+	return &domain.Package{
+		Name:           r.Name,
+		Description:    r.Description,
+		Price:          r.Price,
+		CommissionRate: r.CommissionRate,
+		MaxLevels:      r.MaxLevels,
+		TermMonths:     r.TermMonths,
+		Features:       features,
+	}
+}
+
+// Request DTOs
+
+type PackageFeatureRequest struct {
+	Code  string `json:"code" binding:"required,max=50"`
+	Label string `json:"label" binding:"required,max=200"`
+	Value string `json:"value" binding:"max=200"`
+}
+
+type PackageRequest struct {
+	Name           string                  `json:"name" binding:"required,max=100"`
+	Description    string                  `json:"description"`
+	Price          float64                 `json:"price" binding:"min=0"`
+	CommissionRate float64                 `json:"commission_rate" binding:"min=0,max=100"`
+	MaxLevels      int                     `json:"max_levels" binding:"required,min=1"`
+	TermMonths     int                     `json:"term_months" binding:"min=0"`
+	Features       []PackageFeatureRequest `json:"features" binding:"dive"`
+}
+
+type CreatePackageRequest struct {
+	PackageRequest
+	Code     string `json:"code" binding:"required,max=50"`
+	IsActive *bool  `json:"is_active"` // Defaults to true
+}
+
+type SetPackageActiveRequest struct {
+	IsActive *bool `json:"is_active" binding:"required"`
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mlm-app/backend/internal/service"
+)
+
+type SnapshotController struct {
+	snapshotService service.SnapshotService
+}
+
+func NewSnapshotController(snapshotService service.SnapshotService) *SnapshotController {
+	return &SnapshotController{
+		snapshotService: snapshotService,
+	}
+}
+
+// ClosePeriod godoc
+// @Summary Close a commission period and snapshot the genealogy (admin)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ClosePeriodRequest true "Period to close"
+// @Success 201 {object} domain.GenealogySnapshot
+// @Router /api/v1/admin/snapshots [post]
+func (ctrl *SnapshotController) ClosePeriod(c *gin.Context) {
+	var req ClosePeriodRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	snapshot, err := ctrl.snapshotService.ClosePeriod(req.Period, c.GetUint("distributor_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusCreated, snapshot)
+}
+
+// List godoc
+// @Summary List genealogy snapshots (admin)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} []domain.GenealogySnapshot
+// @Router /api/v1/admin/snapshots [get]
+func (ctrl *SnapshotController) List(c *gin.Context) {
+	snapshots, err := ctrl.snapshotService.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, snapshots)
+}
+
+// parseAsOf reads the optional as_of query parameter as a date or RFC 3339
+// timestamp, writing the error response if it is malformed
+func parseAsOf(c *gin.Context) (*time.Time, bool) {
+	value := c.Query("as_of")
+	if value == "" {
+		return nil, true
+	}
+	
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if asOf, err := time.Parse(layout, value); err == nil {
+			return &asOf, true
+		}
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "as_of must be a date (YYYY-MM-DD) or RFC 3339 timestamp"})
+	return nil, false
+}
+
+// Request DTOs
+type ClosePeriodRequest struct {
+	Period string `json:"period" binding:"required"` // YYYY-MM
+}
@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mlm-app/backend/internal/service"
+)
+
+type StatusController struct {
+	statusService service.StatusService
+}
+
+func NewStatusController(statusService service.StatusService) *StatusController {
+	return &StatusController{
+		statusService: statusService,
+	}
+}
+
+// Suspend godoc
+// @Summary Suspend a distributor with a reason code (admin)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Distributor ID"
+// @Param request body SuspendRequest true "Reason code and note"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/distributors/{id}/suspend [post]
+func (ctrl *StatusController) Suspend(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	var req SuspendRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	if err := ctrl.statusService.Suspend(uint(id), req.ReasonCode, req.Note, auditMeta(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"message": "Distributor suspended"})
+}
+
+// Unsuspend godoc
+// @Summary Lift a distributor's suspension (admin)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Distributor ID"
+// @Param request body UnsuspendRequest true "Why the suspension is lifted"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/distributors/{id}/unsuspend [post]
+func (ctrl *StatusController) Unsuspend(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	var req UnsuspendRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	if err := ctrl.statusService.Unsuspend(uint(id), req.Note, auditMeta(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"message": "Suspension lifted"})
+}
+
+// History godoc
+// @Summary List a distributor's status changes (admin)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Distributor ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(50)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/distributors/{id}/status-history [get]
+func (ctrl *StatusController) History(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	
+	var req PageQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	changes, total, err := ctrl.statusService.History(uint(id), (req.Page-1)*req.Limit, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data":  changes,
+		"total": total,
+		"page":  req.Page,
+		"limit": req.Limit,
+	})
+}
+
+// Request DTOs
+
+type SuspendRequest struct {
+	ReasonCode string `json:"reason_code" binding:"required,oneof=policy_violation fraud chargeback compliance requested other"`
+	Note       string `json:"note" binding:"max=500"`
+}
+
+type UnsuspendRequest struct {
+	Note string `json:"note" binding:"required,max=500"`
+}
@@ -38,28 +38,46 @@ type Distributor struct {
 	
 	// Authentication
 	PasswordHash      string         `gorm:"size:255;not null" json:"-"`
+	TokenVersion      int            `gorm:"default:0" json:"-"` // Bumped to invalidate every access token already issued
+	EmailVerifiedAt   *time.Time     `json:"email_verified_at"`
+	TwoFactorSecret   string         `gorm:"size:64" json:"-"` // Base32 TOTP secret; set during setup, active once TwoFactorEnabledAt is set
+	TwoFactorEnabledAt *time.Time    `json:"two_factor_enabled_at"`
+	TwoFactorLastStep int64          `gorm:"default:0" json:"-"` // Last accepted TOTP time step, so a code cannot be replayed
+	LockedUntil       *time.Time     `json:"locked_until"` // Set after too many failed logins; cleared early by an admin unlock
 	
 	// MLM Structure
 	SponsorID         *uint          `gorm:"index" json:"sponsor_id"`
 	Sponsor           *Distributor   `gorm:"foreignKey:SponsorID" json:"sponsor,omitempty"`
 	TreeType          TreeType       `gorm:"size:20;default:'binary'" json:"tree_type"`
-	Position          string         `gorm:"size:20" json:"position"` // For binary: left/right
+	PlacementID       *uint          `gorm:"uniqueIndex:idx_placement_slot" json:"placement_id"` // Binary/matrix/hybrid placement parent; differs from the sponsor after spillover
+	Placement         *Distributor   `gorm:"foreignKey:PlacementID" json:"placement,omitempty"`
+	Position          string         `gorm:"size:20;uniqueIndex:idx_placement_slot" json:"position"` // For binary: left/right
+	PlacementStatus   string         `gorm:"size:20;default:'placed';index" json:"placement_status"` // placed, pending (in the sponsor's holding tank)
+	HoldingTankExpiresAt *time.Time  `json:"holding_tank_expires_at,omitempty"` // Auto-placed after this if the sponsor has not placed them
 	Level             int            `gorm:"default:0" json:"level"`
+	BrokeAwayAt       *time.Time     `json:"broke_away_at"` // Breakaway plans: when this group split from the sponsor's
 	
 	// Business Metrics
 	TotalSales        float64        `gorm:"type:decimal(15,2);default:0" json:"total_sales"`
-	PersonalSales     float64        `gorm:"type:decimal(15,2);default:0" json:"personal_sales"`
+	PersonalSales     float64        `gorm:"type:decimal(15,2);default:0" json:"personal_sales"` // Commissionable volume of the distributor's own orders
 	TeamSales         float64        `gorm:"type:decimal(15,2);default:0" json:"team_sales"`
+	LeftLegVolume     float64        `gorm:"type:decimal(15,2);default:0" json:"left_leg_volume"` // Binary/hybrid pairing volume
+	RightLegVolume    float64        `gorm:"type:decimal(15,2);default:0" json:"right_leg_volume"`
 	TotalCommission   float64        `gorm:"type:decimal(15,2);default:0" json:"total_commission"`
 	TotalBonus        float64        `gorm:"type:decimal(15,2);default:0" json:"total_bonus"`
+	LastQualifiedAt   *time.Time     `json:"last_qualified_at"` // Last purchase with qualifying volume; inactivity counts from here, or from sign-up
 	
 	// Status and Rank
 	Role              string         `gorm:"size:20;default:'distributor'" json:"role"` // admin or distributor
-	Status            string         `gorm:"size:20;default:'active'" json:"status"` // active, inactive, suspended
+	Status            string         `gorm:"size:20;default:'active'" json:"status"` // active, inactive, suspended, terminated
+	StatusReason      string         `gorm:"size:50" json:"status_reason"` // Reason code of the last status change
+	StatusChangedAt   *time.Time     `json:"status_changed_at"`
+	KYCStatus         string         `gorm:"size:20;default:'none';index" json:"kyc_status"` // none, pending, approved, rejected; commissions are only paid once approved
 	RankID            *uint          `gorm:"index" json:"rank_id"`
 	Rank              *Rank          `gorm:"foreignKey:RankID" json:"rank,omitempty"`
-	PackageID         *uint          `gorm:"index" json:"package_id"`
+	PackageID         *uint          `gorm:"index" json:"package_id"` // Only set by a paid package order, or the default package
 	Package           *Package       `gorm:"foreignKey:PackageID" json:"package,omitempty"`
+	PackageExpiresAt  *time.Time     `gorm:"index" json:"package_expires_at"` // Falls back to the default package after this unless renewed; nil never lapses
 	
 	// Relationships
 	Downlines         []Distributor  `gorm:"foreignKey:SponsorID" json:"downlines,omitempty"`
@@ -68,6 +86,30 @@ type Distributor struct {
 	RankAchievements  []RankAchievement `gorm:"foreignKey:DistributorID" json:"rank_achievements,omitempty"`
 }
 
+// Distributor statuses. Level commissions skip anyone who is not active.
+const (
+	StatusActive     = "active"
+	StatusInactive   = "inactive"  // No qualifying purchase within the inactivity window; can still log in and buy
+	StatusSuspended  = "suspended" // Blocked by an admin
+	StatusTerminated = "terminated"
+)
+
+// Status change reason codes. The first group is set by the system, the
+// rest are chosen by an admin when suspending or terminating.
+const (
+	ReasonInactivity       = "inactivity"
+	ReasonPurchase         = "purchase"
+	ReasonSuspensionLifted = "suspension_lifted"
+	ReasonReinstated       = "reinstated"
+	
+	ReasonPolicyViolation  = "policy_violation"
+	ReasonFraud            = "fraud"
+	ReasonChargeback       = "chargeback"
+	ReasonCompliance       = "compliance"
+	ReasonRequested        = "requested" // At the distributor's own request
+	ReasonOther            = "other"
+)
+
 // Rank represents a rank in the MLM system
 type Rank struct {
 	ID                uint           `gorm:"primaryKey" json:"id"`
@@ -98,18 +140,30 @@ type Package struct {
 	CreatedAt         time.Time      `json:"created_at"`
 	UpdatedAt         time.Time      `json:"updated_at"`
 	
-	Name              string         `gorm:"size:100;not null;uniqueIndex" json:"name"`
+	// Changing a package's terms creates a new version under the same code;
+	// members keep the version they bought
+	Code              string         `gorm:"size:50;not null;uniqueIndex:idx_package_version" json:"code"` // Stable across versions, e.g. starter
+	Version           int            `gorm:"not null;default:1;uniqueIndex:idx_package_version" json:"version"`
+	SupersededAt      *time.Time     `json:"superseded_at,omitempty"` // Set once a newer version replaces this one; no longer sold
+	
+	Name              string         `gorm:"size:100;not null" json:"name"`
 	Description       string         `gorm:"type:text" json:"description"`
 	Price             float64        `gorm:"type:decimal(15,2);not null" json:"price"`
 	
 	// Benefits
 	CommissionRate    float64        `gorm:"type:decimal(5,2);default:0" json:"commission_rate"` // Percentage
 	MaxLevels         int            `gorm:"default:5" json:"max_levels"`
+	TermMonths        int            `gorm:"default:0" json:"term_months"` // Renewal term, e.g. 12 for annual; 0 never lapses
+	Features          []PackageFeature `gorm:"serializer:json;type:text" json:"features"`
 	
-	// Features (JSON stored as text)
-	Features          string         `gorm:"type:text" json:"features"`
-	
-	IsActive          bool           `gorm:"default:true" json:"is_active"`
+	IsActive          bool           `gorm:"default:true" json:"is_active"` // Inactive packages are not sold; members keep them
+}
+
+// PackageFeature is one entry of a package's feature list
+type PackageFeature struct {
+	Code              string         `json:"code"` // e.g. priority_support
+	Label             string         `json:"label"` // Shown in the storefront
+	Value             string         `json:"value,omitempty"` // Optional detail, e.g. "24/7" or "15 levels"
 }
 
 // Order represents a product order
@@ -121,6 +175,9 @@ type Order struct {
 	OrderNumber       string         `gorm:"size:50;uniqueIndex;not null" json:"order_number"`
 	DistributorID     uint           `gorm:"not null;index" json:"distributor_id"`
 	Distributor       *Distributor   `gorm:"foreignKey:DistributorID" json:"distributor,omitempty"`
+	Type              string         `gorm:"size:30;default:'product';index" json:"type"` // product, package, package_upgrade, package_renewal
+	PackageID         *uint          `gorm:"index" json:"package_id,omitempty"` // Package orders: the package activated once paid
+	Package           *Package       `gorm:"foreignKey:PackageID" json:"package,omitempty"`
 	
 	// Order Details
 	SubTotal          float64        `gorm:"type:decimal(15,2);not null" json:"sub_total"`
@@ -143,6 +200,26 @@ type Order struct {
 	OrderItems        []OrderItem    `gorm:"foreignKey:OrderID" json:"order_items,omitempty"`
 }
 
+// Order types
+const (
+	OrderTypeProduct        = "product"
+	OrderTypePackage        = "package"         // Enrolment in a package at its full price
+	OrderTypePackageUpgrade = "package_upgrade" // Prorated difference to a more expensive package
+	OrderTypePackageRenewal = "package_renewal" // Extends the current package by its term
+)
+
+// Order and payment statuses
+const (
+	OrderStatusPending    = "pending"
+	OrderStatusProcessing = "processing"
+	OrderStatusCompleted  = "completed"
+	OrderStatusCancelled  = "cancelled"
+	
+	PaymentStatusPending = "pending"
+	PaymentStatusPaid    = "paid"
+	PaymentStatusFailed  = "failed"
+)
+
 // OrderItem represents an item in an order
 type OrderItem struct {
 	ID                uint           `gorm:"primaryKey" json:"id"`
@@ -207,11 +284,17 @@ type Commission struct {
 	OrderID           *uint          `gorm:"index" json:"order_id"`
 	Order             *Order         `gorm:"foreignKey:OrderID" json:"order,omitempty"`
 	
-	Type              string         `gorm:"size:50;not null" json:"type"` // direct, level, bonus, rank_bonus
+	Type              string         `gorm:"size:50;not null" json:"type"` // direct, level, generation, breakaway_override, bonus, rank_bonus
 	Level             int            `gorm:"default:0" json:"level"`
 	Amount            float64        `gorm:"type:decimal(15,2);not null" json:"amount"`
 	Percentage        float64        `gorm:"type:decimal(5,2);default:0" json:"percentage"`
 	
+	// Breakdown of Amount into the plan rate and the earner's rank bonus
+	BaseAmount        float64        `gorm:"type:decimal(15,2);default:0" json:"base_amount"`
+	BasePercentage    float64        `gorm:"type:decimal(5,2);default:0" json:"base_percentage"`
+	BonusAmount       float64        `gorm:"type:decimal(15,2);default:0" json:"bonus_amount"`
+	BonusPercentage   float64        `gorm:"type:decimal(5,2);default:0" json:"bonus_percentage"` // Rank.CommissionBonus at calculation time
+	
 	FromDistributorID *uint          `gorm:"index" json:"from_distributor_id"` // Who generated this commission
 	FromDistributor   *Distributor   `gorm:"foreignKey:FromDistributorID" json:"from_distributor,omitempty"`
 	
@@ -255,6 +338,351 @@ type Payout struct {
 	Notes             string         `gorm:"type:text" json:"notes"`
 }
 
+// BreakawayEvent records a distributor's group splitting off from their sponsor's group
+type BreakawayEvent struct {
+	ID                uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt         time.Time      `json:"created_at"`
+	
+	DistributorID     uint           `gorm:"not null;index" json:"distributor_id"`
+	Distributor       *Distributor   `gorm:"foreignKey:DistributorID" json:"distributor,omitempty"`
+	
+	SponsorID         uint           `gorm:"not null;index" json:"sponsor_id"` // Leader whose group volume was reduced
+	Sponsor           *Distributor   `gorm:"foreignKey:SponsorID" json:"sponsor,omitempty"`
+	
+	RankID            uint           `gorm:"not null" json:"rank_id"` // Rank that triggered the breakaway
+	Rank              *Rank          `gorm:"foreignKey:RankID" json:"rank,omitempty"`
+	
+	GroupVolume       float64        `gorm:"type:decimal(15,2);default:0" json:"group_volume"` // Volume that left the sponsor's group
+	BrokeAwayAt       time.Time      `gorm:"not null" json:"broke_away_at"`
+}
+
+// AuditLog records a sensitive change with its before and after state.
+// Entries are append-only and are written in the same transaction as the
+// change they describe.
+type AuditLog struct {
+	ID                uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt         time.Time      `gorm:"index" json:"created_at"`
+	
+	ActorID           *uint          `gorm:"index" json:"actor_id"` // Who made the change; nil for system jobs
+	ImpersonatorID    *uint          `gorm:"index" json:"impersonator_id"` // Admin acting as ActorID, if any
+	APIKeyID          *uint          `gorm:"index" json:"api_key_id"` // Set when an integration made the change
+	Action            string         `gorm:"size:100;not null;index" json:"action"` // e.g. tree.move
+	EntityType        string         `gorm:"size:50;not null;index:idx_audit_entity" json:"entity_type"`
+	EntityID          uint           `gorm:"not null;index:idx_audit_entity" json:"entity_id"`
+	
+	Before            string         `gorm:"type:text" json:"before"` // JSON snapshot
+	After             string         `gorm:"type:text" json:"after"`  // JSON snapshot
+	Changes           string         `gorm:"type:text" json:"changes"` // JSON map of changed fields to {"from", "to"}
+	Reason            string         `gorm:"size:500" json:"reason"`
+	
+	IPAddress         string         `gorm:"size:45" json:"ip_address"`
+	RequestID         string         `gorm:"size:64;index" json:"request_id"`
+}
+
+// RefreshToken is a server-side login session. Only a hash of the token is
+// stored. Every refresh rotates it within the same family, and presenting a
+// token that was already rotated revokes the whole family.
+type RefreshToken struct {
+	ID                uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt         time.Time      `json:"created_at"`
+	
+	DistributorID     uint           `gorm:"not null;index" json:"distributor_id"`
+	FamilyID          string         `gorm:"size:64;not null;index" json:"family_id"` // Shared by every rotation of one login
+	TokenHash         string         `gorm:"size:64;not null;uniqueIndex" json:"-"` // SHA-256 of the token
+	ExpiresAt         time.Time      `gorm:"not null" json:"expires_at"`
+	RevokedAt         *time.Time     `json:"revoked_at"`
+	ReplacedByID      *uint          `json:"replaced_by_id"`
+	TwoFactor         bool           `gorm:"default:false" json:"two_factor"` // Session was opened with a second factor
+	
+	UserAgent         string         `gorm:"size:255" json:"user_agent"`
+	IPAddress         string         `gorm:"size:45" json:"ip_address"`
+}
+
+// Account token purposes
+const (
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposePasswordReset     = "password_reset"
+)
+
+// AccountToken is a single-use, expiring token emailed to a distributor to
+// verify their address or reset their password. Only its hash is stored.
+type AccountToken struct {
+	ID                uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt         time.Time      `json:"created_at"`
+	
+	DistributorID     uint           `gorm:"not null;index" json:"distributor_id"`
+	Purpose           string         `gorm:"size:30;not null" json:"purpose"`
+	TokenHash         string         `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ExpiresAt         time.Time      `gorm:"not null" json:"expires_at"`
+	UsedAt            *time.Time     `json:"used_at"`
+}
+
+// API key scopes
+const (
+	ScopeOrdersRead     = "orders:read"
+	ScopeOrdersWrite    = "orders:write" // Record payments
+	ScopeInventoryRead  = "inventory:read"
+	ScopeInventoryWrite = "inventory:write"
+)
+
+// APIKey lets another system call the API without a distributor login.
+// Only a hash of the key is stored; the plaintext is shown once at creation.
+type APIKey struct {
+	ID                uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	
+	Name              string         `gorm:"size:100;not null" json:"name"` // e.g. the system using it
+	Prefix            string         `gorm:"size:20;not null;index" json:"prefix"` // Leading characters of the key, for identifying it
+	KeyHash           string         `gorm:"size:64;not null;uniqueIndex" json:"-"` // SHA-256 of the key
+	Scopes            string         `gorm:"size:500;not null" json:"scopes"` // Comma separated, e.g. orders:read,inventory:write
+	
+	ExpiresAt         *time.Time     `json:"expires_at"` // Nil never expires
+	RevokedAt         *time.Time     `json:"revoked_at"`
+	LastUsedAt        *time.Time     `json:"last_used_at"`
+	LastUsedIP        string         `gorm:"size:45" json:"last_used_ip"`
+	CreatedByID       *uint          `json:"created_by_id"`
+}
+
+// NewAPIKey is returned once when a key is created, with the only copy of
+// the plaintext key
+type NewAPIKey struct {
+	APIKey            *APIKey        `json:"api_key"`
+	Key               string         `json:"key"`
+}
+
+// Login attempt outcomes
+const (
+	LoginResultSuccess            = "success"
+	LoginResultChallengeIssued    = "challenge_issued" // Password accepted, waiting for the second factor
+	LoginResultInvalidCredentials = "invalid_credentials"
+	LoginResultInvalidTwoFactor   = "invalid_two_factor" // Wrong TOTP or recovery code on a challenge
+	LoginResultThrottled          = "throttled"
+	LoginResultLocked             = "locked"
+	LoginResultRejected           = "rejected" // Correct password but the account may not log in
+)
+
+// LoginAttempt records a password login for security review
+type LoginAttempt struct {
+	ID                uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt         time.Time      `gorm:"index" json:"created_at"`
+	Email             string         `gorm:"size:255;not null;index" json:"email"`
+	DistributorID     *uint          `gorm:"index" json:"distributor_id"` // Nil when the email matched no account
+	IPAddress         string         `gorm:"size:45;index" json:"ip_address"`
+	UserAgent         string         `gorm:"size:255" json:"user_agent"`
+	Success           bool           `gorm:"index" json:"success"`
+	Result            string         `gorm:"size:30;not null" json:"result"`
+}
+
+// RecoveryCode is a single-use fallback for a lost authenticator. Only its
+// hash is stored.
+type RecoveryCode struct {
+	ID                uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt         time.Time      `json:"created_at"`
+	DistributorID     uint           `gorm:"not null;index" json:"distributor_id"`
+	CodeHash          string         `gorm:"size:64;not null" json:"-"`
+	UsedAt            *time.Time     `json:"used_at"`
+}
+
+// TwoFactorSetup is returned when a distributor starts TOTP enrollment
+type TwoFactorSetup struct {
+	Secret            string         `json:"secret"`
+	ProvisioningURI   string         `json:"provisioning_uri"` // Render as a QR code for authenticator apps
+}
+
+// LoginChallenge is returned by a password login that still needs a second factor
+type LoginChallenge struct {
+	ChallengeToken    string         `json:"challenge_token"`
+	ExpiresAt         time.Time      `json:"expires_at"`
+}
+
+// TokenPair is the credentials handed out on login and refresh
+type TokenPair struct {
+	AccessToken       string         `json:"token"`
+	ExpiresAt         time.Time      `json:"expires_at"`
+	RefreshToken      string         `json:"refresh_token"`
+	RefreshExpiresAt  time.Time      `json:"refresh_expires_at"`
+}
+
+// ImpersonationToken lets an admin see the API as a distributor. It has no
+// refresh token and cannot be used for writes.
+type ImpersonationToken struct {
+	AccessToken       string         `json:"token"`
+	ExpiresAt         time.Time      `json:"expires_at"`
+	Distributor       *Distributor   `json:"distributor"`
+}
+
+// Termination records a distributor being terminated and where their downline went
+type Termination struct {
+	ID                uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	
+	DistributorID     uint           `gorm:"not null;index" json:"distributor_id"`
+	RolledUpToID      uint           `gorm:"not null" json:"rolled_up_to_id"` // Active upline or house account that took the children
+	PlacementID       *uint          `json:"placement_id"` // Slot the distributor held, freed for spillover until a reinstatement
+	Position          string         `gorm:"size:20" json:"position"`
+	ActorID           *uint          `json:"actor_id"`
+	Reason            string         `gorm:"size:500" json:"reason"`
+	
+	TerminatedAt      time.Time      `gorm:"not null" json:"terminated_at"`
+	ReversibleUntil   time.Time      `gorm:"not null" json:"reversible_until"`
+	ReversedAt        *time.Time     `json:"reversed_at"`
+	
+	RollUps           []TerminationRollUp `gorm:"foreignKey:TerminationID" json:"roll_ups,omitempty"`
+}
+
+// TerminationRollUp keeps the original sponsorship of a child moved by a termination
+type TerminationRollUp struct {
+	ID                  uint         `gorm:"primaryKey" json:"id"`
+	TerminationID       uint         `gorm:"not null;index" json:"termination_id"`
+	DistributorID       uint         `gorm:"not null;index" json:"distributor_id"`
+	OriginalSponsorID   uint         `gorm:"not null" json:"original_sponsor_id"`
+	OriginalPlacementID *uint        `json:"original_placement_id"`
+	OriginalPosition    string       `gorm:"size:20" json:"original_position"`
+	OriginalLevel       int          `json:"original_level"`
+}
+
+// StatusChange is one entry of a distributor's status history
+type StatusChange struct {
+	ID                uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt         time.Time      `gorm:"index" json:"created_at"`
+	
+	DistributorID     uint           `gorm:"not null;index" json:"distributor_id"`
+	FromStatus        string         `gorm:"size:20" json:"from_status"`
+	ToStatus          string         `gorm:"size:20;not null" json:"to_status"`
+	ReasonCode        string         `gorm:"size:50;not null" json:"reason_code"`
+	Note              string         `gorm:"size:500" json:"note"`
+	ActorID           *uint          `json:"actor_id"` // Nil when an automatic rule made the change
+}
+
+// KYC statuses. Distributors start at none; a submission starts as a draft
+// while documents are uploaded and is pending once submitted for review.
+const (
+	KYCStatusNone     = "none"
+	KYCStatusDraft    = "draft"
+	KYCStatusPending  = "pending"
+	KYCStatusApproved = "approved"
+	KYCStatusRejected = "rejected"
+)
+
+// KYC document types
+const (
+	KYCDocumentPassport       = "passport"
+	KYCDocumentNationalID     = "national_id"
+	KYCDocumentDriversLicense = "drivers_license"
+	KYCDocumentProofOfAddress = "proof_of_address"
+	KYCDocumentTaxForm        = "tax_form"
+)
+
+// KYCSubmission is a distributor's identity documents and tax details, as
+// reviewed by an admin. A rejected distributor starts a new submission.
+type KYCSubmission struct {
+	ID                uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	
+	DistributorID     uint           `gorm:"not null;index" json:"distributor_id"`
+	Status            string         `gorm:"size:20;not null;index" json:"status"` // draft, pending, approved, rejected
+	
+	// Tax details
+	LegalName         string         `gorm:"size:200" json:"legal_name"`
+	TaxCountry        string         `gorm:"size:2" json:"tax_country"` // ISO 3166-1 alpha-2
+	TaxIDType         string         `gorm:"size:20" json:"tax_id_type"` // e.g. ssn, ein, vat
+	TaxIDHash         string         `gorm:"size:64;index" json:"-"` // Keyed hash of the normalized tax ID; the ID itself is not stored
+	TaxIDLast4        string         `gorm:"size:4" json:"tax_id_last4"`
+	
+	// Review
+	SubmittedAt       *time.Time     `json:"submitted_at"`
+	ReviewedAt        *time.Time     `json:"reviewed_at"`
+	ReviewedByID      *uint          `json:"reviewed_by_id"`
+	RejectionReason   string         `gorm:"size:500" json:"rejection_reason,omitempty"`
+	
+	Documents         []KYCDocument  `gorm:"foreignKey:SubmissionID" json:"documents,omitempty"`
+}
+
+// KYCDocument is an uploaded identity document. The file is kept in the
+// file store, not the database.
+type KYCDocument struct {
+	ID                uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt         time.Time      `json:"created_at"`
+	
+	SubmissionID      uint           `gorm:"not null;index" json:"submission_id"`
+	Type              string         `gorm:"size:30;not null" json:"type"` // passport, national_id, drivers_license, proof_of_address, tax_form
+	FileName          string         `gorm:"size:255" json:"file_name"` // As uploaded
+	ContentType       string         `gorm:"size:100" json:"content_type"` // Detected from the content, not the upload headers
+	Size              int64          `json:"size"`
+	SHA256            string         `gorm:"size:64" json:"sha256"`
+	StorageKey        string         `gorm:"size:255;not null" json:"-"`
+}
+
+// GenealogySnapshot is a frozen copy of the genealogy, ranks and volumes
+// taken when a commission period closes
+type GenealogySnapshot struct {
+	ID                uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt         time.Time      `json:"created_at"`
+	
+	Period            string         `gorm:"size:20;uniqueIndex;not null" json:"period"` // e.g. 2026-09
+	PeriodStart       time.Time      `gorm:"not null;index" json:"period_start"`
+	PeriodEnd         time.Time      `gorm:"not null;index" json:"period_end"` // Exclusive
+	TakenAt           time.Time      `gorm:"not null" json:"taken_at"`
+	TakenBy           *uint          `json:"taken_by"` // Admin who closed the period; nil for the scheduled close
+	CaptureDelaySeconds int64        `json:"capture_delay_seconds"` // Time between the period end and the capture; changes made in between are included
+	MemberCount       int            `json:"member_count"`
+}
+
+// GenealogySnapshotEntry is one distributor as they stood in a snapshot.
+// PlacementID is the effective placement parent, with legacy binary and
+// matrix rows already resolved to their sponsor.
+type GenealogySnapshotEntry struct {
+	ID                uint           `gorm:"primaryKey" json:"id"`
+	SnapshotID        uint           `gorm:"not null;uniqueIndex:idx_snapshot_distributor;index:idx_snapshot_sponsor;index:idx_snapshot_placement" json:"snapshot_id"`
+	DistributorID     uint           `gorm:"not null;uniqueIndex:idx_snapshot_distributor" json:"distributor_id"`
+	
+	FirstName         string         `gorm:"size:100" json:"first_name"`
+	LastName          string         `gorm:"size:100" json:"last_name"`
+	Email             string         `gorm:"size:255" json:"email"`
+	JoinedAt          time.Time      `json:"joined_at"`
+	
+	SponsorID         *uint          `gorm:"index:idx_snapshot_sponsor" json:"sponsor_id"`
+	PlacementID       *uint          `gorm:"index:idx_snapshot_placement" json:"placement_id"`
+	TreeType          TreeType       `gorm:"size:20" json:"tree_type"`
+	Position          string         `gorm:"size:20" json:"position"`
+	Level             int            `json:"level"`
+	
+	Status            string         `gorm:"size:20" json:"status"`
+	RankID            *uint          `json:"rank_id"`
+	RankName          string         `gorm:"size:100" json:"rank_name"`
+	
+	TotalSales        float64        `gorm:"type:decimal(15,2);default:0" json:"total_sales"`
+	PersonalSales     float64        `gorm:"type:decimal(15,2);default:0" json:"personal_sales"`
+	TeamSales         float64        `gorm:"type:decimal(15,2);default:0" json:"team_sales"`
+	LeftLegVolume     float64        `gorm:"type:decimal(15,2);default:0" json:"left_leg_volume"`
+	RightLegVolume    float64        `gorm:"type:decimal(15,2);default:0" json:"right_leg_volume"`
+	TotalCommission   float64        `gorm:"type:decimal(15,2);default:0" json:"total_commission"`
+	TotalBonus        float64        `gorm:"type:decimal(15,2);default:0" json:"total_bonus"`
+}
+
+// DistributorStats summarises a distributor's standing, either live or as of
+// a snapshot
+type DistributorStats struct {
+	DistributorID     uint           `json:"distributor_id"`
+	SnapshotID        *uint          `json:"snapshot_id,omitempty"`
+	Period            string         `json:"period,omitempty"`
+	Level             int            `json:"level"`
+	Status            string         `json:"status"`
+	RankName          string         `json:"rank_name"`
+	TotalSales        float64        `json:"total_sales"`
+	PersonalSales     float64        `json:"personal_sales"`
+	TeamSales         float64        `json:"team_sales"`
+	LeftLegVolume     float64        `json:"left_leg_volume"`
+	RightLegVolume    float64        `json:"right_leg_volume"`
+	TotalCommission   float64        `json:"total_commission"`
+	TotalBonus        float64        `json:"total_bonus"`
+	DirectDownlines   int64          `json:"direct_downlines"`
+	ActiveDownlines   int64          `json:"active_downlines"`
+}
+
 // TreeNode represents a node in the MLM tree for visualization
 type TreeNode struct {
 	ID                uint           `json:"id"`
@@ -262,10 +690,69 @@ type TreeNode struct {
 	Name              string         `json:"name"`
 	Email             string         `json:"email"`
 	SponsorID         *uint          `json:"sponsor_id"`
+	PlacementID       *uint          `json:"placement_id,omitempty"`
 	Position          string         `json:"position"`
 	Level             int            `json:"level"`
 	TotalSales        float64        `json:"total_sales"`
 	RankName          string         `json:"rank_name"`
 	Status            string         `json:"status"`
+	ChildCount        *int64         `json:"child_count,omitempty"` // Set by the lazy-loading endpoints so clients know whether a node can expand
 	Children          []TreeNode     `json:"children,omitempty"`
 }
+
+// DownlineFilter narrows a search of a distributor's organization. Zero
+// values mean no constraint.
+type DownlineFilter struct {
+	Query             string     // Matched against name and email
+	RankID            *uint
+	Status            string
+	JoinedFrom        *time.Time
+	JoinedTo          *time.Time
+	MinDepth          int        // Relative to the searching distributor; direct members are depth 1
+	MaxDepth          int
+	Leg               string     // left or right: the side of the searcher the member sits on
+	MinPersonalSales  *float64
+	MaxPersonalSales  *float64
+	MinTeamSales      *float64
+	MaxTeamSales      *float64
+	SortBy            string     // name, email, joined, depth, rank, personal_sales, team_sales, total_sales
+	SortDesc          bool
+	Offset            int
+	Limit             int
+}
+
+// DownlineMember is a distributor found by a downline search, with their
+// position relative to the searching distributor
+type DownlineMember struct {
+	Distributor
+	Depth             int            `json:"depth"`
+	Leg               string         `json:"leg"`
+}
+
+// Genealogy integrity violation types
+const (
+	ViolationSponsorCycle      = "sponsor_cycle"
+	ViolationPlacementCycle    = "placement_cycle"
+	ViolationOrphan            = "orphan"
+	ViolationLevelMismatch     = "level_mismatch"
+	ViolationOverCapacity      = "over_capacity"
+	ViolationDuplicatePosition = "duplicate_position"
+	ViolationInvalidPosition   = "invalid_position"
+)
+
+// IntegrityViolation is a single problem found in the genealogy
+type IntegrityViolation struct {
+	Type              string         `json:"type"`
+	DistributorID     uint           `json:"distributor_id"`
+	Details           string         `json:"details"`
+	Repairable        bool           `json:"repairable"`
+	Repaired          bool           `json:"repaired"`
+}
+
+// IntegrityReport summarises a full genealogy scan
+type IntegrityReport struct {
+	CheckedAt         time.Time      `json:"checked_at"`
+	Scanned           int            `json:"scanned"`
+	Counts            map[string]int `json:"counts"`
+	Violations        []IntegrityViolation `json:"violations"`
+}
@@ -1,24 +1,69 @@
 package middleware
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/mlm-app/backend/internal/config"
+	"github.com/mlm-app/backend/internal/domain"
+	"github.com/mlm-app/backend/pkg/jwtkeys"
 )
 
 type Claims struct {
 	DistributorID uint   `json:"distributor_id"`
 	Email         string `json:"email"`
 	Role          string `json:"role"` // admin or distributor
+	TokenVersion  int    `json:"ver"`  // Must match the distributor's current token version
+	TwoFactor     bool   `json:"mfa,omitempty"` // Issued after a TOTP or recovery code check
+	Purpose       string `json:"purpose,omitempty"` // Set on restricted tokens such as login challenges, which cannot call the API
+	
+	// Set when an admin is acting as the distributor; the admin's own token
+	// version is checked too, so logging the admin out ends the session
+	ImpersonatorID      uint `json:"imp,omitempty"`
+	ImpersonatorVersion int  `json:"imp_ver,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+// challengePurpose marks the token handed out between the password and the
+// second factor of a two-step login
+const challengePurpose = "2fa_challenge"
+
+// APIKeyPrefix marks a bearer credential as an API key rather than a JWT
+const APIKeyPrefix = "mlmk_"
+
+// Values of the auth_type context key
+const (
+	AuthTypeUser   = "user"
+	AuthTypeAPIKey = "api_key"
+)
+
+// APIKeyAuthenticator resolves an API key presented by another system
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(rawKey, ipAddress string) (*domain.APIKey, error)
+}
+
+// TokenValidator re-checks on every request that the subject of a valid
+// token may still use it, so revocation and suspension apply immediately
+type TokenValidator interface {
+	ValidateAccess(distributorID uint, tokenVersion int) error
+}
+
+// AuthMiddleware authenticates a distributor by bearer JWT. When apiKeys is
+// set it also accepts API keys, either as the bearer credential or in the
+// X-API-Key header; pass nil on routes that act on behalf of a distributor.
+func AuthMiddleware(keys *jwtkeys.KeySet, validator TokenValidator, apiKeys APIKeyAuthenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if apiKeys != nil {
+			if rawKey := apiKeyFromRequest(c); rawKey != "" {
+				authenticateAPIKey(c, apiKeys, rawKey)
+				return
+			}
+		}
+		
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
@@ -37,10 +82,7 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 		tokenString := parts[1]
 		
 		// Parse and validate token
-		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-			return []byte(cfg.JWT.Secret), nil
-		})
-		
+		token, err := keys.Parse(tokenString, &Claims{})
 		if err != nil || !token.Valid {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
@@ -48,31 +90,178 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 		}
 		
 		// Extract claims
-		if claims, ok := token.Claims.(*Claims); ok {
-			c.Set("distributor_id", claims.DistributorID)
-			c.Set("email", claims.Email)
-			c.Set("role", claims.Role)
-		} else {
+		claims, ok := token.Claims.(*Claims)
+		if !ok || claims.Purpose != "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
 			c.Abort()
 			return
 		}
 		
+		if err := validator.ValidateAccess(claims.DistributorID, claims.TokenVersion); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		
+		c.Set("distributor_id", claims.DistributorID)
+		c.Set("email", claims.Email)
+		c.Set("role", claims.Role)
+		c.Set("two_factor", claims.TwoFactor)
+		c.Set("auth_type", AuthTypeUser)
+		
+		if claims.ImpersonatorID != 0 {
+			impersonate(c, validator, claims)
+			return
+		}
+		
+		c.Next()
+	}
+}
+
+// impersonate runs the rest of the chain for an admin acting as a
+// distributor. Sessions are read-only and every request is logged against
+// the admin.
+func impersonate(c *gin.Context, validator TokenValidator, claims *Claims) {
+	if err := validator.ValidateAccess(claims.ImpersonatorID, claims.ImpersonatorVersion); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "impersonation session ended: " + err.Error()})
+		c.Abort()
+		return
+	}
+	c.Set("impersonator_id", claims.ImpersonatorID)
+	
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		log.Printf("[impersonation] admin=%d distributor=%d blocked %s %s request_id=%s",
+			claims.ImpersonatorID, claims.DistributorID, c.Request.Method, c.Request.URL.Path, c.GetString("request_id"))
+		c.JSON(http.StatusForbidden, gin.H{"error": "Impersonation sessions are read-only"})
+		c.Abort()
+		return
+	}
+	
+	c.Next()
+	log.Printf("[impersonation] admin=%d distributor=%d %s %s status=%d request_id=%s",
+		claims.ImpersonatorID, claims.DistributorID, c.Request.Method, c.Request.URL.Path, c.Writer.Status(), c.GetString("request_id"))
+}
+
+func apiKeyFromRequest(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+	if bearer := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); strings.HasPrefix(bearer, APIKeyPrefix) {
+		return bearer
+	}
+	return ""
+}
+
+// authenticateAPIKey runs the rest of the chain as the key and logs the call
+// against it, since there is no distributor to attribute it to
+func authenticateAPIKey(c *gin.Context, apiKeys APIKeyAuthenticator, rawKey string) {
+	key, err := apiKeys.AuthenticateAPIKey(rawKey, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		c.Abort()
+		return
+	}
+	
+	c.Set("auth_type", AuthTypeAPIKey)
+	c.Set("api_key_id", key.ID)
+	c.Set("api_key_name", key.Name)
+	c.Set("scopes", strings.Split(key.Scopes, ","))
+	
+	start := time.Now()
+	c.Next()
+	log.Printf("[api-key] id=%d name=%q %s %s status=%d ip=%s latency=%s request_id=%s",
+		key.ID, key.Name, c.Request.Method, c.Request.URL.Path, c.Writer.Status(), c.ClientIP(), time.Since(start), c.GetString("request_id"))
+}
+
+// RequireScope admits API keys holding scope, and admins signed in with a
+// second factor. It must run after AuthMiddleware.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_type") == AuthTypeAPIKey {
+			for _, granted := range c.GetStringSlice("scopes") {
+				if granted == scope {
+					c.Next()
+					return
+				}
+			}
+			c.JSON(http.StatusForbidden, gin.H{"error": "API key lacks the " + scope + " scope"})
+			c.Abort()
+			return
+		}
+		
+		if c.GetString("role") != "admin" || !c.GetBool("two_factor") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			c.Abort()
+			return
+		}
+		
 		c.Next()
 	}
 }
 
-func GenerateToken(distributorID uint, email string, role string, cfg *config.Config) (string, error) {
-	claims := Claims{
+// RequireRole rejects requests whose token does not carry one of the given roles.
+// It must run after AuthMiddleware.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+		
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		c.Abort()
+	}
+}
+
+// RequireTwoFactor rejects tokens that were issued without a second factor.
+// It must run after AuthMiddleware.
+func RequireTwoFactor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool("two_factor") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication required"})
+			c.Abort()
+			return
+		}
+		
+		c.Next()
+	}
+}
+
+// GenerateToken signs claims as an access token that expires at expiresAt
+func GenerateToken(claims Claims, expiresAt time.Time, keys *jwtkeys.KeySet) (string, error) {
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+	}
+	
+	return keys.Sign(claims)
+}
+
+// GenerateChallengeToken issues the short-lived token that carries a
+// password-verified login over to the second-factor step
+func GenerateChallengeToken(distributorID uint, tokenVersion int, expiresAt time.Time, keys *jwtkeys.KeySet) (string, error) {
+	return GenerateToken(Claims{
 		DistributorID: distributorID,
-		Email:         email,
-		Role:          role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(cfg.JWT.Expiry)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+		TokenVersion:  tokenVersion,
+		Purpose:       challengePurpose,
+	}, expiresAt, keys)
+}
+
+// ParseChallengeToken validates a challenge token and returns its claims
+func ParseChallengeToken(tokenString string, keys *jwtkeys.KeySet) (*Claims, error) {
+	token, err := keys.Parse(tokenString, &Claims{})
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid or expired challenge")
 	}
 	
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(cfg.JWT.Secret))
+	claims, ok := token.Claims.(*Claims)
+	if !ok || claims.Purpose != challengePurpose {
+		return nil, errors.New("invalid or expired challenge")
+	}
+	return claims, nil
 }
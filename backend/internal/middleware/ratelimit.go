@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mlm-app/backend/pkg/ratelimit"
+)
+
+// RateLimit allows at most limit requests per client IP in each window for
+// the routes it guards. scope keeps the counters of different route groups apart.
+func RateLimit(store ratelimit.Store, scope string, limit int, window time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		entry, err := store.Hit(scope+":ip:"+c.ClientIP(), window)
+		if err != nil {
+			// Fail open: an unavailable limiter store should not lock everyone out
+			c.Next()
+			return
+		}
+		
+		if entry.Count > limit {
+			retryAfter := int(math.Ceil(time.Until(entry.ResetAt).Seconds()))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, please try again later"})
+			c.Abort()
+			return
+		}
+		
+		c.Next()
+	}
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mlm-app/backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type AccountTokenRepository interface {
+	Create(token *domain.AccountToken) error
+	FindByHash(purpose, tokenHash string) (*domain.AccountToken, error)
+	MarkUsed(id uint) (bool, error)
+	InvalidateOutstanding(distributorID uint, purpose string) error
+}
+
+type accountTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewAccountTokenRepository(db *gorm.DB) AccountTokenRepository {
+	return &accountTokenRepository{db: db}
+}
+
+func (r *accountTokenRepository) Create(token *domain.AccountToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *accountTokenRepository) FindByHash(purpose, tokenHash string) (*domain.AccountToken, error) {
+	var token domain.AccountToken
+	err := r.db.Where("purpose = ? AND token_hash = ?", purpose, tokenHash).First(&token).Error
+	
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("token not found")
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkUsed consumes the token, reporting false if it had already been used
+func (r *accountTokenRepository) MarkUsed(id uint) (bool, error) {
+	result := r.db.Model(&domain.AccountToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+// InvalidateOutstanding consumes every unused token of a purpose, so only
+// the most recently sent link works
+func (r *accountTokenRepository) InvalidateOutstanding(distributorID uint, purpose string) error {
+	return r.db.Model(&domain.AccountToken{}).
+		Where("distributor_id = ? AND purpose = ? AND used_at IS NULL", distributorID, purpose).
+		Update("used_at", time.Now()).Error
+}
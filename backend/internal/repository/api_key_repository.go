@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mlm-app/backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+// lastUsedResolution limits how often a busy key's last-used time is written
+const lastUsedResolution = time.Minute
+
+type APIKeyRepository interface {
+	WithTx(tx *Tx) APIKeyRepository
+	Create(key *domain.APIKey) error
+	FindByID(id uint) (*domain.APIKey, error)
+	FindByHash(keyHash string) (*domain.APIKey, error)
+	List() ([]domain.APIKey, error)
+	Revoke(id uint) error
+	TouchLastUsed(id uint, ipAddress string) error
+}
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func (r *apiKeyRepository) WithTx(tx *Tx) APIKeyRepository {
+	return &apiKeyRepository{db: tx.db}
+}
+
+func (r *apiKeyRepository) Create(key *domain.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+func (r *apiKeyRepository) FindByID(id uint) (*domain.APIKey, error) {
+	var key domain.APIKey
+	err := r.db.First(&key, id).Error
+	
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("API key not found")
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) FindByHash(keyHash string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	err := r.db.Where("key_hash = ?", keyHash).First(&key).Error
+	
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("API key not found")
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) List() ([]domain.APIKey, error) {
+	var keys []domain.APIKey
+	err := r.db.Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *apiKeyRepository) Revoke(id uint) error {
+	return r.db.Model(&domain.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+// TouchLastUsed records a use of the key, at most once per
+// lastUsedResolution so every request does not cost a write
+func (r *apiKeyRepository) TouchLastUsed(id uint, ipAddress string) error {
+	now := time.Now()
+	return r.db.Model(&domain.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, now.Add(-lastUsedResolution)).
+		Updates(map[string]interface{}{"last_used_at": now, "last_used_ip": ipAddress}).Error
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/mlm-app/backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+// AuditFilter narrows an audit log query; zero values match everything
+type AuditFilter struct {
+	EntityType string
+	EntityID   *uint
+	ActorID    *uint
+	Action     string
+	RequestID  string
+	From       *time.Time
+	To         *time.Time
+}
+
+// AuditRepository is append-only: entries can be written and read but never
+// changed or removed.
+type AuditRepository interface {
+	WithTx(tx *Tx) AuditRepository
+	Create(entry *domain.AuditLog) error
+	ListByEntity(entityType string, entityID uint) ([]domain.AuditLog, error)
+	List(filter AuditFilter, offset, limit int) ([]domain.AuditLog, int64, error)
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) WithTx(tx *Tx) AuditRepository {
+	return &auditRepository{db: tx.db}
+}
+
+func (r *auditRepository) Create(entry *domain.AuditLog) error {
+	return r.db.Create(entry).Error
+}
+
+func (r *auditRepository) ListByEntity(entityType string, entityID uint) ([]domain.AuditLog, error) {
+	var entries []domain.AuditLog
+	err := r.db.Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Order("created_at DESC").
+		Find(&entries).Error
+	return entries, err
+}
+
+func (r *auditRepository) List(filter AuditFilter, offset, limit int) ([]domain.AuditLog, int64, error) {
+	query := r.db.Model(&domain.AuditLog{})
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != nil {
+		query = query.Where("entity_id = ?", *filter.EntityID)
+	}
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	var entries []domain.AuditLog
+	err := query.Order("created_at DESC, id DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&entries).Error
+	return entries, total, err
+}
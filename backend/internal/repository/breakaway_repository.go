@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"github.com/mlm-app/backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type BreakawayRepository interface {
+	WithTx(tx *Tx) BreakawayRepository
+	Create(event *domain.BreakawayEvent) error
+	List(offset, limit int) ([]domain.BreakawayEvent, int64, error)
+	ListBySponsor(sponsorID uint) ([]domain.BreakawayEvent, error)
+}
+
+type breakawayRepository struct {
+	db *gorm.DB
+}
+
+func NewBreakawayRepository(db *gorm.DB) BreakawayRepository {
+	return &breakawayRepository{db: db}
+}
+
+func (r *breakawayRepository) WithTx(tx *Tx) BreakawayRepository {
+	return &breakawayRepository{db: tx.db}
+}
+
+func (r *breakawayRepository) Create(event *domain.BreakawayEvent) error {
+	return r.db.Create(event).Error
+}
+
+func (r *breakawayRepository) List(offset, limit int) ([]domain.BreakawayEvent, int64, error) {
+	var events []domain.BreakawayEvent
+	var total int64
+	
+	err := r.db.Model(&domain.BreakawayEvent{}).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	
+	err = r.db.Preload("Distributor").
+		Preload("Sponsor").
+		Preload("Rank").
+		Offset(offset).
+		Limit(limit).
+		Order("broke_away_at DESC").
+		Find(&events).Error
+	
+	return events, total, err
+}
+
+func (r *breakawayRepository) ListBySponsor(sponsorID uint) ([]domain.BreakawayEvent, error) {
+	var events []domain.BreakawayEvent
+	err := r.db.Where("sponsor_id = ?", sponsorID).
+		Preload("Distributor").
+		Preload("Rank").
+		Order("broke_away_at DESC").
+		Find(&events).Error
+	return events, err
+}
@@ -8,6 +8,7 @@ import (
 )
 
 type CommissionRepository interface {
+	WithTx(tx *Tx) CommissionRepository
 	Create(commission *domain.Commission) error
 	FindByID(id uint) (*domain.Commission, error)
 	Update(commission *domain.Commission) error
@@ -26,6 +27,10 @@ func NewCommissionRepository(db *gorm.DB) CommissionRepository {
 	return &commissionRepository{db: db}
 }
 
+func (r *commissionRepository) WithTx(tx *Tx) CommissionRepository {
+	return &commissionRepository{db: tx.db}
+}
+
 func (r *commissionRepository) Create(commission *domain.Commission) error {
 	return r.db.Create(commission).Error
 }
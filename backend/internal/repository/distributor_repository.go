@@ -2,26 +2,70 @@ package repository
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/mlm-app/backend/internal/domain"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrPositionTaken is returned by Create when another distributor already
+// holds the same slot under the same placement parent
+var ErrPositionTaken = errors.New("position already taken")
+
+// errDuplicateEntry is MySQL's ER_DUP_ENTRY
+const errDuplicateEntry = 1062
+
 type DistributorRepository interface {
+	WithTx(tx *Tx) DistributorRepository
 	Create(distributor *domain.Distributor) error
 	FindByID(id uint) (*domain.Distributor, error)
+	FindByIDWithDeleted(id uint) (*domain.Distributor, error)
 	FindByEmail(email string) (*domain.Distributor, error)
+	FindAuthState(id uint) (*domain.Distributor, error)
 	Update(distributor *domain.Distributor) error
+	UpdateStatus(distributorID uint, status, reasonCode string) error
+	MarkQualified(distributorID uint, at time.Time) error
+	UpdatePackage(distributorID uint, packageID *uint, expiresAt *time.Time) error
+	UpdateKYCStatus(distributorID uint, status string) error
+	IncrementTokenVersion(distributorID uint) error
+	UpdatePassword(distributorID uint, passwordHash string) error
+	MarkEmailVerified(distributorID uint, at time.Time) error
+	SetTwoFactorSecret(distributorID uint, secret string) error
+	EnableTwoFactor(distributorID uint, at time.Time) error
+	DisableTwoFactor(distributorID uint) error
+	ConsumeTwoFactorStep(distributorID uint, step int64) (bool, error)
+	LockAccount(distributorID uint, until time.Time) error
+	UnlockAccount(distributorID uint) error
 	Delete(id uint) error
+	Restore(id uint) error
 	List(offset, limit int) ([]domain.Distributor, int64, error)
+	ListGenealogy() ([]domain.Distributor, error)
+	StreamDownlines(sponsorIDs []uint, fn func(*domain.Distributor) error) error
 	GetDownlines(sponsorID uint) ([]domain.Distributor, error)
 	GetDownlinesByLevel(sponsorID uint, level int) ([]domain.Distributor, error)
+	SearchDownline(rootID uint, filter domain.DownlineFilter) ([]domain.DownlineMember, int64, error)
 	GetTreeStructure(distributorID uint, depth int) (*domain.TreeNode, error)
+	GetPlacementTreeStructure(distributorID uint, depth int) (*domain.TreeNode, error)
+	GetChildrenPage(parentID uint, placement bool, afterID uint, limit int) ([]domain.TreeNode, error)
+	GetPathToNode(rootID, targetID uint, placement bool) ([]domain.TreeNode, error)
+	GetPlacementChildren(placementID uint) ([]domain.Distributor, error)
+	GetByPlacementAndPosition(placementID uint, position string) (*domain.Distributor, error)
 	CountDownlines(sponsorID uint) (int64, error)
 	CountActiveDownlines(sponsorID uint) (int64, error)
 	GetByTreeTypeAndPosition(sponsorID uint, treeType domain.TreeType, position string) (*domain.Distributor, error)
 	UpdateSales(distributorID uint, amount float64) error
 	UpdateCommission(distributorID uint, amount float64) error
+	UpdateLegVolume(distributorID uint, position string, amount float64) error
+	UpdatePlacement(distributorID uint, sponsorID, placementID *uint, position string, level int) error
+	UpdateLevel(distributorID uint, level int) error
+	ListHoldingTank(sponsorID uint) ([]domain.Distributor, error)
+	ListExpiredHoldingTank(now time.Time) ([]domain.Distributor, error)
+	ListInactiveSince(cutoff time.Time) ([]domain.Distributor, error)
+	ListLapsedPackages(now time.Time) ([]domain.Distributor, error)
 }
 
 type distributorRepository struct {
@@ -32,8 +76,24 @@ func NewDistributorRepository(db *gorm.DB) DistributorRepository {
 	return &distributorRepository{db: db}
 }
 
+func (r *distributorRepository) WithTx(tx *Tx) DistributorRepository {
+	return &distributorRepository{db: tx.db}
+}
+
 func (r *distributorRepository) Create(distributor *domain.Distributor) error {
-	return r.db.Create(distributor).Error
+	return translatePlacementError(r.db.Create(distributor).Error)
+}
+
+// translatePlacementError maps a violation of the placement slot index to
+// ErrPositionTaken. MySQL reports every unique index as error 1062 and only
+// names the key in the message, which is what tells a taken slot apart from
+// a duplicate email or referral code.
+func translatePlacementError(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == errDuplicateEntry && strings.Contains(mysqlErr.Message, "idx_placement_slot") {
+		return ErrPositionTaken
+	}
+	return err
 }
 
 func (r *distributorRepository) FindByID(id uint) (*domain.Distributor, error) {
@@ -53,6 +113,23 @@ func (r *distributorRepository) FindByID(id uint) (*domain.Distributor, error) {
 	return &distributor, nil
 }
 
+// FindByIDWithDeleted also returns soft-deleted (terminated) distributors
+func (r *distributorRepository) FindByIDWithDeleted(id uint) (*domain.Distributor, error) {
+	var distributor domain.Distributor
+	err := r.db.Unscoped().
+		Preload("Rank").
+		Preload("Package").
+		First(&distributor, id).Error
+	
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("distributor not found")
+		}
+		return nil, err
+	}
+	return &distributor, nil
+}
+
 func (r *distributorRepository) FindByEmail(email string) (*domain.Distributor, error) {
 	var distributor domain.Distributor
 	err := r.db.Where("email = ?", email).
@@ -70,14 +147,137 @@ func (r *distributorRepository) FindByEmail(email string) (*domain.Distributor,
 	return &distributor, nil
 }
 
+// FindAuthState loads only the columns needed to authorize a request, so it
+// stays cheap enough to run on every authenticated call
+func (r *distributorRepository) FindAuthState(id uint) (*domain.Distributor, error) {
+	var distributor domain.Distributor
+	err := r.db.Select("id", "email", "role", "status", "token_version").
+		First(&distributor, id).Error
+	
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("distributor not found")
+		}
+		return nil, err
+	}
+	return &distributor, nil
+}
+
+// Update saves the distributor's own columns. Preloaded associations are
+// omitted so a stale Downlines slice cannot re-parent moved children.
 func (r *distributorRepository) Update(distributor *domain.Distributor) error {
-	return r.db.Save(distributor).Error
+	return r.db.Omit(clause.Associations).Save(distributor).Error
+}
+
+func (r *distributorRepository) UpdateStatus(distributorID uint, status, reasonCode string) error {
+	return r.db.Model(&domain.Distributor{}).
+		Where("id = ?", distributorID).
+		UpdateColumns(map[string]interface{}{
+			"status":            status,
+			"status_reason":     reasonCode,
+			"status_changed_at": time.Now(),
+		}).
+		Error
+}
+
+// MarkQualified records a qualifying purchase, restarting the inactivity window
+func (r *distributorRepository) MarkQualified(distributorID uint, at time.Time) error {
+	return r.db.Model(&domain.Distributor{}).
+		Where("id = ?", distributorID).
+		UpdateColumn("last_qualified_at", at).
+		Error
+}
+
+func (r *distributorRepository) UpdatePackage(distributorID uint, packageID *uint, expiresAt *time.Time) error {
+	return r.db.Model(&domain.Distributor{}).
+		Where("id = ?", distributorID).
+		UpdateColumns(map[string]interface{}{
+			"package_id":         packageID,
+			"package_expires_at": expiresAt,
+		}).
+		Error
+}
+
+func (r *distributorRepository) UpdateKYCStatus(distributorID uint, status string) error {
+	return r.db.Model(&domain.Distributor{}).
+		Where("id = ?", distributorID).
+		UpdateColumn("kyc_status", status).
+		Error
+}
+
+func (r *distributorRepository) IncrementTokenVersion(distributorID uint) error {
+	return r.db.Model(&domain.Distributor{}).
+		Where("id = ?", distributorID).
+		Update("token_version", gorm.Expr("token_version + 1")).Error
+}
+
+func (r *distributorRepository) UpdatePassword(distributorID uint, passwordHash string) error {
+	return r.db.Model(&domain.Distributor{}).
+		Where("id = ?", distributorID).
+		Update("password_hash", passwordHash).Error
+}
+
+func (r *distributorRepository) MarkEmailVerified(distributorID uint, at time.Time) error {
+	return r.db.Model(&domain.Distributor{}).
+		Where("id = ? AND email_verified_at IS NULL", distributorID).
+		Update("email_verified_at", at).Error
+}
+
+// SetTwoFactorSecret stores a pending secret; it only takes effect once
+// EnableTwoFactor is called
+func (r *distributorRepository) SetTwoFactorSecret(distributorID uint, secret string) error {
+	return r.db.Model(&domain.Distributor{}).
+		Where("id = ? AND two_factor_enabled_at IS NULL", distributorID).
+		Update("two_factor_secret", secret).Error
+}
+
+func (r *distributorRepository) EnableTwoFactor(distributorID uint, at time.Time) error {
+	return r.db.Model(&domain.Distributor{}).
+		Where("id = ?", distributorID).
+		Update("two_factor_enabled_at", at).Error
+}
+
+func (r *distributorRepository) DisableTwoFactor(distributorID uint) error {
+	return r.db.Model(&domain.Distributor{}).
+		Where("id = ?", distributorID).
+		Updates(map[string]interface{}{
+			"two_factor_secret":     "",
+			"two_factor_enabled_at": nil,
+			"two_factor_last_step":  0,
+		}).Error
+}
+
+// ConsumeTwoFactorStep records step as the last accepted TOTP step, reporting
+// false if it (or a later one) was already used
+func (r *distributorRepository) ConsumeTwoFactorStep(distributorID uint, step int64) (bool, error) {
+	result := r.db.Model(&domain.Distributor{}).
+		Where("id = ? AND two_factor_last_step < ?", distributorID, step).
+		Update("two_factor_last_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *distributorRepository) LockAccount(distributorID uint, until time.Time) error {
+	return r.db.Model(&domain.Distributor{}).
+		Where("id = ?", distributorID).
+		Update("locked_until", until).Error
+}
+
+func (r *distributorRepository) UnlockAccount(distributorID uint) error {
+	return r.db.Model(&domain.Distributor{}).
+		Where("id = ?", distributorID).
+		Update("locked_until", nil).Error
 }
 
 func (r *distributorRepository) Delete(id uint) error {
 	return r.db.Delete(&domain.Distributor{}, id).Error
 }
 
+func (r *distributorRepository) Restore(id uint) error {
+	return r.db.Unscoped().Model(&domain.Distributor{}).
+		Where("id = ?", id).
+		Update("deleted_at", nil).Error
+}
+
 func (r *distributorRepository) List(offset, limit int) ([]domain.Distributor, int64, error) {
 	var distributors []domain.Distributor
 	var total int64
@@ -97,6 +297,41 @@ func (r *distributorRepository) List(offset, limit int) ([]domain.Distributor, i
 	return distributors, total, err
 }
 
+// ListGenealogy loads only the tree columns of every distributor, including
+// soft-deleted ones, for whole-genealogy scans
+func (r *distributorRepository) ListGenealogy() ([]domain.Distributor, error) {
+	var distributors []domain.Distributor
+	err := r.db.Unscoped().
+		Select("id", "created_at", "deleted_at", "sponsor_id", "placement_id", "tree_type", "position", "placement_status", "level", "status").
+		Order("id ASC").
+		Find(&distributors).Error
+	return distributors, err
+}
+
+// StreamDownlines calls fn for every direct downline of the given sponsors,
+// reading rows one at a time instead of loading them all
+func (r *distributorRepository) StreamDownlines(sponsorIDs []uint, fn func(*domain.Distributor) error) error {
+	rows, err := r.db.Model(&domain.Distributor{}).
+		Where("sponsor_id IN ?", sponsorIDs).
+		Order("sponsor_id ASC, id ASC").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	
+	for rows.Next() {
+		var distributor domain.Distributor
+		if err := r.db.ScanRows(rows, &distributor); err != nil {
+			return err
+		}
+		if err := fn(&distributor); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
 func (r *distributorRepository) GetDownlines(sponsorID uint) ([]domain.Distributor, error) {
 	var downlines []domain.Distributor
 	err := r.db.Where("sponsor_id = ?", sponsorID).
@@ -114,6 +349,140 @@ func (r *distributorRepository) GetDownlinesByLevel(sponsorID uint, level int) (
 	return downlines, err
 }
 
+// maxDownlineDepth bounds the recursive downline walk so a corrupted cycle
+// cannot run away
+const maxDownlineDepth = 200
+
+// downlineSortColumns maps the sort keys accepted by SearchDownline to columns
+var downlineSortColumns = map[string]string{
+	"name":           "distributors.first_name",
+	"email":          "distributors.email",
+	"joined":         "distributors.created_at",
+	"depth":          "downline.depth",
+	"rank":           "ranks.level",
+	"personal_sales": "distributors.personal_sales",
+	"team_sales":     "distributors.team_sales",
+	"total_sales":    "distributors.total_sales",
+}
+
+// likeEscaper escapes LIKE wildcards in a search term so they match literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// SearchDownline finds distributors anywhere below rootID that match the
+// filter. The walk follows the placement parent where there is one and the
+// sponsor otherwise, so binary and matrix members are found on the leg they
+// are placed in and unilevel and holding tank members under their sponsor.
+func (r *distributorRepository) SearchDownline(rootID uint, filter domain.DownlineFilter) ([]domain.DownlineMember, int64, error) {
+	maxDepth := maxDownlineDepth
+	if filter.MaxDepth > 0 && filter.MaxDepth < maxDepth {
+		maxDepth = filter.MaxDepth
+	}
+	
+	query := r.db.Model(&domain.Distributor{}).
+		Joins(`JOIN (
+			WITH RECURSIVE tree (id, depth, leg) AS (
+				SELECT id, 1, position FROM distributors
+				WHERE deleted_at IS NULL AND (placement_id = ? OR (placement_id IS NULL AND sponsor_id = ?))
+				UNION ALL
+				SELECT d.id, tree.depth + 1, tree.leg FROM distributors d
+				JOIN tree ON d.placement_id = tree.id OR (d.placement_id IS NULL AND d.sponsor_id = tree.id)
+				WHERE d.deleted_at IS NULL AND tree.depth < ?
+			)
+			SELECT id, depth, leg FROM tree
+		) AS downline ON downline.id = distributors.id`, rootID, rootID, maxDepth)
+	
+	if filter.Query != "" {
+		like := "%" + likeEscaper.Replace(filter.Query) + "%"
+		query = query.Where(`(CONCAT(distributors.first_name, ' ', distributors.last_name) LIKE ? ESCAPE '\\'
+			OR distributors.email LIKE ? ESCAPE '\\')`, like, like)
+	}
+	if filter.RankID != nil {
+		query = query.Where("distributors.rank_id = ?", *filter.RankID)
+	}
+	if filter.Status != "" {
+		query = query.Where("distributors.status = ?", filter.Status)
+	}
+	if filter.JoinedFrom != nil {
+		query = query.Where("distributors.created_at >= ?", *filter.JoinedFrom)
+	}
+	if filter.JoinedTo != nil {
+		query = query.Where("distributors.created_at < ?", *filter.JoinedTo)
+	}
+	if filter.MinDepth > 0 {
+		query = query.Where("downline.depth >= ?", filter.MinDepth)
+	}
+	if filter.Leg != "" {
+		query = query.Where("downline.leg = ?", filter.Leg)
+	}
+	if filter.MinPersonalSales != nil {
+		query = query.Where("distributors.personal_sales >= ?", *filter.MinPersonalSales)
+	}
+	if filter.MaxPersonalSales != nil {
+		query = query.Where("distributors.personal_sales <= ?", *filter.MaxPersonalSales)
+	}
+	if filter.MinTeamSales != nil {
+		query = query.Where("distributors.team_sales >= ?", *filter.MinTeamSales)
+	}
+	if filter.MaxTeamSales != nil {
+		query = query.Where("distributors.team_sales <= ?", *filter.MaxTeamSales)
+	}
+	
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	column, ok := downlineSortColumns[filter.SortBy]
+	if !ok {
+		column = "downline.depth"
+	}
+	direction := "ASC"
+	if filter.SortDesc {
+		direction = "DESC"
+	}
+	
+	// Ranks sort by level, with unranked members below the lowest rank
+	if filter.SortBy == "rank" {
+		query = query.Joins("LEFT JOIN ranks ON ranks.id = distributors.rank_id")
+	}
+	
+	var hits []struct {
+		ID    uint
+		Depth int
+		Leg   string
+	}
+	err := query.Select("distributors.id, downline.depth, downline.leg").
+		Order(column + " " + direction + ", distributors.id ASC").
+		Offset(filter.Offset).
+		Limit(filter.Limit).
+		Scan(&hits).Error
+	if err != nil || len(hits) == 0 {
+		return nil, total, err
+	}
+	
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	var distributors []domain.Distributor
+	if err := r.db.Where("id IN ?", ids).Preload("Rank").Preload("Package").Find(&distributors).Error; err != nil {
+		return nil, 0, err
+	}
+	byID := make(map[uint]domain.Distributor, len(distributors))
+	for _, d := range distributors {
+		byID[d.ID] = d
+	}
+	
+	// Keep the page in the order the search sorted it
+	members := make([]domain.DownlineMember, 0, len(hits))
+	for _, hit := range hits {
+		if d, ok := byID[hit.ID]; ok {
+			members = append(members, domain.DownlineMember{Distributor: d, Depth: hit.Depth, Leg: hit.Leg})
+		}
+	}
+	return members, total, nil
+}
+
 func (r *distributorRepository) GetTreeStructure(distributorID uint, depth int) (*domain.TreeNode, error) {
 	distributor, err := r.FindByID(distributorID)
 	if err != nil {
@@ -123,12 +492,157 @@ func (r *distributorRepository) GetTreeStructure(distributorID uint, depth int)
 	node := r.buildTreeNode(distributor)
 	
 	if depth > 0 {
-		r.populateChildren(node, depth-1)
+		r.populateChildren(node, "sponsor_id", depth-1)
 	}
 	
 	return node, nil
 }
 
+// GetPlacementTreeStructure builds the hybrid binary placement tree, which
+// follows placement_id rather than the enrolling sponsor
+func (r *distributorRepository) GetPlacementTreeStructure(distributorID uint, depth int) (*domain.TreeNode, error) {
+	distributor, err := r.FindByID(distributorID)
+	if err != nil {
+		return nil, err
+	}
+	
+	node := r.buildTreeNode(distributor)
+	
+	if depth > 0 {
+		r.populateChildren(node, "placement_id", depth-1)
+	}
+	
+	return node, nil
+}
+
+// GetChildrenPage returns up to limit direct children of parentID with IDs
+// above afterID, in the enrollment tree or, with placement, the placement
+// tree. Each node carries its own child count so it can be expanded later.
+func (r *distributorRepository) GetChildrenPage(parentID uint, placement bool, afterID uint, limit int) ([]domain.TreeNode, error) {
+	query := r.db.Preload("Rank")
+	if placement {
+		query = query.Where("("+legacyPlacementClause+")", parentID, parentID)
+	} else {
+		query = query.Where("sponsor_id = ?", parentID)
+	}
+	
+	var children []domain.Distributor
+	err := query.Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&children).Error
+	if err != nil {
+		return nil, err
+	}
+	
+	return r.buildCountedNodes(children, placement)
+}
+
+// GetPathToNode returns the chain of nodes from rootID down to targetID,
+// following sponsors or, with placement, placement parents. A rootID of 0
+// walks all the way up to the top of the tree.
+func (r *distributorRepository) GetPathToNode(rootID, targetID uint, placement bool) ([]domain.TreeNode, error) {
+	var chain []domain.Distributor
+	visited := make(map[uint]bool)
+	id := targetID
+	for {
+		if visited[id] {
+			return nil, errors.New("cycle detected in genealogy")
+		}
+		visited[id] = true
+		
+		var distributor domain.Distributor
+		if err := r.db.Preload("Rank").First(&distributor, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("distributor not found")
+			}
+			return nil, err
+		}
+		chain = append(chain, distributor)
+		
+		if distributor.ID == rootID {
+			break
+		}
+		parentID := treeParent(&distributor, placement)
+		if parentID == nil {
+			if rootID != 0 {
+				return nil, errors.New("distributor is not in the requested downline")
+			}
+			break
+		}
+		id = *parentID
+	}
+	
+	// The walk went bottom-up; paths read from the root down
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return r.buildCountedNodes(chain, placement)
+}
+
+// treeParent is the parent a node hangs under in the chosen view, matching
+// legacyPlacementClause for binary and matrix rows without a placement_id
+func treeParent(distributor *domain.Distributor, placement bool) *uint {
+	if !placement {
+		return distributor.SponsorID
+	}
+	if distributor.PlacementID != nil {
+		return distributor.PlacementID
+	}
+	switch distributor.TreeType {
+	case domain.TreeTypeBinary, domain.TreeTypeMatrix:
+		if distributor.PlacementStatus == "placed" {
+			return distributor.SponsorID
+		}
+	}
+	return nil
+}
+
+// buildCountedNodes converts distributors to tree nodes with child counts,
+// using one grouped count query for the whole batch
+func (r *distributorRepository) buildCountedNodes(distributors []domain.Distributor, placement bool) ([]domain.TreeNode, error) {
+	nodes := make([]domain.TreeNode, 0, len(distributors))
+	if len(distributors) == 0 {
+		return nodes, nil
+	}
+	
+	ids := make([]uint, len(distributors))
+	for i, d := range distributors {
+		ids[i] = d.ID
+	}
+	
+	var counts []struct {
+		ParentID uint
+		Total    int64
+	}
+	query := r.db.Model(&domain.Distributor{})
+	if placement {
+		query = query.Select("COALESCE(placement_id, sponsor_id) AS parent_id, COUNT(*) AS total").
+			Where("placement_id IN ? OR (placement_id IS NULL AND sponsor_id IN ? AND tree_type IN ('binary', 'matrix') AND placement_status = 'placed')", ids, ids).
+			Group("COALESCE(placement_id, sponsor_id)")
+	} else {
+		query = query.Select("sponsor_id AS parent_id, COUNT(*) AS total").
+			Where("sponsor_id IN ?", ids).
+			Group("sponsor_id")
+	}
+	if err := query.Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	
+	byParent := make(map[uint]int64, len(counts))
+	for _, count := range counts {
+		byParent[count.ParentID] = count.Total
+	}
+	
+	for i := range distributors {
+		node := r.buildTreeNode(&distributors[i])
+		childCount := byParent[node.ID]
+		node.ChildCount = &childCount
+		nodes = append(nodes, *node)
+	}
+	return nodes, nil
+}
+
 func (r *distributorRepository) buildTreeNode(distributor *domain.Distributor) *domain.TreeNode {
 	node := &domain.TreeNode{
 		ID:            distributor.ID,
@@ -136,6 +650,7 @@ func (r *distributorRepository) buildTreeNode(distributor *domain.Distributor) *
 		Name:          distributor.FirstName + " " + distributor.LastName,
 		Email:         distributor.Email,
 		SponsorID:     distributor.SponsorID,
+		PlacementID:   distributor.PlacementID,
 		Position:      distributor.Position,
 		Level:         distributor.Level,
 		TotalSales:    distributor.TotalSales,
@@ -149,20 +664,20 @@ func (r *distributorRepository) buildTreeNode(distributor *domain.Distributor) *
 	return node
 }
 
-func (r *distributorRepository) populateChildren(node *domain.TreeNode, depth int) {
+func (r *distributorRepository) populateChildren(node *domain.TreeNode, parentColumn string, depth int) {
 	if depth < 0 {
 		return
 	}
 	
 	var children []domain.Distributor
-	r.db.Where("sponsor_id = ?", node.DistributorID).
+	r.db.Where(parentColumn+" = ?", node.DistributorID).
 		Preload("Rank").
 		Find(&children)
 	
 	for _, child := range children {
 		childNode := r.buildTreeNode(&child)
 		if depth > 0 {
-			r.populateChildren(childNode, depth-1)
+			r.populateChildren(childNode, parentColumn, depth-1)
 		}
 		node.Children = append(node.Children, *childNode)
 	}
@@ -179,7 +694,7 @@ func (r *distributorRepository) CountDownlines(sponsorID uint) (int64, error) {
 func (r *distributorRepository) CountActiveDownlines(sponsorID uint) (int64, error) {
 	var count int64
 	err := r.db.Model(&domain.Distributor{}).
-		Where("sponsor_id = ? AND status = ?", sponsorID, "active").
+		Where("sponsor_id = ? AND status = ?", sponsorID, domain.StatusActive).
 		Count(&count).Error
 	return count, err
 }
@@ -198,10 +713,42 @@ func (r *distributorRepository) GetByTreeTypeAndPosition(sponsorID uint, treeTyp
 	return &distributor, nil
 }
 
+// GetPlacementChildren returns the members placed directly under a parent.
+// Binary and matrix rows created before placement_id existed are matched
+// on their sponsor instead.
+func (r *distributorRepository) GetPlacementChildren(placementID uint) ([]domain.Distributor, error) {
+	var children []domain.Distributor
+	err := r.db.Where(legacyPlacementClause, placementID, placementID).
+		Order("position ASC").
+		Find(&children).Error
+	return children, err
+}
+
+const legacyPlacementClause = "placement_id = ? OR (placement_id IS NULL AND sponsor_id = ? AND tree_type IN ('binary', 'matrix') AND placement_status = 'placed')"
+
+func (r *distributorRepository) GetByPlacementAndPosition(placementID uint, position string) (*domain.Distributor, error) {
+	var distributor domain.Distributor
+	err := r.db.Where("("+legacyPlacementClause+") AND position = ?", placementID, placementID, position).
+		First(&distributor).Error
+	
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &distributor, nil
+}
+
+// UpdateSales adds the volume of a distributor's own order to their total
+// and personal sales
 func (r *distributorRepository) UpdateSales(distributorID uint, amount float64) error {
 	return r.db.Model(&domain.Distributor{}).
 		Where("id = ?", distributorID).
-		UpdateColumn("total_sales", gorm.Expr("total_sales + ?", amount)).
+		UpdateColumns(map[string]interface{}{
+			"total_sales":    gorm.Expr("total_sales + ?", amount),
+			"personal_sales": gorm.Expr("personal_sales + ?", amount),
+		}).
 		Error
 }
 
@@ -211,3 +758,80 @@ func (r *distributorRepository) UpdateCommission(distributorID uint, amount floa
 		UpdateColumn("total_commission", gorm.Expr("total_commission + ?", amount)).
 		Error
 }
+
+// UpdateLegVolume adds amount to a binary parent's left or right leg
+func (r *distributorRepository) UpdateLegVolume(distributorID uint, position string, amount float64) error {
+	var column string
+	switch position {
+	case "left":
+		column = "left_leg_volume"
+	case "right":
+		column = "right_leg_volume"
+	default:
+		return fmt.Errorf("position %q is not a binary leg", position)
+	}
+	return r.db.Model(&domain.Distributor{}).
+		Where("id = ?", distributorID).
+		UpdateColumn(column, gorm.Expr(column+" + ?", amount)).
+		Error
+}
+
+// UpdatePlacement moves a distributor to a slot, which also takes them out
+// of any holding tank
+func (r *distributorRepository) UpdatePlacement(distributorID uint, sponsorID, placementID *uint, position string, level int) error {
+	err := r.db.Model(&domain.Distributor{}).
+		Where("id = ?", distributorID).
+		Updates(map[string]interface{}{
+			"sponsor_id":              sponsorID,
+			"placement_id":            placementID,
+			"position":                position,
+			"level":                   level,
+			"placement_status":        "placed",
+			"holding_tank_expires_at": nil,
+		}).Error
+	return translatePlacementError(err)
+}
+
+func (r *distributorRepository) ListHoldingTank(sponsorID uint) ([]domain.Distributor, error) {
+	var members []domain.Distributor
+	err := r.db.Where("sponsor_id = ? AND placement_status = ?", sponsorID, "pending").
+		Preload("Package").
+		Order("holding_tank_expires_at ASC").
+		Find(&members).Error
+	return members, err
+}
+
+func (r *distributorRepository) ListExpiredHoldingTank(now time.Time) ([]domain.Distributor, error) {
+	var members []domain.Distributor
+	err := r.db.Where("placement_status = ? AND holding_tank_expires_at <= ?", "pending", now).
+		Order("holding_tank_expires_at ASC").
+		Find(&members).Error
+	return members, err
+}
+
+// ListInactiveSince returns active distributors whose last qualifying
+// purchase, or sign-up if they never made one, is before cutoff. Admin
+// accounts are never made inactive.
+func (r *distributorRepository) ListInactiveSince(cutoff time.Time) ([]domain.Distributor, error) {
+	var distributors []domain.Distributor
+	err := r.db.Where("status = ? AND role <> ? AND COALESCE(last_qualified_at, created_at) < ?", domain.StatusActive, "admin", cutoff).
+		Order("id ASC").
+		Find(&distributors).Error
+	return distributors, err
+}
+
+// ListLapsedPackages returns distributors whose package term ended before now
+func (r *distributorRepository) ListLapsedPackages(now time.Time) ([]domain.Distributor, error) {
+	var distributors []domain.Distributor
+	err := r.db.Where("package_expires_at <= ?", now).
+		Order("package_expires_at ASC").
+		Find(&distributors).Error
+	return distributors, err
+}
+
+func (r *distributorRepository) UpdateLevel(distributorID uint, level int) error {
+	return r.db.Model(&domain.Distributor{}).
+		Where("id = ?", distributorID).
+		UpdateColumn("level", level).
+		Error
+}
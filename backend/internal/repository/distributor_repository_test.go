@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/mlm-app/backend/internal/domain"
+	"gorm.io/gorm/schema"
+)
+
+func TestTranslatePlacementError(t *testing.T) {
+	slotTaken := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1-left' for key 'distributors.idx_placement_slot'"}
+	other := errors.New("connection reset")
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"slot index", slotTaken, ErrPositionTaken},
+		{"wrapped slot index", fmt.Errorf("create distributor: %w", slotTaken), ErrPositionTaken},
+		{"duplicate email", &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a@example.com' for key 'distributors.idx_distributors_email'"}, nil},
+		{"other error on the slot index", &mysql.MySQLError{Number: 1452, Message: "foreign key constraint fails on idx_placement_slot"}, nil},
+		{"not a MySQL error", other, other},
+		{"no error", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.want
+			if want == nil {
+				want = tt.err
+			}
+			if got := translatePlacementError(tt.err); got != want {
+				t.Errorf("translatePlacementError(%v) = %v, want %v", tt.err, got, want)
+			}
+		})
+	}
+}
+
+// The translation matches the index by name, so the model must declare it
+// under that name and over the slot columns
+func TestPlacementSlotIndexMatchesModel(t *testing.T) {
+	s, err := schema.Parse(&domain.Distributor{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index, ok := s.ParseIndexes()["idx_placement_slot"]
+	if !ok {
+		t.Fatal("Distributor declares no idx_placement_slot index")
+	}
+	if index.Class != "UNIQUE" {
+		t.Errorf("idx_placement_slot class = %q, want UNIQUE", index.Class)
+	}
+	var columns []string
+	for _, field := range index.Fields {
+		columns = append(columns, field.DBName)
+	}
+	if fmt.Sprint(columns) != "[placement_id position]" {
+		t.Errorf("idx_placement_slot covers %v, want [placement_id position]", columns)
+	}
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/mlm-app/backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+// ErrKYCSubmissionNotFound is returned when a submission does not exist
+var ErrKYCSubmissionNotFound = errors.New("KYC submission not found")
+
+type KYCRepository interface {
+	WithTx(tx *Tx) KYCRepository
+	CreateSubmission(submission *domain.KYCSubmission) error
+	UpdateSubmission(submission *domain.KYCSubmission) error
+	FindSubmission(id uint) (*domain.KYCSubmission, error)
+	FindLatestByDistributor(distributorID uint) (*domain.KYCSubmission, error)
+	ListSubmissions(status string, offset, limit int) ([]domain.KYCSubmission, int64, error)
+	CreateDocument(document *domain.KYCDocument) error
+	FindDocument(id uint) (*domain.KYCDocument, error)
+}
+
+type kycRepository struct {
+	db *gorm.DB
+}
+
+func NewKYCRepository(db *gorm.DB) KYCRepository {
+	return &kycRepository{db: db}
+}
+
+func (r *kycRepository) WithTx(tx *Tx) KYCRepository {
+	return &kycRepository{db: tx.db}
+}
+
+func (r *kycRepository) CreateSubmission(submission *domain.KYCSubmission) error {
+	return r.db.Create(submission).Error
+}
+
+func (r *kycRepository) UpdateSubmission(submission *domain.KYCSubmission) error {
+	return r.db.Omit("Documents").Save(submission).Error
+}
+
+func (r *kycRepository) FindSubmission(id uint) (*domain.KYCSubmission, error) {
+	var submission domain.KYCSubmission
+	err := r.db.Preload("Documents").First(&submission, id).Error
+	
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrKYCSubmissionNotFound
+		}
+		return nil, err
+	}
+	return &submission, nil
+}
+
+func (r *kycRepository) FindLatestByDistributor(distributorID uint) (*domain.KYCSubmission, error) {
+	var submission domain.KYCSubmission
+	err := r.db.Where("distributor_id = ?", distributorID).
+		Preload("Documents").
+		Order("id DESC").
+		First(&submission).Error
+	
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrKYCSubmissionNotFound
+		}
+		return nil, err
+	}
+	return &submission, nil
+}
+
+// ListSubmissions returns submissions, oldest first so the review queue is
+// worked in order; an empty status matches everything but drafts
+func (r *kycRepository) ListSubmissions(status string, offset, limit int) ([]domain.KYCSubmission, int64, error) {
+	query := r.db.Model(&domain.KYCSubmission{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	} else {
+		query = query.Where("status <> ?", domain.KYCStatusDraft)
+	}
+	
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	var submissions []domain.KYCSubmission
+	err := query.Preload("Documents").
+		Order("submitted_at ASC, id ASC").
+		Offset(offset).
+		Limit(limit).
+		Find(&submissions).Error
+	return submissions, total, err
+}
+
+func (r *kycRepository) CreateDocument(document *domain.KYCDocument) error {
+	return r.db.Create(document).Error
+}
+
+func (r *kycRepository) FindDocument(id uint) (*domain.KYCDocument, error) {
+	var document domain.KYCDocument
+	err := r.db.First(&document, id).Error
+	
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("KYC document not found")
+		}
+		return nil, err
+	}
+	return &document, nil
+}
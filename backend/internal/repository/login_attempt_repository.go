@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"github.com/mlm-app/backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+// LoginAttemptFilter narrows a login attempt listing; zero values match everything
+type LoginAttemptFilter struct {
+	Email         string
+	DistributorID *uint
+	IPAddress     string
+	Success       *bool
+}
+
+type LoginAttemptRepository interface {
+	Create(attempt *domain.LoginAttempt) error
+	List(filter LoginAttemptFilter, offset, limit int) ([]domain.LoginAttempt, int64, error)
+}
+
+type loginAttemptRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginAttemptRepository(db *gorm.DB) LoginAttemptRepository {
+	return &loginAttemptRepository{db: db}
+}
+
+func (r *loginAttemptRepository) Create(attempt *domain.LoginAttempt) error {
+	return r.db.Create(attempt).Error
+}
+
+func (r *loginAttemptRepository) List(filter LoginAttemptFilter, offset, limit int) ([]domain.LoginAttempt, int64, error) {
+	query := r.db.Model(&domain.LoginAttempt{})
+	if filter.Email != "" {
+		query = query.Where("email = ?", filter.Email)
+	}
+	if filter.DistributorID != nil {
+		query = query.Where("distributor_id = ?", *filter.DistributorID)
+	}
+	if filter.IPAddress != "" {
+		query = query.Where("ip_address = ?", filter.IPAddress)
+	}
+	if filter.Success != nil {
+		query = query.Where("success = ?", *filter.Success)
+	}
+	
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	var attempts []domain.LoginAttempt
+	err := query.Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&attempts).Error
+	return attempts, total, err
+}
@@ -8,10 +8,13 @@ import (
 )
 
 type OrderRepository interface {
+	WithTx(tx *Tx) OrderRepository
 	Create(order *domain.Order) error
 	FindByID(id uint) (*domain.Order, error)
 	FindByOrderNumber(orderNumber string) (*domain.Order, error)
 	Update(order *domain.Order) error
+	UpdatePayment(id uint, paymentStatus, status, paymentMethod string) error
+	CancelPendingPackageOrders(distributorID uint) error
 	List(offset, limit int) ([]domain.Order, int64, error)
 	ListByDistributor(distributorID uint, offset, limit int) ([]domain.Order, int64, error)
 	GetTotalSalesByDistributor(distributorID uint) (float64, error)
@@ -25,6 +28,10 @@ func NewOrderRepository(db *gorm.DB) OrderRepository {
 	return &orderRepository{db: db}
 }
 
+func (r *orderRepository) WithTx(tx *Tx) OrderRepository {
+	return &orderRepository{db: tx.db}
+}
+
 func (r *orderRepository) Create(order *domain.Order) error {
 	return r.db.Create(order).Error
 }
@@ -32,6 +39,7 @@ func (r *orderRepository) Create(order *domain.Order) error {
 func (r *orderRepository) FindByID(id uint) (*domain.Order, error) {
 	var order domain.Order
 	err := r.db.Preload("Distributor").
+		Preload("Package").
 		Preload("OrderItems.Product").
 		First(&order, id).Error
 	
@@ -64,6 +72,25 @@ func (r *orderRepository) Update(order *domain.Order) error {
 	return r.db.Save(order).Error
 }
 
+func (r *orderRepository) UpdatePayment(id uint, paymentStatus, status, paymentMethod string) error {
+	return r.db.Model(&domain.Order{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"payment_status": paymentStatus,
+			"status":         status,
+			"payment_method": paymentMethod,
+		}).Error
+}
+
+// CancelPendingPackageOrders cancels a distributor's unpaid package orders,
+// so only the latest one can activate a package
+func (r *orderRepository) CancelPendingPackageOrders(distributorID uint) error {
+	return r.db.Model(&domain.Order{}).
+		Where("distributor_id = ? AND package_id IS NOT NULL AND status = ? AND payment_status <> ?",
+			distributorID, domain.OrderStatusPending, domain.PaymentStatusPaid).
+		Update("status", domain.OrderStatusCancelled).Error
+}
+
 func (r *orderRepository) List(offset, limit int) ([]domain.Order, int64, error) {
 	var orders []domain.Order
 	var total int64
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mlm-app/backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type PackageRepository interface {
+	WithTx(tx *Tx) PackageRepository
+	Create(pkg *domain.Package) error
+	FindByID(id uint) (*domain.Package, error)
+	FindLatestByCode(code string) (*domain.Package, error)
+	Update(pkg *domain.Package) error
+	MarkSuperseded(id uint, at time.Time) error
+	Delete(id uint) error
+	List(activeOnly bool) ([]domain.Package, error)
+	ListVersions(code string) ([]domain.Package, error)
+	CountUsage(id uint) (int64, error)
+}
+
+type packageRepository struct {
+	db *gorm.DB
+}
+
+func NewPackageRepository(db *gorm.DB) PackageRepository {
+	return &packageRepository{db: db}
+}
+
+func (r *packageRepository) WithTx(tx *Tx) PackageRepository {
+	return &packageRepository{db: tx.db}
+}
+
+// Create inserts pkg. IsActive is written separately because the column
+// defaults to true, which would otherwise override false.
+func (r *packageRepository) Create(pkg *domain.Package) error {
+	if err := r.db.Create(pkg).Error; err != nil {
+		return err
+	}
+	return r.db.Model(pkg).UpdateColumn("is_active", pkg.IsActive).Error
+}
+
+func (r *packageRepository) FindByID(id uint) (*domain.Package, error) {
+	var pkg domain.Package
+	err := r.db.First(&pkg, id).Error
+	
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package not found")
+		}
+		return nil, err
+	}
+	return &pkg, nil
+}
+
+func (r *packageRepository) FindLatestByCode(code string) (*domain.Package, error) {
+	var pkg domain.Package
+	err := r.db.Where("code = ?", code).
+		Order("version DESC").
+		First(&pkg).Error
+	
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package not found")
+		}
+		return nil, err
+	}
+	return &pkg, nil
+}
+
+func (r *packageRepository) Update(pkg *domain.Package) error {
+	return r.db.Save(pkg).Error
+}
+
+func (r *packageRepository) MarkSuperseded(id uint, at time.Time) error {
+	return r.db.Model(&domain.Package{}).
+		Where("id = ?", id).
+		Update("superseded_at", at).Error
+}
+
+func (r *packageRepository) Delete(id uint) error {
+	return r.db.Delete(&domain.Package{}, id).Error
+}
+
+// List returns the current version of every package, cheapest first
+func (r *packageRepository) List(activeOnly bool) ([]domain.Package, error) {
+	query := r.db.Where("superseded_at IS NULL")
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+	
+	var packages []domain.Package
+	err := query.Order("price ASC, id ASC").Find(&packages).Error
+	return packages, err
+}
+
+// ListVersions returns every version of a package, newest first
+func (r *packageRepository) ListVersions(code string) ([]domain.Package, error) {
+	var packages []domain.Package
+	err := r.db.Where("code = ?", code).
+		Order("version DESC").
+		Find(&packages).Error
+	return packages, err
+}
+
+// CountUsage counts the distributors, including terminated ones, and
+// orders that reference a package version
+func (r *packageRepository) CountUsage(id uint) (int64, error) {
+	var distributors, orders int64
+	if err := r.db.Unscoped().Model(&domain.Distributor{}).Where("package_id = ?", id).Count(&distributors).Error; err != nil {
+		return 0, err
+	}
+	if err := r.db.Model(&domain.Order{}).Where("package_id = ?", id).Count(&orders).Error; err != nil {
+		return 0, err
+	}
+	return distributors + orders, nil
+}
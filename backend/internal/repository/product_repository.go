@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/mlm-app/backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type ProductRepository interface {
+	FindBySKU(sku string) (*domain.Product, error)
+	List(offset, limit int) ([]domain.Product, int64, error)
+	UpdateStock(id uint, stock int) error
+}
+
+type productRepository struct {
+	db *gorm.DB
+}
+
+func NewProductRepository(db *gorm.DB) ProductRepository {
+	return &productRepository{db: db}
+}
+
+func (r *productRepository) FindBySKU(sku string) (*domain.Product, error) {
+	var product domain.Product
+	err := r.db.Where("sku = ?", sku).First(&product).Error
+	
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("product not found")
+		}
+		return nil, err
+	}
+	return &product, nil
+}
+
+func (r *productRepository) List(offset, limit int) ([]domain.Product, int64, error) {
+	var products []domain.Product
+	var total int64
+	
+	err := r.db.Model(&domain.Product{}).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	
+	err = r.db.Order("sku").
+		Offset(offset).
+		Limit(limit).
+		Find(&products).Error
+	
+	return products, total, err
+}
+
+func (r *productRepository) UpdateStock(id uint, stock int) error {
+	return r.db.Model(&domain.Product{}).
+		Where("id = ?", id).
+		Update("stock", stock).Error
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/mlm-app/backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type RecoveryCodeRepository interface {
+	ReplaceAll(distributorID uint, codeHashes []string) error
+	Consume(distributorID uint, codeHash string) (bool, error)
+	DeleteAll(distributorID uint) error
+}
+
+type recoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+func NewRecoveryCodeRepository(db *gorm.DB) RecoveryCodeRepository {
+	return &recoveryCodeRepository{db: db}
+}
+
+// ReplaceAll discards a distributor's recovery codes and stores a new set
+func (r *recoveryCodeRepository) ReplaceAll(distributorID uint, codeHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("distributor_id = ?", distributorID).Delete(&domain.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		
+		codes := make([]domain.RecoveryCode, len(codeHashes))
+		for i, hash := range codeHashes {
+			codes[i] = domain.RecoveryCode{DistributorID: distributorID, CodeHash: hash}
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// Consume marks a matching unused code as used, reporting whether one was found
+func (r *recoveryCodeRepository) Consume(distributorID uint, codeHash string) (bool, error) {
+	result := r.db.Model(&domain.RecoveryCode{}).
+		Where("distributor_id = ? AND code_hash = ? AND used_at IS NULL", distributorID, codeHash).
+		Limit(1).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *recoveryCodeRepository) DeleteAll(distributorID uint) error {
+	return r.db.Where("distributor_id = ?", distributorID).Delete(&domain.RecoveryCode{}).Error
+}
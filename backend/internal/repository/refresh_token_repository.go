@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mlm-app/backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+// ErrRefreshTokenReused is returned by Rotate when the token was already
+// rotated or revoked, which means it has been replayed
+var ErrRefreshTokenReused = errors.New("refresh token already used")
+
+type RefreshTokenRepository interface {
+	Create(token *domain.RefreshToken) error
+	FindByHash(tokenHash string) (*domain.RefreshToken, error)
+	Rotate(current, next *domain.RefreshToken) error
+	Revoke(id uint) error
+	RevokeFamily(familyID string) error
+	RevokeAllForDistributor(distributorID uint) error
+}
+
+type refreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{db: db}
+}
+
+func (r *refreshTokenRepository) Create(token *domain.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *refreshTokenRepository) FindByHash(tokenHash string) (*domain.RefreshToken, error) {
+	var token domain.RefreshToken
+	err := r.db.Where("token_hash = ?", tokenHash).First(&token).Error
+	
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("refresh token not found")
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Rotate revokes current and stores next in its place. Only one of several
+// concurrent rotations of the same token can succeed; the others get
+// ErrRefreshTokenReused.
+func (r *refreshTokenRepository) Rotate(current, next *domain.RefreshToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domain.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", current.ID).
+			Update("revoked_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+		
+		if err := tx.Create(next).Error; err != nil {
+			return err
+		}
+		return tx.Model(&domain.RefreshToken{}).
+			Where("id = ?", current.ID).
+			Update("replaced_by_id", next.ID).Error
+	})
+}
+
+func (r *refreshTokenRepository) Revoke(id uint) error {
+	return r.db.Model(&domain.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *refreshTokenRepository) RevokeFamily(familyID string) error {
+	return r.db.Model(&domain.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *refreshTokenRepository) RevokeAllForDistributor(distributorID uint) error {
+	return r.db.Model(&domain.RefreshToken{}).
+		Where("distributor_id = ? AND revoked_at IS NULL", distributorID).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mlm-app/backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type SnapshotRepository interface {
+	Capture(snapshot *domain.GenealogySnapshot) error
+	FindByID(id uint) (*domain.GenealogySnapshot, error)
+	FindByPeriod(period string) (*domain.GenealogySnapshot, error)
+	FindCovering(at time.Time) (*domain.GenealogySnapshot, error)
+	List() ([]domain.GenealogySnapshot, error)
+	FindEntry(snapshotID, distributorID uint) (*domain.GenealogySnapshotEntry, error)
+	ListChildEntries(snapshotID uint, parentIDs []uint, placement bool) ([]domain.GenealogySnapshotEntry, error)
+	CountChildEntries(snapshotID, parentID uint) (int64, int64, error)
+}
+
+type snapshotRepository struct {
+	db *gorm.DB
+}
+
+func NewSnapshotRepository(db *gorm.DB) SnapshotRepository {
+	return &snapshotRepository{db: db}
+}
+
+// Capture creates the snapshot and copies every live distributor into it in
+// one transaction. The copy runs inside the database so a large genealogy is
+// never loaded into memory.
+func (r *snapshotRepository) Capture(snapshot *domain.GenealogySnapshot) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(snapshot).Error; err != nil {
+			return err
+		}
+		
+		result := tx.Exec(`
+			INSERT INTO genealogy_snapshot_entries (
+				snapshot_id, distributor_id, first_name, last_name, email, joined_at,
+				sponsor_id, placement_id, tree_type, position, level,
+				status, rank_id, rank_name,
+				total_sales, personal_sales, team_sales, left_leg_volume, right_leg_volume,
+				total_commission, total_bonus
+			)
+			SELECT ?, d.id, d.first_name, d.last_name, d.email, d.created_at,
+				d.sponsor_id,
+				CASE
+					WHEN d.placement_id IS NOT NULL THEN d.placement_id
+					WHEN d.tree_type IN ('binary', 'matrix') AND d.placement_status = 'placed' THEN d.sponsor_id
+				END,
+				d.tree_type, d.position, d.level,
+				d.status, d.rank_id, COALESCE(ranks.name, ''),
+				d.total_sales, d.personal_sales, d.team_sales, d.left_leg_volume, d.right_leg_volume,
+				d.total_commission, d.total_bonus
+			FROM distributors d
+			LEFT JOIN ranks ON ranks.id = d.rank_id
+			WHERE d.deleted_at IS NULL`, snapshot.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		
+		snapshot.MemberCount = int(result.RowsAffected)
+		return tx.Model(snapshot).Update("member_count", snapshot.MemberCount).Error
+	})
+}
+
+func (r *snapshotRepository) FindByID(id uint) (*domain.GenealogySnapshot, error) {
+	var snapshot domain.GenealogySnapshot
+	err := r.db.First(&snapshot, id).Error
+	
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("snapshot not found")
+		}
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+func (r *snapshotRepository) FindByPeriod(period string) (*domain.GenealogySnapshot, error) {
+	var snapshot domain.GenealogySnapshot
+	err := r.db.Where("period = ?", period).First(&snapshot).Error
+	
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// FindCovering returns the snapshot of the period that contains at
+func (r *snapshotRepository) FindCovering(at time.Time) (*domain.GenealogySnapshot, error) {
+	var snapshot domain.GenealogySnapshot
+	err := r.db.Where("period_start <= ? AND period_end > ?", at, at).
+		Order("taken_at DESC").
+		First(&snapshot).Error
+	
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("no snapshot covers the requested date")
+		}
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+func (r *snapshotRepository) List() ([]domain.GenealogySnapshot, error) {
+	var snapshots []domain.GenealogySnapshot
+	err := r.db.Order("period_start DESC").Find(&snapshots).Error
+	return snapshots, err
+}
+
+func (r *snapshotRepository) FindEntry(snapshotID, distributorID uint) (*domain.GenealogySnapshotEntry, error) {
+	var entry domain.GenealogySnapshotEntry
+	err := r.db.Where("snapshot_id = ? AND distributor_id = ?", snapshotID, distributorID).
+		First(&entry).Error
+	
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("distributor not found in snapshot")
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// ListChildEntries returns the direct children of the given parents in the
+// snapshot's enrollment tree or, with placement, its placement tree
+func (r *snapshotRepository) ListChildEntries(snapshotID uint, parentIDs []uint, placement bool) ([]domain.GenealogySnapshotEntry, error) {
+	parentColumn := "sponsor_id"
+	if placement {
+		parentColumn = "placement_id"
+	}
+	
+	var entries []domain.GenealogySnapshotEntry
+	err := r.db.Where("snapshot_id = ? AND "+parentColumn+" IN ?", snapshotID, parentIDs).
+		Order("distributor_id ASC").
+		Find(&entries).Error
+	return entries, err
+}
+
+// CountChildEntries returns how many direct downlines a distributor had in
+// the snapshot and how many of them were active
+func (r *snapshotRepository) CountChildEntries(snapshotID, parentID uint) (int64, int64, error) {
+	var counts struct {
+		Total  int64
+		Active int64
+	}
+	err := r.db.Model(&domain.GenealogySnapshotEntry{}).
+		Select("COUNT(*) AS total, COALESCE(SUM(status = 'active'), 0) AS active").
+		Where("snapshot_id = ? AND sponsor_id = ?", snapshotID, parentID).
+		Scan(&counts).Error
+	return counts.Total, counts.Active, err
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"github.com/mlm-app/backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type StatusChangeRepository interface {
+	WithTx(tx *Tx) StatusChangeRepository
+	Create(change *domain.StatusChange) error
+	ListByDistributor(distributorID uint, offset, limit int) ([]domain.StatusChange, int64, error)
+}
+
+type statusChangeRepository struct {
+	db *gorm.DB
+}
+
+func NewStatusChangeRepository(db *gorm.DB) StatusChangeRepository {
+	return &statusChangeRepository{db: db}
+}
+
+func (r *statusChangeRepository) WithTx(tx *Tx) StatusChangeRepository {
+	return &statusChangeRepository{db: tx.db}
+}
+
+func (r *statusChangeRepository) Create(change *domain.StatusChange) error {
+	return r.db.Create(change).Error
+}
+
+// ListByDistributor returns a distributor's status history, newest first
+func (r *statusChangeRepository) ListByDistributor(distributorID uint, offset, limit int) ([]domain.StatusChange, int64, error) {
+	query := r.db.Model(&domain.StatusChange{}).Where("distributor_id = ?", distributorID)
+	
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	var changes []domain.StatusChange
+	err := query.Order("created_at DESC, id DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&changes).Error
+	return changes, total, err
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/mlm-app/backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type TerminationRepository interface {
+	WithTx(tx *Tx) TerminationRepository
+	Create(termination *domain.Termination) error
+	FindLatestByDistributor(distributorID uint) (*domain.Termination, error)
+	Update(termination *domain.Termination) error
+}
+
+type terminationRepository struct {
+	db *gorm.DB
+}
+
+func NewTerminationRepository(db *gorm.DB) TerminationRepository {
+	return &terminationRepository{db: db}
+}
+
+func (r *terminationRepository) WithTx(tx *Tx) TerminationRepository {
+	return &terminationRepository{db: tx.db}
+}
+
+func (r *terminationRepository) Create(termination *domain.Termination) error {
+	return r.db.Create(termination).Error
+}
+
+func (r *terminationRepository) FindLatestByDistributor(distributorID uint) (*domain.Termination, error) {
+	var termination domain.Termination
+	err := r.db.Where("distributor_id = ?", distributorID).
+		Preload("RollUps").
+		Order("terminated_at DESC").
+		First(&termination).Error
+	
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("termination not found")
+		}
+		return nil, err
+	}
+	return &termination, nil
+}
+
+func (r *terminationRepository) Update(termination *domain.Termination) error {
+	return r.db.Omit("RollUps").Save(termination).Error
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+)
+
+// Tx is an open database transaction. Repositories join it through WithTx,
+// so a service can commit a change and its audit entry together.
+type Tx struct {
+	db *gorm.DB
+}
+
+type Transactor interface {
+	// Transaction runs fn in a transaction, committing if it returns nil
+	Transaction(fn func(tx *Tx) error) error
+}
+
+type transactor struct {
+	db *gorm.DB
+}
+
+func NewTransactor(db *gorm.DB) Transactor {
+	return &transactor{db: db}
+}
+
+func (t *transactor) Transaction(fn func(tx *Tx) error) error {
+	return t.db.Transaction(func(db *gorm.DB) error {
+		return fn(&Tx{db: db})
+	})
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/mlm-app/backend/internal/config"
+	"github.com/mlm-app/backend/internal/domain"
+	"github.com/mlm-app/backend/internal/repository"
+	"github.com/mlm-app/backend/pkg/mailer"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var errInvalidAccountToken = errors.New("invalid or expired token")
+
+type AccountService interface {
+	SendVerificationEmail(distributor *domain.Distributor) error
+	ResendVerification(email string) error
+	VerifyEmail(token string) error
+	RequestPasswordReset(email string) error
+	ResetPassword(token, newPassword string) error
+	ChangePassword(distributorID uint, currentPassword, newPassword string) error
+}
+
+type accountService struct {
+	distributorRepo  repository.DistributorRepository
+	accountTokenRepo repository.AccountTokenRepository
+	authService      AuthService
+	mailer           mailer.Mailer
+	config           *config.Config
+}
+
+func NewAccountService(
+	distributorRepo repository.DistributorRepository,
+	accountTokenRepo repository.AccountTokenRepository,
+	authService AuthService,
+	mailer mailer.Mailer,
+	cfg *config.Config,
+) AccountService {
+	return &accountService{
+		distributorRepo:  distributorRepo,
+		accountTokenRepo: accountTokenRepo,
+		authService:      authService,
+		mailer:           mailer,
+		config:           cfg,
+	}
+}
+
+// SendVerificationEmail emails a new verification link, cancelling any
+// link sent before
+func (s *accountService) SendVerificationEmail(distributor *domain.Distributor) error {
+	if distributor.EmailVerifiedAt != nil {
+		return nil
+	}
+	
+	token, err := s.createToken(distributor.ID, domain.TokenPurposeEmailVerification, s.config.Auth.EmailVerificationExpiry)
+	if err != nil {
+		return err
+	}
+	
+	return s.mailer.Send(mailer.Message{
+		To:      distributor.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\nThe link expires in %s.\n",
+			distributor.FirstName, s.link("/verify-email", token), s.config.Auth.EmailVerificationExpiry),
+	})
+}
+
+// ResendVerification sends a fresh verification link. Unknown addresses are
+// ignored so the endpoint cannot be used to discover accounts.
+func (s *accountService) ResendVerification(email string) error {
+	distributor, err := s.distributorRepo.FindByEmail(email)
+	if err != nil {
+		return nil
+	}
+	return s.SendVerificationEmail(distributor)
+}
+
+// VerifyEmail consumes a verification token and marks the address verified
+func (s *accountService) VerifyEmail(token string) error {
+	accountToken, err := s.consumeToken(domain.TokenPurposeEmailVerification, token)
+	if err != nil {
+		return err
+	}
+	return s.distributorRepo.MarkEmailVerified(accountToken.DistributorID, time.Now())
+}
+
+// RequestPasswordReset emails a reset link. Like ResendVerification it
+// succeeds silently for unknown addresses.
+func (s *accountService) RequestPasswordReset(email string) error {
+	distributor, err := s.distributorRepo.FindByEmail(email)
+	if err != nil {
+		return nil
+	}
+	
+	token, err := s.createToken(distributor.ID, domain.TokenPurposePasswordReset, s.config.Auth.PasswordResetExpiry)
+	if err != nil {
+		return err
+	}
+	
+	return s.mailer.Send(mailer.Message{
+		To:      distributor.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nWe received a request to reset your password. Open the link below to choose a new one:\n\n%s\n\nThe link expires in %s. If you did not ask for this, you can ignore this email.\n",
+			distributor.FirstName, s.link("/reset-password", token), s.config.Auth.PasswordResetExpiry),
+	})
+}
+
+// ResetPassword sets a new password from a reset token and signs the
+// distributor out everywhere. Receiving the link also proves they own the
+// email address.
+func (s *accountService) ResetPassword(token, newPassword string) error {
+	accountToken, err := s.consumeToken(domain.TokenPurposePasswordReset, token)
+	if err != nil {
+		return err
+	}
+	
+	if err := s.setPassword(accountToken.DistributorID, newPassword); err != nil {
+		return err
+	}
+	return s.distributorRepo.MarkEmailVerified(accountToken.DistributorID, time.Now())
+}
+
+// ChangePassword replaces the password of a signed-in distributor after
+// checking the current one, and revokes every existing session
+func (s *accountService) ChangePassword(distributorID uint, currentPassword, newPassword string) error {
+	distributor, err := s.distributorRepo.FindByID(distributorID)
+	if err != nil {
+		return err
+	}
+	
+	if err := bcrypt.CompareHashAndPassword([]byte(distributor.PasswordHash), []byte(currentPassword)); err != nil {
+		return errors.New("current password is incorrect")
+	}
+	
+	return s.setPassword(distributorID, newPassword)
+}
+
+func (s *accountService) setPassword(distributorID uint, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	
+	if err := s.distributorRepo.UpdatePassword(distributorID, string(hashedPassword)); err != nil {
+		return err
+	}
+	return s.authService.LogoutAll(distributorID)
+}
+
+func (s *accountService) createToken(distributorID uint, purpose string, ttl time.Duration) (string, error) {
+	if err := s.accountTokenRepo.InvalidateOutstanding(distributorID, purpose); err != nil {
+		return "", err
+	}
+	
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	
+	err = s.accountTokenRepo.Create(&domain.AccountToken{
+		DistributorID: distributorID,
+		Purpose:       purpose,
+		TokenHash:     hashToken(token),
+		ExpiresAt:     time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// consumeToken checks a token and marks it used; concurrent uses of the
+// same token cannot both succeed
+func (s *accountService) consumeToken(purpose, token string) (*domain.AccountToken, error) {
+	accountToken, err := s.accountTokenRepo.FindByHash(purpose, hashToken(token))
+	if err != nil {
+		return nil, errInvalidAccountToken
+	}
+	if accountToken.UsedAt != nil || time.Now().After(accountToken.ExpiresAt) {
+		return nil, errInvalidAccountToken
+	}
+	
+	used, err := s.accountTokenRepo.MarkUsed(accountToken.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, errInvalidAccountToken
+	}
+	return accountToken, nil
+}
+
+func (s *accountService) link(path, token string) string {
+	return s.config.Mail.AppURL + path + "?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/mlm-app/backend/internal/domain"
+	"github.com/mlm-app/backend/internal/middleware"
+	"github.com/mlm-app/backend/internal/repository"
+)
+
+var errInvalidAPIKey = errors.New("invalid or expired API key")
+
+// validScopes lists every scope an API key can be granted
+var validScopes = map[string]bool{
+	domain.ScopeOrdersRead:     true,
+	domain.ScopeOrdersWrite:    true,
+	domain.ScopeInventoryRead:  true,
+	domain.ScopeInventoryWrite: true,
+}
+
+type APIKeyService interface {
+	Create(name string, scopes []string, expiresAt *time.Time, meta AuditMeta) (*domain.NewAPIKey, error)
+	List() ([]domain.APIKey, error)
+	Revoke(id uint, meta AuditMeta) error
+	AuthenticateAPIKey(rawKey, ipAddress string) (*domain.APIKey, error)
+}
+
+type apiKeyService struct {
+	apiKeyRepo repository.APIKeyRepository
+	auditRepo  repository.AuditRepository
+	transactor repository.Transactor
+}
+
+func NewAPIKeyService(
+	apiKeyRepo repository.APIKeyRepository,
+	auditRepo repository.AuditRepository,
+	transactor repository.Transactor,
+) APIKeyService {
+	return &apiKeyService{
+		apiKeyRepo: apiKeyRepo,
+		auditRepo:  auditRepo,
+		transactor: transactor,
+	}
+}
+
+// Create generates a key with the given scopes. The plaintext is only
+// returned here; afterwards the key can only be identified by its prefix.
+func (s *apiKeyService) Create(name string, scopes []string, expiresAt *time.Time, meta AuditMeta) (*domain.NewAPIKey, error) {
+	if len(scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !validScopes[scope] {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, errors.New("expires_at must be in the future")
+	}
+	
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	rawKey := middleware.APIKeyPrefix + secret
+	
+	key := &domain.APIKey{
+		Name:        name,
+		Prefix:      rawKey[:len(middleware.APIKeyPrefix)+8],
+		KeyHash:     hashToken(rawKey),
+		Scopes:      strings.Join(scopes, ","),
+		ExpiresAt:   expiresAt,
+		CreatedByID: optionalID(meta.ActorID),
+	}
+	err = s.transactor.Transaction(func(tx *repository.Tx) error {
+		if err := s.apiKeyRepo.WithTx(tx).Create(key); err != nil {
+			return err
+		}
+		return s.auditRepo.WithTx(tx).Create(newAuditEntry(meta, "api_key.create", "api_key", key.ID, nil, key, ""))
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	return &domain.NewAPIKey{APIKey: key, Key: rawKey}, nil
+}
+
+func (s *apiKeyService) List() ([]domain.APIKey, error) {
+	return s.apiKeyRepo.List()
+}
+
+// Revoke disables a key immediately
+func (s *apiKeyService) Revoke(id uint, meta AuditMeta) error {
+	key, err := s.apiKeyRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if key.RevokedAt != nil {
+		return errors.New("API key is already revoked")
+	}
+	
+	return s.transactor.Transaction(func(tx *repository.Tx) error {
+		if err := s.apiKeyRepo.WithTx(tx).Revoke(id); err != nil {
+			return err
+		}
+		
+		revoked, err := s.apiKeyRepo.WithTx(tx).FindByID(id)
+		if err != nil {
+			return err
+		}
+		return s.auditRepo.WithTx(tx).Create(newAuditEntry(meta, "api_key.revoke", "api_key", id, key, revoked, ""))
+	})
+}
+
+// AuthenticateAPIKey resolves a presented key and records its use
+func (s *apiKeyService) AuthenticateAPIKey(rawKey, ipAddress string) (*domain.APIKey, error) {
+	if !strings.HasPrefix(rawKey, middleware.APIKeyPrefix) {
+		return nil, errInvalidAPIKey
+	}
+	
+	key, err := s.apiKeyRepo.FindByHash(hashToken(rawKey))
+	if err != nil {
+		return nil, errInvalidAPIKey
+	}
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && !key.ExpiresAt.After(time.Now())) {
+		return nil, errInvalidAPIKey
+	}
+	
+	// Usage tracking is best effort and must not fail the request
+	if err := s.apiKeyRepo.TouchLastUsed(key.ID, ipAddress); err != nil {
+		log.Printf("failed to record use of API key %d: %v", key.ID, err)
+	}
+	return key, nil
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mlm-app/backend/internal/domain"
+	"github.com/mlm-app/backend/internal/middleware"
+	"github.com/mlm-app/backend/internal/repository"
+)
+
+// apiKeyRepo is an in-memory APIKeyRepository
+type apiKeyRepo struct {
+	repository.APIKeyRepository
+
+	keys    map[string]domain.APIKey
+	touched []uint
+}
+
+func (r *apiKeyRepo) FindByHash(keyHash string) (*domain.APIKey, error) {
+	key, ok := r.keys[keyHash]
+	if !ok {
+		return nil, errors.New("API key not found")
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepo) TouchLastUsed(id uint, ipAddress string) error {
+	r.touched = append(r.touched, id)
+	return nil
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		key     domain.APIKey
+		wantErr bool
+	}{
+		{"no expiry", domain.APIKey{ID: 1}, false},
+		{"expires later", domain.APIKey{ID: 2, ExpiresAt: &future}, false},
+		{"expired", domain.APIKey{ID: 3, ExpiresAt: &past}, true},
+		{"revoked", domain.APIKey{ID: 4, RevokedAt: &past}, true},
+		{"revoked before expiry", domain.APIKey{ID: 5, ExpiresAt: &future, RevokedAt: &past}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rawKey := middleware.APIKeyPrefix + tt.name
+			repo := &apiKeyRepo{keys: map[string]domain.APIKey{hashToken(rawKey): tt.key}}
+			s := &apiKeyService{apiKeyRepo: repo}
+
+			key, err := s.AuthenticateAPIKey(rawKey, "127.0.0.1")
+			if tt.wantErr {
+				if !errors.Is(err, errInvalidAPIKey) {
+					t.Errorf("err = %v, want errInvalidAPIKey", err)
+				}
+				if len(repo.touched) != 0 {
+					t.Error("a refused key was recorded as used")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("valid key refused: %v", err)
+			}
+			if key.ID != tt.key.ID {
+				t.Errorf("resolved key %d, want %d", key.ID, tt.key.ID)
+			}
+			if len(repo.touched) != 1 || repo.touched[0] != tt.key.ID {
+				t.Errorf("last use recorded for %v, want [%d]", repo.touched, tt.key.ID)
+			}
+		})
+	}
+}
+
+func TestAuthenticateAPIKeyRejectsUnknownKeys(t *testing.T) {
+	rawKey := middleware.APIKeyPrefix + "known"
+	repo := &apiKeyRepo{keys: map[string]domain.APIKey{hashToken(rawKey): {ID: 1}}}
+	s := &apiKeyService{apiKeyRepo: repo}
+
+	for _, presented := range []string{
+		middleware.APIKeyPrefix + "unknown",
+		"known",
+		"",
+	} {
+		if _, err := s.AuthenticateAPIKey(presented, "127.0.0.1"); !errors.Is(err, errInvalidAPIKey) {
+			t.Errorf("AuthenticateAPIKey(%q): err = %v, want errInvalidAPIKey", presented, err)
+		}
+	}
+}
+
+func TestCreateAPIKeyValidation(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		scopes    []string
+		expiresAt *time.Time
+	}{
+		{"no scopes", nil, nil},
+		{"unknown scope", []string{domain.ScopeOrdersRead, "admin:all"}, nil},
+		{"expired", []string{domain.ScopeOrdersRead}, &past},
+		{"expires now", []string{domain.ScopeOrdersRead}, &now},
+	}
+
+	s := &apiKeyService{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Create("integration", tt.scopes, tt.expiresAt, AuditMeta{}); err == nil {
+				t.Error("invalid key created")
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/mlm-app/backend/internal/domain"
+	"github.com/mlm-app/backend/internal/repository"
+)
+
+// AuditMeta identifies who made a change and in which request. The zero
+// value stands for a system job.
+type AuditMeta struct {
+	ActorID        uint
+	ImpersonatorID uint
+	APIKeyID       uint
+	IPAddress      string
+	RequestID      string
+}
+
+// fieldChange is one entry of AuditLog.Changes
+type fieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+type AuditService interface {
+	List(filter repository.AuditFilter, offset, limit int) ([]domain.AuditLog, int64, error)
+}
+
+type auditService struct {
+	auditRepo repository.AuditRepository
+}
+
+func NewAuditService(auditRepo repository.AuditRepository) AuditService {
+	return &auditService{
+		auditRepo: auditRepo,
+	}
+}
+
+// List returns audit entries matching filter, newest first
+func (s *auditService) List(filter repository.AuditFilter, offset, limit int) ([]domain.AuditLog, int64, error) {
+	return s.auditRepo.List(filter, offset, limit)
+}
+
+// newAuditEntry builds an audit entry from JSON snapshots of the entity
+// before and after the change; either may be nil for creations and
+// deletions. Top-level fields that differ are listed in Changes.
+func newAuditEntry(meta AuditMeta, action, entityType string, entityID uint, before, after interface{}, reason string) *domain.AuditLog {
+	entry := &domain.AuditLog{
+		ActorID:        optionalID(meta.ActorID),
+		ImpersonatorID: optionalID(meta.ImpersonatorID),
+		APIKeyID:       optionalID(meta.APIKeyID),
+		Action:         action,
+		EntityType:     entityType,
+		EntityID:       entityID,
+		Reason:         truncate(reason, 500),
+		IPAddress:      meta.IPAddress,
+		RequestID:      meta.RequestID,
+	}
+	
+	beforeFields := toFields(before)
+	afterFields := toFields(after)
+	if before != nil {
+		data, _ := json.Marshal(beforeFields)
+		entry.Before = string(data)
+	}
+	if after != nil {
+		data, _ := json.Marshal(afterFields)
+		entry.After = string(data)
+	}
+	
+	changes := make(map[string]fieldChange)
+	for key, from := range beforeFields {
+		if to, ok := afterFields[key]; !ok || !reflect.DeepEqual(from, to) {
+			changes[key] = fieldChange{From: from, To: afterFields[key]}
+		}
+	}
+	for key, to := range afterFields {
+		if _, ok := beforeFields[key]; !ok {
+			changes[key] = fieldChange{To: to}
+		}
+	}
+	if len(changes) > 0 {
+		data, _ := json.Marshal(changes)
+		entry.Changes = string(data)
+	}
+	
+	return entry
+}
+
+// toFields round-trips a snapshot through JSON so structs and maps compare
+// field by field under their JSON names
+func toFields(snapshot interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if snapshot == nil {
+		return fields
+	}
+	
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fields
+	}
+	json.Unmarshal(data, &fields)
+	return fields
+}
+
+func optionalID(id uint) *uint {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/mlm-app/backend/internal/config"
+	"github.com/mlm-app/backend/internal/domain"
+	"github.com/mlm-app/backend/internal/middleware"
+	"github.com/mlm-app/backend/internal/repository"
+	"github.com/mlm-app/backend/pkg/jwtkeys"
+)
+
+var errInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+type AuthService interface {
+	IssueTokens(distributor *domain.Distributor, twoFactor bool, userAgent, ipAddress string) (*domain.TokenPair, error)
+	IssueChallenge(distributor *domain.Distributor) (*domain.LoginChallenge, error)
+	Refresh(refreshToken, userAgent, ipAddress string) (*domain.TokenPair, error)
+	Logout(refreshToken string) error
+	LogoutAll(distributorID uint) error
+	ValidateAccess(distributorID uint, tokenVersion int) error
+	Impersonate(distributorID uint, meta AuditMeta, reason string) (*domain.ImpersonationToken, error)
+}
+
+type authService struct {
+	distributorRepo  repository.DistributorRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+	auditRepo        repository.AuditRepository
+	keys             *jwtkeys.KeySet
+	config           *config.Config
+}
+
+func NewAuthService(
+	distributorRepo repository.DistributorRepository,
+	refreshTokenRepo repository.RefreshTokenRepository,
+	auditRepo repository.AuditRepository,
+	keys *jwtkeys.KeySet,
+	cfg *config.Config,
+) AuthService {
+	return &authService{
+		distributorRepo:  distributorRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		auditRepo:        auditRepo,
+		keys:             keys,
+		config:           cfg,
+	}
+}
+
+// IssueTokens starts a new session: a short-lived access token and a
+// refresh token in a new rotation family. twoFactor records whether the
+// login passed a second factor, which admin routes require.
+func (s *authService) IssueTokens(distributor *domain.Distributor, twoFactor bool, userAgent, ipAddress string) (*domain.TokenPair, error) {
+	familyID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	return s.issue(distributor, familyID, twoFactor, userAgent, ipAddress, nil)
+}
+
+// IssueChallenge is used instead of IssueTokens when the password was right
+// but a TOTP or recovery code is still needed
+func (s *authService) IssueChallenge(distributor *domain.Distributor) (*domain.LoginChallenge, error) {
+	expiresAt := time.Now().Add(s.config.Auth.LoginChallengeExpiry)
+	token, err := middleware.GenerateChallengeToken(distributor.ID, distributor.TokenVersion, expiresAt, s.keys)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.LoginChallenge{ChallengeToken: token, ExpiresAt: expiresAt}, nil
+}
+
+// Refresh exchanges a refresh token for a new pair. The old refresh token
+// stops working; if it is ever presented again the whole session is revoked,
+// since that means it was stolen or replayed.
+func (s *authService) Refresh(refreshToken, userAgent, ipAddress string) (*domain.TokenPair, error) {
+	current, err := s.refreshTokenRepo.FindByHash(hashToken(refreshToken))
+	if err != nil {
+		return nil, errInvalidRefreshToken
+	}
+	
+	if current.RevokedAt != nil {
+		if err := s.refreshTokenRepo.RevokeFamily(current.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, errInvalidRefreshToken
+	}
+	if time.Now().After(current.ExpiresAt) {
+		return nil, errInvalidRefreshToken
+	}
+	
+	distributor, err := s.distributorRepo.FindAuthState(current.DistributorID)
+	if err != nil {
+		return nil, errInvalidRefreshToken
+	}
+	if err := checkAccountStatus(distributor); err != nil {
+		return nil, err
+	}
+	
+	pair, err := s.issue(distributor, current.FamilyID, current.TwoFactor, userAgent, ipAddress, current)
+	if errors.Is(err, repository.ErrRefreshTokenReused) {
+		// Lost a race with another use of the same token
+		if err := s.refreshTokenRepo.RevokeFamily(current.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, errInvalidRefreshToken
+	}
+	return pair, err
+}
+
+// Logout ends the session the refresh token belongs to
+func (s *authService) Logout(refreshToken string) error {
+	token, err := s.refreshTokenRepo.FindByHash(hashToken(refreshToken))
+	if err != nil {
+		return errInvalidRefreshToken
+	}
+	return s.refreshTokenRepo.RevokeFamily(token.FamilyID)
+}
+
+// LogoutAll ends every session and invalidates every access token already
+// issued to the distributor
+func (s *authService) LogoutAll(distributorID uint) error {
+	if err := s.distributorRepo.IncrementTokenVersion(distributorID); err != nil {
+		return err
+	}
+	return s.refreshTokenRepo.RevokeAllForDistributor(distributorID)
+}
+
+// ValidateAccess implements middleware.TokenValidator
+func (s *authService) ValidateAccess(distributorID uint, tokenVersion int) error {
+	distributor, err := s.distributorRepo.FindAuthState(distributorID)
+	if err != nil {
+		return errors.New("account no longer exists")
+	}
+	if distributor.TokenVersion != tokenVersion {
+		return errors.New("token has been revoked")
+	}
+	return checkAccountStatus(distributor)
+}
+
+// Impersonate issues a short-lived, read-only token acting as a distributor
+// on behalf of an admin. Admin accounts cannot be impersonated, and the
+// token never carries the second-factor flag, so it opens no admin routes.
+func (s *authService) Impersonate(distributorID uint, meta AuditMeta, reason string) (*domain.ImpersonationToken, error) {
+	admin, err := s.distributorRepo.FindAuthState(meta.ActorID)
+	if err != nil {
+		return nil, err
+	}
+	distributor, err := s.distributorRepo.FindByID(distributorID)
+	if err != nil {
+		return nil, err
+	}
+	if distributor.Role == "admin" {
+		return nil, errors.New("admin accounts cannot be impersonated")
+	}
+	if err := checkAccountStatus(distributor); err != nil {
+		return nil, err
+	}
+	
+	expiresAt := time.Now().Add(s.config.Auth.ImpersonationExpiry)
+	token, err := middleware.GenerateToken(middleware.Claims{
+		DistributorID:       distributor.ID,
+		Email:               distributor.Email,
+		Role:                distributor.Role,
+		TokenVersion:        distributor.TokenVersion,
+		ImpersonatorID:      admin.ID,
+		ImpersonatorVersion: admin.TokenVersion,
+	}, expiresAt, s.keys)
+	if err != nil {
+		return nil, err
+	}
+	
+	after := map[string]interface{}{"expires_at": expiresAt}
+	if err := s.auditRepo.Create(newAuditEntry(meta, "distributor.impersonate", "distributor", distributor.ID, nil, after, reason)); err != nil {
+		return nil, err
+	}
+	
+	return &domain.ImpersonationToken{AccessToken: token, ExpiresAt: expiresAt, Distributor: distributor}, nil
+}
+
+// issue signs an access token and stores a new refresh token, rotating
+// current out when refreshing
+func (s *authService) issue(distributor *domain.Distributor, familyID string, twoFactor bool, userAgent, ipAddress string, current *domain.RefreshToken) (*domain.TokenPair, error) {
+	now := time.Now()
+	pair := &domain.TokenPair{
+		ExpiresAt:        now.Add(s.config.JWT.Expiry),
+		RefreshExpiresAt: now.Add(s.config.JWT.RefreshExpiry),
+	}
+	
+	accessToken, err := middleware.GenerateToken(middleware.Claims{
+		DistributorID: distributor.ID,
+		Email:         distributor.Email,
+		Role:          distributor.Role,
+		TokenVersion:  distributor.TokenVersion,
+		TwoFactor:     twoFactor,
+	}, pair.ExpiresAt, s.keys)
+	if err != nil {
+		return nil, err
+	}
+	pair.AccessToken = accessToken
+	
+	pair.RefreshToken, err = randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	next := &domain.RefreshToken{
+		DistributorID: distributor.ID,
+		FamilyID:      familyID,
+		TokenHash:     hashToken(pair.RefreshToken),
+		ExpiresAt:     pair.RefreshExpiresAt,
+		TwoFactor:     twoFactor,
+		UserAgent:     truncate(userAgent, 255),
+		IPAddress:     ipAddress,
+	}
+	
+	if current != nil {
+		err = s.refreshTokenRepo.Rotate(current, next)
+	} else {
+		err = s.refreshTokenRepo.Create(next)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// checkAccountStatus is the status rule enforced at login and on every
+// request. Inactive distributors keep access so they can buy their way back.
+func checkAccountStatus(distributor *domain.Distributor) error {
+	switch distributor.Status {
+	case domain.StatusActive, domain.StatusInactive:
+		return nil
+	case domain.StatusSuspended:
+		return ErrAccountSuspended
+	}
+	return errors.New("account is not active")
+}
+
+func randomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func truncate(value string, max int) string {
+	if len(value) > max {
+		return value[:max]
+	}
+	return value
+}
@@ -0,0 +1,317 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mlm-app/backend/internal/config"
+	"github.com/mlm-app/backend/internal/domain"
+	"github.com/mlm-app/backend/internal/repository"
+	"github.com/mlm-app/backend/pkg/jwtkeys"
+)
+
+// authRepo is an in-memory DistributorRepository covering the calls the
+// auth and two-factor services make
+type authRepo struct {
+	repository.DistributorRepository
+
+	mu           sync.Mutex
+	distributors map[uint]domain.Distributor
+}
+
+func newAuthRepo(distributors ...domain.Distributor) *authRepo {
+	r := &authRepo{distributors: make(map[uint]domain.Distributor)}
+	for _, d := range distributors {
+		r.distributors[d.ID] = d
+	}
+	return r
+}
+
+func (r *authRepo) FindAuthState(id uint) (*domain.Distributor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d, ok := r.distributors[id]
+	if !ok {
+		return nil, errors.New("distributor not found")
+	}
+	return &d, nil
+}
+
+func (r *authRepo) IncrementTokenVersion(distributorID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d := r.distributors[distributorID]
+	d.TokenVersion++
+	r.distributors[distributorID] = d
+	return nil
+}
+
+func (r *authRepo) ConsumeTwoFactorStep(distributorID uint, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d := r.distributors[distributorID]
+	if d.TwoFactorLastStep >= step {
+		return false, nil
+	}
+	d.TwoFactorLastStep = step
+	r.distributors[distributorID] = d
+	return true, nil
+}
+
+// refreshRepo is an in-memory RefreshTokenRepository with the same
+// single-use rotation guarantee as the database one
+type refreshRepo struct {
+	mu     sync.Mutex
+	nextID uint
+	tokens map[uint]domain.RefreshToken
+
+	beforeRotate func() // Runs once at the start of the next Rotate
+}
+
+func newRefreshRepo() *refreshRepo {
+	return &refreshRepo{tokens: make(map[uint]domain.RefreshToken)}
+}
+
+func (r *refreshRepo) Create(token *domain.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.create(token)
+	return nil
+}
+
+func (r *refreshRepo) create(token *domain.RefreshToken) {
+	r.nextID++
+	token.ID = r.nextID
+	r.tokens[token.ID] = *token
+}
+
+func (r *refreshRepo) FindByHash(tokenHash string) (*domain.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			return &token, nil
+		}
+	}
+	return nil, errors.New("refresh token not found")
+}
+
+func (r *refreshRepo) Rotate(current, next *domain.RefreshToken) error {
+	if hook := r.beforeRotate; hook != nil {
+		r.beforeRotate = nil
+		hook()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := r.tokens[current.ID]
+	if stored.RevokedAt != nil {
+		return repository.ErrRefreshTokenReused
+	}
+	now := time.Now()
+	stored.RevokedAt = &now
+	r.create(next)
+	stored.ReplacedByID = &next.ID
+	r.tokens[current.ID] = stored
+	return nil
+}
+
+func (r *refreshRepo) Revoke(id uint) error {
+	return r.revokeWhere(func(token domain.RefreshToken) bool { return token.ID == id })
+}
+
+func (r *refreshRepo) RevokeFamily(familyID string) error {
+	return r.revokeWhere(func(token domain.RefreshToken) bool { return token.FamilyID == familyID })
+}
+
+func (r *refreshRepo) RevokeAllForDistributor(distributorID uint) error {
+	return r.revokeWhere(func(token domain.RefreshToken) bool { return token.DistributorID == distributorID })
+}
+
+func (r *refreshRepo) revokeWhere(match func(domain.RefreshToken) bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for id, token := range r.tokens {
+		if match(token) && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			r.tokens[id] = token
+		}
+	}
+	return nil
+}
+
+func newTestAuthService(t *testing.T) (*authService, *authRepo, *refreshRepo) {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.JWT.Secret = "test-secret"
+	cfg.JWT.Expiry = 15 * time.Minute
+	cfg.JWT.RefreshExpiry = 24 * time.Hour
+	keys, err := jwtkeys.Load(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	distributors := newAuthRepo(domain.Distributor{ID: 1, Email: "a@example.com", Role: "distributor", Status: domain.StatusActive})
+	tokens := newRefreshRepo()
+	s := NewAuthService(distributors, tokens, nil, keys, cfg).(*authService)
+	return s, distributors, tokens
+}
+
+func login(t *testing.T, s *authService, repo *authRepo) *domain.TokenPair {
+	t.Helper()
+	distributor, err := repo.FindAuthState(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pair, err := s.IssueTokens(distributor, false, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pair
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	s, repo, tokens := newTestAuthService(t)
+	first := login(t, s, repo)
+
+	second, err := s.Refresh(first.RefreshToken, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("Refresh returned the same refresh token")
+	}
+
+	old, _ := tokens.FindByHash(hashToken(first.RefreshToken))
+	next, _ := tokens.FindByHash(hashToken(second.RefreshToken))
+	if old.RevokedAt == nil {
+		t.Error("rotated token is still valid")
+	}
+	if old.ReplacedByID == nil || *old.ReplacedByID != next.ID {
+		t.Error("rotated token does not point at its replacement")
+	}
+	if next.FamilyID != old.FamilyID {
+		t.Error("rotation started a new family")
+	}
+
+	if _, err := s.Refresh(second.RefreshToken, "test", "127.0.0.1"); err != nil {
+		t.Errorf("refreshing with the new token: %v", err)
+	}
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	s, repo, tokens := newTestAuthService(t)
+	stolen := login(t, s, repo)
+	other := login(t, s, repo)
+
+	rotated, err := s.Refresh(stolen.RefreshToken, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Presenting the rotated-out token again means it was replayed
+	if _, err := s.Refresh(stolen.RefreshToken, "attacker", "10.0.0.1"); !errors.Is(err, errInvalidRefreshToken) {
+		t.Fatalf("reused token: err = %v, want errInvalidRefreshToken", err)
+	}
+	if _, err := s.Refresh(rotated.RefreshToken, "test", "127.0.0.1"); !errors.Is(err, errInvalidRefreshToken) {
+		t.Errorf("token from the replayed family still works: err = %v", err)
+	}
+
+	// Other sessions of the same distributor are a different family
+	token, _ := tokens.FindByHash(hashToken(other.RefreshToken))
+	if token.RevokedAt != nil {
+		t.Error("reuse revoked an unrelated session")
+	}
+	if _, err := s.Refresh(other.RefreshToken, "test", "127.0.0.1"); err != nil {
+		t.Errorf("unrelated session: %v", err)
+	}
+}
+
+func TestRefreshLostRaceRevokesFamily(t *testing.T) {
+	s, repo, tokens := newTestAuthService(t)
+	pair := login(t, s, repo)
+
+	// Another request rotates the same token between this request's lookup
+	// and its rotation
+	var winner *domain.TokenPair
+	tokens.beforeRotate = func() {
+		var err error
+		if winner, err = s.Refresh(pair.RefreshToken, "other", "10.0.0.1"); err != nil {
+			t.Fatalf("competing refresh: %v", err)
+		}
+	}
+
+	if _, err := s.Refresh(pair.RefreshToken, "test", "127.0.0.1"); !errors.Is(err, errInvalidRefreshToken) {
+		t.Fatalf("losing refresh: err = %v, want errInvalidRefreshToken", err)
+	}
+	if _, err := s.Refresh(winner.RefreshToken, "other", "10.0.0.1"); !errors.Is(err, errInvalidRefreshToken) {
+		t.Errorf("family survived the concurrent reuse: err = %v", err)
+	}
+}
+
+func TestRefreshRejectsExpiredToken(t *testing.T) {
+	s, repo, tokens := newTestAuthService(t)
+	pair := login(t, s, repo)
+
+	token, _ := tokens.FindByHash(hashToken(pair.RefreshToken))
+	token.ExpiresAt = time.Now().Add(-time.Second)
+	tokens.tokens[token.ID] = *token
+
+	if _, err := s.Refresh(pair.RefreshToken, "test", "127.0.0.1"); !errors.Is(err, errInvalidRefreshToken) {
+		t.Errorf("expired token: err = %v, want errInvalidRefreshToken", err)
+	}
+}
+
+func TestRefreshRejectsSuspendedAccount(t *testing.T) {
+	s, repo, _ := newTestAuthService(t)
+	pair := login(t, s, repo)
+
+	d := repo.distributors[1]
+	d.Status = domain.StatusSuspended
+	repo.distributors[1] = d
+
+	if _, err := s.Refresh(pair.RefreshToken, "test", "127.0.0.1"); !errors.Is(err, ErrAccountSuspended) {
+		t.Errorf("suspended account: err = %v, want ErrAccountSuspended", err)
+	}
+}
+
+func TestLogoutRevokesSession(t *testing.T) {
+	s, repo, _ := newTestAuthService(t)
+	pair := login(t, s, repo)
+	rotated, err := s.Refresh(pair.RefreshToken, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Logout(rotated.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Refresh(rotated.RefreshToken, "test", "127.0.0.1"); !errors.Is(err, errInvalidRefreshToken) {
+		t.Errorf("refresh after logout: err = %v, want errInvalidRefreshToken", err)
+	}
+}
+
+func TestLogoutAllRevokesAccessTokens(t *testing.T) {
+	s, repo, _ := newTestAuthService(t)
+	first := login(t, s, repo)
+	second := login(t, s, repo)
+
+	if err := s.ValidateAccess(1, 0); err != nil {
+		t.Fatalf("ValidateAccess before logout: %v", err)
+	}
+	if err := s.LogoutAll(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.ValidateAccess(1, 0); err == nil {
+		t.Error("access token from before LogoutAll still accepted")
+	}
+	for _, pair := range []*domain.TokenPair{first, second} {
+		if _, err := s.Refresh(pair.RefreshToken, "test", "127.0.0.1"); !errors.Is(err, errInvalidRefreshToken) {
+			t.Errorf("refresh after LogoutAll: err = %v, want errInvalidRefreshToken", err)
+		}
+	}
+}
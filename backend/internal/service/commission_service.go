@@ -10,38 +10,50 @@ import (
 )
 
 type CommissionService interface {
-	CalculateAndCreateCommissions(order *domain.Order) error
+	CalculateAndCreateCommissions(tx *repository.Tx, order *domain.Order) error
 	CalculateDirectReferralCommission(order *domain.Order) (*domain.Commission, error)
 	CalculateLevelCommissions(order *domain.Order) ([]domain.Commission, error)
+	CalculateGenerationBonuses(order *domain.Order) ([]domain.Commission, error)
+	CalculateBreakawayOverrides(order *domain.Order) ([]domain.Commission, error)
 	CalculateRankBonus(distributorID uint) (*domain.Commission, error)
-	ApproveCommission(commissionID uint) error
-	PayCommission(commissionID uint) error
+	ApproveCommission(commissionID uint, meta AuditMeta) error
+	PayCommission(commissionID uint, meta AuditMeta) error
 	GetDistributorCommissions(distributorID uint, offset, limit int) ([]domain.Commission, int64, error)
+	List(offset, limit int) ([]domain.Commission, int64, error)
 }
 
 type commissionService struct {
 	commissionRepo  repository.CommissionRepository
 	distributorRepo repository.DistributorRepository
+	auditRepo       repository.AuditRepository
 	treeService     TreeService
+	transactor      repository.Transactor
 	config          *config.Config
 }
 
 func NewCommissionService(
 	commissionRepo repository.CommissionRepository,
 	distributorRepo repository.DistributorRepository,
+	auditRepo repository.AuditRepository,
 	treeService TreeService,
+	transactor repository.Transactor,
 	cfg *config.Config,
 ) CommissionService {
 	return &commissionService{
 		commissionRepo:  commissionRepo,
 		distributorRepo: distributorRepo,
+		auditRepo:       auditRepo,
 		treeService:     treeService,
+		transactor:      transactor,
 		config:          cfg,
 	}
 }
 
-// CalculateAndCreateCommissions calculates all commissions for an order
-func (s *commissionService) CalculateAndCreateCommissions(order *domain.Order) error {
+// CalculateAndCreateCommissions calculates all commissions for a paid order
+// and records its volume, inside the payment's transaction
+func (s *commissionService) CalculateAndCreateCommissions(tx *repository.Tx, order *domain.Order) error {
+	commissionRepo := s.commissionRepo.WithTx(tx)
+	distributorRepo := s.distributorRepo.WithTx(tx)
 	var commissions []domain.Commission
 	
 	// 1. Direct referral commission
@@ -56,19 +68,43 @@ func (s *commissionService) CalculateAndCreateCommissions(order *domain.Order) e
 		commissions = append(commissions, levelComms...)
 	}
 	
-	// 3. Save all commissions
+	// 3. Generation bonuses (rank-based generations)
+	if s.config.MLM.GenerationBonusEnabled {
+		genComms, err := s.CalculateGenerationBonuses(order)
+		if err == nil && len(genComms) > 0 {
+			commissions = append(commissions, genComms...)
+		}
+	}
+	
+	// 4. Breakaway overrides on groups that split from their sponsor
+	overrideComms, err := s.CalculateBreakawayOverrides(order)
+	if err == nil && len(overrideComms) > 0 {
+		commissions = append(commissions, overrideComms...)
+	}
+	
+	// 5. Save all commissions
 	if len(commissions) > 0 {
-		if err := s.commissionRepo.BulkCreate(commissions); err != nil {
+		if err := commissionRepo.BulkCreate(commissions); err != nil {
 			return err
 		}
 		
 		// Update distributor commission totals
 		for _, comm := range commissions {
-			s.distributorRepo.UpdateCommission(comm.DistributorID, comm.Amount)
+			if err := distributorRepo.UpdateCommission(comm.DistributorID, comm.Amount); err != nil {
+				return err
+			}
 		}
 	}
 	
-	return nil
+	// The buyer's personal sales record the volume they carry into their
+	// uplines' legs, so a later move takes exactly that volume along
+	volume := s.calculateCommissionableValue(order)
+	if err := distributorRepo.UpdateSales(order.DistributorID, volume); err != nil {
+		return err
+	}
+	
+	// Binary and hybrid placement trees accumulate leg volume for pairing
+	return s.treeService.AddLegVolumeInTx(tx, order.DistributorID, volume)
 }
 
 // CalculateDirectReferralCommission calculates commission for direct sponsor
@@ -96,24 +132,26 @@ func (s *commissionService) CalculateDirectReferralCommission(order *domain.Orde
 	
 	// Calculate commissionable value from order items
 	commissionableValue := s.calculateCommissionableValue(order)
-	amount := commissionableValue * (commissionRate / 100)
 	
 	commission := &domain.Commission{
 		DistributorID:     sponsor.ID,
 		OrderID:           &order.ID,
 		Type:              "direct",
 		Level:             1,
-		Amount:            amount,
-		Percentage:        commissionRate,
 		FromDistributorID: &distributor.ID,
 		Status:            "pending",
 		Description:       fmt.Sprintf("Direct referral commission from order #%s", order.OrderNumber),
 	}
+	s.applyRate(commission, sponsor, commissionableValue, commissionRate)
 	
 	return commission, nil
 }
 
-// CalculateLevelCommissions calculates commissions for upline chain
+// CalculateLevelCommissions calculates commissions for upline chain.
+// Levels follow the enrollment tree (SponsorID), so hybrid plans pay
+// unilevel-style regardless of binary placement.
+// With compression enabled, a level whose upline is inactive or unqualified
+// passes up to the next qualified upline instead of being dropped.
 func (s *commissionService) CalculateLevelCommissions(order *domain.Order) ([]domain.Commission, error) {
 	var commissions []domain.Commission
 	
@@ -128,7 +166,13 @@ func (s *commissionService) CalculateLevelCommissions(order *domain.Order) ([]do
 		maxLevels = distributor.Package.MaxLevels
 	}
 	
-	upline, err := s.treeService.GetUplineChain(distributor.ID, maxLevels)
+	// Compression may skip uplines, so it needs the chain up to the root
+	chainLength := maxLevels
+	if s.config.MLM.CompressionEnabled && distributor.Level > chainLength {
+		chainLength = distributor.Level
+	}
+	
+	upline, err := s.treeService.GetUplineChain(distributor.ID, chainLength)
 	if err != nil {
 		return nil, err
 	}
@@ -137,36 +181,197 @@ func (s *commissionService) CalculateLevelCommissions(order *domain.Order) ([]do
 	levelPercentage := s.config.MLM.LevelCommissionPercentage
 	
 	// Calculate commission for each level
+	paidLevels := 0
 	for i, uplineDistributor := range upline {
-		level := i + 2 // Level 1 is direct, so start from 2
+		if paidLevels >= maxLevels {
+			break
+		}
+		
+		// In breakaway plans, level pay stops at the first group that broke
+		// away; the leader above it earns a breakaway override instead
+		if distributor.TreeType == domain.TreeTypeBreakaway {
+			child := distributor
+			if i > 0 {
+				child = &upline[i-1]
+			}
+			if child.BrokeAwayAt != nil {
+				break
+			}
+		}
+		
+		actualLevel := i + 2 // Level 1 is direct, so start from 2
+		level := actualLevel
+		if s.config.MLM.CompressionEnabled {
+			level = paidLevels + 2
+		}
 		
-		// Skip if distributor is not active
-		if uplineDistributor.Status != "active" {
+		// Skip if distributor is not active; with compression the level
+		// rolls up to the next qualified upline
+		if !s.isQualified(&uplineDistributor) {
+			if !s.config.MLM.CompressionEnabled {
+				paidLevels++
+			}
 			continue
 		}
+		paidLevels++
 		
 		// Calculate commission with decreasing percentage
 		percentage := levelPercentage / float64(level)
-		amount := commissionableValue * (percentage / 100)
+		
+		description := fmt.Sprintf("Level %d commission from order #%s", level, order.OrderNumber)
+		if level != actualLevel {
+			description = fmt.Sprintf("Level %d commission (compressed from level %d) from order #%s", level, actualLevel, order.OrderNumber)
+		}
 		
 		commission := domain.Commission{
 			DistributorID:     uplineDistributor.ID,
 			OrderID:           &order.ID,
 			Type:              "level",
 			Level:             level,
+			FromDistributorID: &distributor.ID,
+			Status:            "pending",
+			Description:       description,
+		}
+		s.applyRate(&commission, &upline[i], commissionableValue, percentage)
+		
+		commissions = append(commissions, commission)
+	}
+	
+	return commissions, nil
+}
+
+// CalculateGenerationBonuses pays uplines by generation rather than by level.
+// Every qualified upline holding at least GenerationRankLevel closes a
+// generation, so the first Gold above the buyer earns generation 1, the next
+// Gold above them earns generation 2, and so on.
+func (s *commissionService) CalculateGenerationBonuses(order *domain.Order) ([]domain.Commission, error) {
+	var commissions []domain.Commission
+	
+	percentages := s.config.MLM.GenerationBonusPercentages
+	if len(percentages) == 0 {
+		return nil, nil
+	}
+	
+	distributor, err := s.distributorRepo.FindByID(order.DistributorID)
+	if err != nil {
+		return nil, err
+	}
+	
+	upline, err := s.treeService.GetUplineChain(distributor.ID, distributor.Level)
+	if err != nil {
+		return nil, err
+	}
+	
+	commissionableValue := s.calculateCommissionableValue(order)
+	generation := 0
+	
+	for _, uplineDistributor := range upline {
+		if generation >= len(percentages) {
+			break
+		}
+		
+		if uplineDistributor.Rank == nil || uplineDistributor.Rank.Level < s.config.MLM.GenerationRankLevel {
+			continue
+		}
+		
+		// Unqualified leaders are compressed out of the generation count
+		if !s.isQualified(&uplineDistributor) {
+			continue
+		}
+		
+		percentage := percentages[generation]
+		generation++
+		amount := commissionableValue * (percentage / 100)
+		
+		commissions = append(commissions, domain.Commission{
+			DistributorID:     uplineDistributor.ID,
+			OrderID:           &order.ID,
+			Type:              "generation",
+			Level:             generation,
 			Amount:            amount,
 			Percentage:        percentage,
+			BaseAmount:        amount,
+			BasePercentage:    percentage,
 			FromDistributorID: &distributor.ID,
 			Status:            "pending",
-			Description:       fmt.Sprintf("Level %d commission from order #%s", level, order.OrderNumber),
+			Description:       fmt.Sprintf("Generation %d bonus (%s) from order #%s", generation, uplineDistributor.Rank.Name, order.OrderNumber),
+		})
+	}
+	
+	return commissions, nil
+}
+
+// CalculateBreakawayOverrides pays the sponsor of each broken-away group above
+// the buyer an override, one configured percentage per breakaway generation
+func (s *commissionService) CalculateBreakawayOverrides(order *domain.Order) ([]domain.Commission, error) {
+	var commissions []domain.Commission
+	
+	distributor, err := s.distributorRepo.FindByID(order.DistributorID)
+	if err != nil {
+		return nil, err
+	}
+	
+	percentages := s.config.MLM.BreakawayOverridePercentages
+	if distributor.TreeType != domain.TreeTypeBreakaway || len(percentages) == 0 {
+		return nil, nil
+	}
+	
+	upline, err := s.treeService.GetUplineChain(distributor.ID, distributor.Level)
+	if err != nil {
+		return nil, err
+	}
+	
+	commissionableValue := s.calculateCommissionableValue(order)
+	generation := 0
+	
+	for i := range upline {
+		if generation >= len(percentages) {
+			break
 		}
 		
-		commissions = append(commissions, commission)
+		child := distributor
+		if i > 0 {
+			child = &upline[i-1]
+		}
+		if child.BrokeAwayAt == nil {
+			continue
+		}
+		
+		leader := &upline[i]
+		if !s.isQualified(leader) {
+			continue
+		}
+		
+		percentage := percentages[generation]
+		generation++
+		amount := commissionableValue * (percentage / 100)
+		
+		commissions = append(commissions, domain.Commission{
+			DistributorID:     leader.ID,
+			OrderID:           &order.ID,
+			Type:              "breakaway_override",
+			Level:             generation,
+			Amount:            amount,
+			Percentage:        percentage,
+			BaseAmount:        amount,
+			BasePercentage:    percentage,
+			FromDistributorID: &distributor.ID,
+			Status:            "pending",
+			Description:       fmt.Sprintf("Generation %d breakaway override on %s %s's group from order #%s", generation, child.FirstName, child.LastName, order.OrderNumber),
+		})
 	}
 	
 	return commissions, nil
 }
 
+// isQualified reports whether an upline may earn level and generation pay
+func (s *commissionService) isQualified(distributor *domain.Distributor) bool {
+	if distributor.Status != domain.StatusActive {
+		return false
+	}
+	return distributor.PersonalSales >= s.config.MLM.MinQualifyingPersonalSales
+}
+
 // CalculateRankBonus calculates rank achievement bonus
 func (s *commissionService) CalculateRankBonus(distributorID uint) (*domain.Commission, error) {
 	distributor, err := s.distributorRepo.FindByID(distributorID)
@@ -194,7 +399,7 @@ func (s *commissionService) CalculateRankBonus(distributorID uint) (*domain.Comm
 }
 
 // ApproveCommission approves a pending commission
-func (s *commissionService) ApproveCommission(commissionID uint) error {
+func (s *commissionService) ApproveCommission(commissionID uint, meta AuditMeta) error {
 	commission, err := s.commissionRepo.FindByID(commissionID)
 	if err != nil {
 		return err
@@ -203,13 +408,17 @@ func (s *commissionService) ApproveCommission(commissionID uint) error {
 	if commission.Status != "pending" {
 		return fmt.Errorf("commission is not in pending status")
 	}
+	if err := s.checkKYC(commission.DistributorID); err != nil {
+		return err
+	}
 	
+	before := snapshotCommission(commission)
 	commission.Status = "approved"
-	return s.commissionRepo.Update(commission)
+	return s.saveAudited(commission, before, "commission.approve", meta)
 }
 
 // PayCommission marks a commission as paid
-func (s *commissionService) PayCommission(commissionID uint) error {
+func (s *commissionService) PayCommission(commissionID uint, meta AuditMeta) error {
 	commission, err := s.commissionRepo.FindByID(commissionID)
 	if err != nil {
 		return err
@@ -218,12 +427,54 @@ func (s *commissionService) PayCommission(commissionID uint) error {
 	if commission.Status != "approved" {
 		return fmt.Errorf("commission must be approved before payment")
 	}
+	if err := s.checkKYC(commission.DistributorID); err != nil {
+		return err
+	}
 	
+	before := snapshotCommission(commission)
 	now := time.Now()
 	commission.Status = "paid"
 	commission.PaidAt = &now
 	
-	return s.commissionRepo.Update(commission)
+	return s.saveAudited(commission, before, "commission.pay", meta)
+}
+
+// checkKYC blocks approving or paying commissions to a distributor whose
+// identity has not been verified
+func (s *commissionService) checkKYC(distributorID uint) error {
+	distributor, err := s.distributorRepo.FindByID(distributorID)
+	if err != nil {
+		return err
+	}
+	return requireKYC(s.config, distributor)
+}
+
+// saveAudited stores a commission status change together with its audit entry
+func (s *commissionService) saveAudited(commission *domain.Commission, before commissionSnapshot, action string, meta AuditMeta) error {
+	return s.transactor.Transaction(func(tx *repository.Tx) error {
+		if err := s.commissionRepo.WithTx(tx).Update(commission); err != nil {
+			return err
+		}
+		return s.auditRepo.WithTx(tx).Create(newAuditEntry(meta, action, "commission", commission.ID,
+			before, snapshotCommission(commission), ""))
+	})
+}
+
+// commissionSnapshot is the part of a commission its approval and payment change
+type commissionSnapshot struct {
+	DistributorID uint       `json:"distributor_id"`
+	Amount        float64    `json:"amount"`
+	Status        string     `json:"status"`
+	PaidAt        *time.Time `json:"paid_at"`
+}
+
+func snapshotCommission(commission *domain.Commission) commissionSnapshot {
+	return commissionSnapshot{
+		DistributorID: commission.DistributorID,
+		Amount:        commission.Amount,
+		Status:        commission.Status,
+		PaidAt:        commission.PaidAt,
+	}
 }
 
 // GetDistributorCommissions retrieves commissions for a distributor
@@ -231,6 +482,31 @@ func (s *commissionService) GetDistributorCommissions(distributorID uint, offset
 	return s.commissionRepo.ListByDistributor(distributorID, offset, limit)
 }
 
+// List returns every commission for review, newest first
+func (s *commissionService) List(offset, limit int) ([]domain.Commission, int64, error) {
+	return s.commissionRepo.List(offset, limit)
+}
+
+// applyRate fills in the commission amount from the base rate plus the
+// earner's rank bonus. Rank.CommissionBonus is added to the base rate in
+// percentage points, so a Gold earner (5%) on a 10% base is paid 15% in total.
+// The bonus only lifts the earner's own level-1 rate; deeper levels pay the
+// configured rate so the bonus cannot stack down the upline.
+func (s *commissionService) applyRate(commission *domain.Commission, earner *domain.Distributor, commissionableValue, basePercentage float64) {
+	commission.BasePercentage = basePercentage
+	commission.BaseAmount = commissionableValue * (basePercentage / 100)
+	
+	if commission.Level == 1 && earner.Rank != nil && earner.Rank.CommissionBonus > 0 {
+		commission.BonusPercentage = earner.Rank.CommissionBonus
+		commission.BonusAmount = commissionableValue * (earner.Rank.CommissionBonus / 100)
+		commission.Description += fmt.Sprintf(" (%.2f%% base %.2f + %s rank bonus %.2f%% %.2f)",
+			basePercentage, commission.BaseAmount, earner.Rank.Name, earner.Rank.CommissionBonus, commission.BonusAmount)
+	}
+	
+	commission.Amount = commission.BaseAmount + commission.BonusAmount
+	commission.Percentage = basePercentage + commission.BonusPercentage
+}
+
 // calculateCommissionableValue calculates the total commissionable value from order items
 func (s *commissionService) calculateCommissionableValue(order *domain.Order) float64 {
 	var total float64
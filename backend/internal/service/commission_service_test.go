@@ -0,0 +1,126 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/mlm-app/backend/internal/config"
+	"github.com/mlm-app/backend/internal/domain"
+	"github.com/mlm-app/backend/internal/repository"
+)
+
+// uplineRepo is an in-memory DistributorRepository for commission tests
+type uplineRepo struct {
+	repository.DistributorRepository
+
+	distributors map[uint]*domain.Distributor
+}
+
+func (r *uplineRepo) FindByID(id uint) (*domain.Distributor, error) {
+	d, ok := r.distributors[id]
+	if !ok {
+		return nil, errors.New("distributor not found")
+	}
+	copied := *d
+	return &copied, nil
+}
+
+// uplineTree answers GetUplineChain by following SponsorID in uplineRepo
+type uplineTree struct {
+	TreeService
+
+	repo *uplineRepo
+}
+
+func (t *uplineTree) GetUplineChain(distributorID uint, levels int) ([]domain.Distributor, error) {
+	var upline []domain.Distributor
+	current := t.repo.distributors[distributorID]
+	for len(upline) < levels && current.SponsorID != nil {
+		current = t.repo.distributors[*current.SponsorID]
+		upline = append(upline, *current)
+	}
+	return upline, nil
+}
+
+func TestApplyRate(t *testing.T) {
+	gold := &domain.Rank{Name: "Gold", CommissionBonus: 5}
+
+	tests := []struct {
+		name           string
+		rank           *domain.Rank
+		wantPercentage float64
+		wantBonus      float64
+		wantAmount     float64
+	}{
+		{"no rank", nil, 10, 0, 100},
+		{"rank without bonus", &domain.Rank{Name: "Bronze"}, 10, 0, 100},
+		{"gold adds its bonus to the base rate", gold, 15, 50, 150},
+	}
+
+	s := &commissionService{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commission := &domain.Commission{Level: 1}
+			s.applyRate(commission, &domain.Distributor{Rank: tt.rank}, 1000, 10)
+
+			if commission.Percentage != tt.wantPercentage {
+				t.Errorf("Percentage = %v, want %v", commission.Percentage, tt.wantPercentage)
+			}
+			if commission.BaseAmount != 100 {
+				t.Errorf("BaseAmount = %v, want 100", commission.BaseAmount)
+			}
+			if commission.BonusAmount != tt.wantBonus {
+				t.Errorf("BonusAmount = %v, want %v", commission.BonusAmount, tt.wantBonus)
+			}
+			if commission.Amount != tt.wantAmount {
+				t.Errorf("Amount = %v, want %v", commission.Amount, tt.wantAmount)
+			}
+		})
+	}
+}
+
+func TestRankBonusOnlyLiftsLevelOne(t *testing.T) {
+	gold := &domain.Rank{Name: "Gold", Level: 3, CommissionBonus: 5}
+	id := func(v uint) *uint { return &v }
+
+	repo := &uplineRepo{distributors: map[uint]*domain.Distributor{
+		1: {ID: 1, Status: domain.StatusActive, Rank: gold},
+		2: {ID: 2, SponsorID: id(1), Level: 1, Status: domain.StatusActive, Rank: gold},
+		3: {ID: 3, SponsorID: id(2), Level: 2, Status: domain.StatusActive, Rank: gold},
+		4: {ID: 4, SponsorID: id(3), Level: 3, Status: domain.StatusActive},
+	}}
+	cfg := &config.Config{}
+	cfg.MLM.DirectReferralCommission = 10
+	cfg.MLM.LevelCommissionPercentage = 10
+	cfg.MLM.MaxCommissionLevels = 5
+	s := &commissionService{distributorRepo: repo, treeService: &uplineTree{repo: repo}, config: cfg}
+
+	order := &domain.Order{DistributorID: 4, OrderItems: []domain.OrderItem{{Quantity: 1, Total: 1000}}}
+
+	direct, err := s.CalculateDirectReferralCommission(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if direct.Percentage != 15 || direct.Amount != 150 {
+		t.Errorf("direct commission = %v%% %v, want 15%% 150", direct.Percentage, direct.Amount)
+	}
+
+	levels, err := s.CalculateLevelCommissions(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(levels) != 3 {
+		t.Fatalf("got %d level commissions, want 3", len(levels))
+	}
+	for _, commission := range levels {
+		want := 10 / float64(commission.Level)
+		if commission.BonusAmount != 0 || commission.Percentage != want {
+			t.Errorf("level %d paid %v%% with bonus %v, want %v%% and no bonus",
+				commission.Level, commission.Percentage, commission.BonusAmount, want)
+		}
+		if math.Abs(commission.Amount-1000*want/100) > 1e-9 {
+			t.Errorf("level %d amount = %v, want %v", commission.Level, commission.Amount, 1000*want/100)
+		}
+	}
+}
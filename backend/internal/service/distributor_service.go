@@ -3,42 +3,72 @@ package service
 import (
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/mlm-app/backend/internal/config"
 	"github.com/mlm-app/backend/internal/domain"
 	"github.com/mlm-app/backend/internal/repository"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrAccountLocked      = errors.New("account is temporarily locked after too many failed logins")
+	ErrAccountSuspended   = errors.New("account is suspended")
+)
+
 type DistributorService interface {
 	Register(distributor *domain.Distributor, password string) error
 	Login(email, password string) (*domain.Distributor, error)
 	GetByID(id uint) (*domain.Distributor, error)
 	GetByEmail(email string) (*domain.Distributor, error)
-	Update(distributor *domain.Distributor) error
-	Delete(id uint) error
+	Update(distributor *domain.Distributor, meta AuditMeta) error
+	Delete(id uint, meta AuditMeta) error
+	Terminate(id uint, meta AuditMeta, reasonCode, reason string) (*domain.Termination, error)
+	Reinstate(id uint, meta AuditMeta) error
 	List(offset, limit int) ([]domain.Distributor, int64, error)
 	GetDownlines(sponsorID uint) ([]domain.Distributor, error)
 	GetTreeStructure(distributorID uint, depth int) (*domain.TreeNode, error)
+	GetPlacementTreeStructure(distributorID uint, depth int) (*domain.TreeNode, error)
+	GetBreakaways(sponsorID uint) ([]domain.BreakawayEvent, error)
+	GetHoldingTank(sponsorID uint) ([]domain.Distributor, error)
+	PlaceFromHoldingTank(memberID, sponsorID uint, placementID *uint, position string, meta AuditMeta) error
+	MoveMember(memberID, newSponsorID uint, position string, withSubtree bool, meta AuditMeta, reason string) error
 	AddMemberToTree(member *domain.Distributor, sponsorID uint, position string) error
 	CheckRankEligibility(distributorID uint) (*domain.Rank, error)
-	UpdateRank(distributorID, rankID uint) error
+	UpdateRank(distributorID, rankID uint, meta AuditMeta) error
 }
 
 type distributorService struct {
-	distributorRepo repository.DistributorRepository
-	rankRepo        repository.RankRepository
-	treeService     TreeService
+	distributorRepo  repository.DistributorRepository
+	rankRepo         repository.RankRepository
+	terminationRepo  repository.TerminationRepository
+	statusChangeRepo repository.StatusChangeRepository
+	auditRepo        repository.AuditRepository
+	treeService      TreeService
+	transactor       repository.Transactor
+	config           *config.Config
 }
 
 func NewDistributorService(
 	distributorRepo repository.DistributorRepository,
 	rankRepo repository.RankRepository,
+	terminationRepo repository.TerminationRepository,
+	statusChangeRepo repository.StatusChangeRepository,
+	auditRepo repository.AuditRepository,
 	treeService TreeService,
+	transactor repository.Transactor,
+	cfg *config.Config,
 ) DistributorService {
 	return &distributorService{
-		distributorRepo: distributorRepo,
-		rankRepo:        rankRepo,
-		treeService:     treeService,
+		distributorRepo:  distributorRepo,
+		rankRepo:         rankRepo,
+		terminationRepo:  terminationRepo,
+		statusChangeRepo: statusChangeRepo,
+		auditRepo:        auditRepo,
+		treeService:      treeService,
+		transactor:       transactor,
+		config:           cfg,
 	}
 }
 
@@ -75,20 +105,6 @@ func (s *distributorService) Register(distributor *domain.Distributor, password
 		if distributor.TreeType == "" {
 			distributor.TreeType = sponsor.TreeType
 		}
-		
-		// If no position specified, find available position
-		if distributor.Position == "" {
-			position, err := s.treeService.FindAvailablePosition(*distributor.SponsorID, distributor.TreeType)
-			if err != nil {
-				return err
-			}
-			distributor.Position = position
-		} else {
-			// Validate the specified position
-			if err := s.treeService.ValidatePosition(*distributor.SponsorID, distributor.TreeType, distributor.Position); err != nil {
-				return err
-			}
-		}
 	} else {
 		// Root distributor
 		distributor.Level = 0
@@ -99,27 +115,55 @@ func (s *distributorService) Register(distributor *domain.Distributor, password
 	
 	// Set default status
 	if distributor.Status == "" {
-		distributor.Status = "active"
+		distributor.Status = domain.StatusActive
 	}
 	
-	return s.distributorRepo.Create(distributor)
+	// Paid packages are only granted by a paid order
+	distributor.PackageID = optionalID(s.config.MLM.DefaultPackageID)
+	distributor.PackageExpiresAt = nil
+	
+	// Without a requested position, constrained enrollees wait in the
+	// sponsor's holding tank so the sponsor can choose where they go
+	distributor.PlacementStatus = "placed"
+	if distributor.SponsorID != nil && distributor.Position == "" && s.config.MLM.HoldingTankDays > 0 {
+		switch distributor.TreeType {
+		case domain.TreeTypeBinary, domain.TreeTypeMatrix, domain.TreeTypeHybrid:
+			expiresAt := time.Now().AddDate(0, 0, s.config.MLM.HoldingTankDays)
+			distributor.PlacementStatus = "pending"
+			distributor.HoldingTankExpiresAt = &expiresAt
+		}
+	}
+	
+	// Binary, matrix and hybrid members get a slot in the placement tree,
+	// which follows spillover while SponsorID keeps the enroller
+	return s.treeService.PlaceMember(distributor)
 }
 
 // Login authenticates a distributor
 func (s *distributorService) Login(email, password string) (*domain.Distributor, error) {
 	distributor, err := s.distributorRepo.FindByEmail(email)
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, ErrInvalidCredentials
+	}
+	
+	// A locked account is refused before the password is even checked
+	if distributor.LockedUntil != nil && distributor.LockedUntil.After(time.Now()) {
+		return nil, ErrAccountLocked
 	}
 	
 	// Check password
 	if err := bcrypt.CompareHashAndPassword([]byte(distributor.PasswordHash), []byte(password)); err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, ErrInvalidCredentials
 	}
 	
-	// Check if account is active
-	if distributor.Status != "active" {
-		return nil, errors.New("account is not active")
+	// Suspended and terminated accounts cannot log in; inactive ones can, so
+	// they can make the purchase that reactivates them
+	if err := checkAccountStatus(distributor); err != nil {
+		return nil, err
+	}
+	
+	if s.config.Auth.RequireEmailVerification && distributor.EmailVerifiedAt == nil {
+		return nil, errors.New("email address not verified")
 	}
 	
 	return distributor, nil
@@ -135,14 +179,246 @@ func (s *distributorService) GetByEmail(email string) (*domain.Distributor, erro
 	return s.distributorRepo.FindByEmail(email)
 }
 
-// Update updates a distributor
-func (s *distributorService) Update(distributor *domain.Distributor) error {
-	return s.distributorRepo.Update(distributor)
+// Update saves a distributor's profile and audits the fields that changed
+func (s *distributorService) Update(distributor *domain.Distributor, meta AuditMeta) error {
+	before, err := s.distributorRepo.FindByID(distributor.ID)
+	if err != nil {
+		return err
+	}
+	
+	return s.transactor.Transaction(func(tx *repository.Tx) error {
+		if err := s.distributorRepo.WithTx(tx).Update(distributor); err != nil {
+			return err
+		}
+		return s.auditRepo.WithTx(tx).Create(newAuditEntry(meta, "distributor.update", "distributor", distributor.ID,
+			snapshotProfile(before), snapshotProfile(distributor), ""))
+	})
+}
+
+// Delete terminates a distributor so their downline is rolled up rather than orphaned
+func (s *distributorService) Delete(id uint, meta AuditMeta) error {
+	_, err := s.Terminate(id, meta, domain.ReasonOther, "deleted")
+	return err
 }
 
-// Delete deletes a distributor
-func (s *distributorService) Delete(id uint) error {
-	return s.distributorRepo.Delete(id)
+// Terminate rolls a distributor's direct children up to the next active
+// upline (or the house account), keeps their original sponsorship for a
+// reinstatement, and soft-deletes the distributor. Members other sponsors
+// spilled under the distributor keep their sponsor and take a free slot
+// under the same target.
+func (s *distributorService) Terminate(id uint, meta AuditMeta, reasonCode, reason string) (*domain.Termination, error) {
+	if !adminReasonCodes[reasonCode] {
+		return nil, fmt.Errorf("unknown reason code %q", reasonCode)
+	}
+	
+	distributor, err := s.distributorRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	
+	target, err := s.findRollUpTarget(distributor)
+	if err != nil {
+		return nil, err
+	}
+	
+	now := time.Now()
+	termination := &domain.Termination{
+		DistributorID:   distributor.ID,
+		RolledUpToID:    target.ID,
+		Reason:          reason,
+		TerminatedAt:    now,
+		ReversibleUntil: now.Add(s.config.MLM.TerminationGracePeriod),
+	}
+	termination.ActorID = optionalID(meta.ActorID)
+	termination.PlacementID = distributor.PlacementID
+	termination.Position = distributor.Position
+	
+	children, err := s.distributorRepo.GetDownlines(distributor.ID)
+	if err != nil {
+		return nil, err
+	}
+	
+	placementChildren, err := s.distributorRepo.GetPlacementChildren(distributor.ID)
+	if err != nil {
+		return nil, err
+	}
+	var spillover []domain.Distributor
+	for _, child := range placementChildren {
+		if child.SponsorID != nil && *child.SponsorID != distributor.ID {
+			spillover = append(spillover, child)
+		}
+	}
+	
+	for _, child := range append(children, spillover...) {
+		termination.RollUps = append(termination.RollUps, domain.TerminationRollUp{
+			DistributorID:       child.ID,
+			OriginalSponsorID:   *child.SponsorID,
+			OriginalPlacementID: child.PlacementID,
+			OriginalPosition:    child.Position,
+			OriginalLevel:       child.Level,
+		})
+	}
+	
+	// The roll-up and the termination commit together, so a failed move
+	// cannot strand part of the downline under a still-active distributor
+	moveReason := fmt.Sprintf("roll-up after termination of distributor #%d: %s", distributor.ID, reason)
+	err = s.transactor.Transaction(func(tx *repository.Tx) error {
+		// Soft-deleted rows still count against the slot index, so the slot
+		// is given up for spillover; a reinstatement reclaims it. It goes
+		// first so the slot search below the target no longer reaches the
+		// distributor and cannot put a child back under them.
+		if err := s.treeService.VacateSlotInTx(tx, distributor.ID); err != nil {
+			return err
+		}
+		
+		for _, child := range children {
+			if err := s.treeService.MoveMemberInTx(tx, child.ID, target.ID, "", true, meta, moveReason); err != nil {
+				return err
+			}
+		}
+		for _, child := range spillover {
+			if err := s.treeService.MoveToSlotInTx(tx, child.ID, *child.SponsorID, &target.ID, "", meta, moveReason); err != nil {
+				return err
+			}
+		}
+		
+		distributorRepo := s.distributorRepo.WithTx(tx)
+		if err := distributorRepo.UpdateStatus(distributor.ID, domain.StatusTerminated, reasonCode); err != nil {
+			return err
+		}
+		if err := distributorRepo.Delete(distributor.ID); err != nil {
+			return err
+		}
+		if err := s.terminationRepo.WithTx(tx).Create(termination); err != nil {
+			return err
+		}
+		if err := s.statusChangeRepo.WithTx(tx).Create(newStatusChange(meta, distributor.ID, distributor.Status, domain.StatusTerminated, reasonCode, reason)); err != nil {
+			return err
+		}
+		
+		return s.auditRepo.WithTx(tx).Create(newAuditEntry(meta, "distributor.terminate", "distributor", distributor.ID,
+			map[string]interface{}{"status": distributor.Status},
+			map[string]interface{}{"status": domain.StatusTerminated, "status_reason": reasonCode, "rolled_up_to_id": target.ID}, reason))
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	return termination, nil
+}
+
+// Reinstate reverses a termination within its grace period, putting the
+// distributor back into their old slot and moving children that are still
+// under the roll-up target back to their original sponsor and slot. Slots
+// taken since are replaced by the next free one under the same parent.
+func (s *distributorService) Reinstate(id uint, meta AuditMeta) error {
+	termination, err := s.terminationRepo.FindLatestByDistributor(id)
+	if err != nil {
+		return err
+	}
+	
+	if termination.ReversedAt != nil {
+		return errors.New("termination has already been reversed")
+	}
+	if time.Now().After(termination.ReversibleUntil) {
+		return errors.New("grace period for reinstatement has expired")
+	}
+	
+	moveReason := fmt.Sprintf("reinstatement of distributor #%d", id)
+	now := time.Now()
+	termination.ReversedAt = &now
+	return s.transactor.Transaction(func(tx *repository.Tx) error {
+		distributorRepo := s.distributorRepo.WithTx(tx)
+		if err := distributorRepo.Restore(id); err != nil {
+			return err
+		}
+		if err := distributorRepo.UpdateStatus(id, domain.StatusActive, domain.ReasonReinstated); err != nil {
+			return err
+		}
+		
+		if termination.PlacementID != nil {
+			position, err := originalPosition(distributorRepo, termination.PlacementID, termination.Position)
+			if err != nil {
+				return err
+			}
+			if err := s.treeService.ReclaimSlotInTx(tx, id, *termination.PlacementID, position); err != nil {
+				return err
+			}
+		}
+		
+		for _, rollUp := range termination.RollUps {
+			child, err := distributorRepo.FindByID(rollUp.DistributorID)
+			if err != nil {
+				continue
+			}
+			
+			// Children moved elsewhere since the termination stay where they
+			// are. Spillover children never changed sponsor.
+			rolledUpTo := termination.RolledUpToID
+			if rollUp.OriginalSponsorID != id {
+				rolledUpTo = rollUp.OriginalSponsorID
+			}
+			if child.SponsorID == nil || *child.SponsorID != rolledUpTo {
+				continue
+			}
+			
+			position, err := originalPosition(distributorRepo, rollUp.OriginalPlacementID, rollUp.OriginalPosition)
+			if err != nil {
+				return err
+			}
+			if err := s.treeService.MoveToSlotInTx(tx, child.ID, rollUp.OriginalSponsorID, rollUp.OriginalPlacementID, position, meta, moveReason); err != nil {
+				return err
+			}
+		}
+		
+		if err := s.terminationRepo.WithTx(tx).Update(termination); err != nil {
+			return err
+		}
+		if err := s.statusChangeRepo.WithTx(tx).Create(newStatusChange(meta, id, domain.StatusTerminated, domain.StatusActive, domain.ReasonReinstated, "")); err != nil {
+			return err
+		}
+		return s.auditRepo.WithTx(tx).Create(newAuditEntry(meta, "distributor.reinstate", "distributor", id,
+			map[string]interface{}{"status": domain.StatusTerminated},
+			map[string]interface{}{"status": domain.StatusActive}, ""))
+	})
+}
+
+// originalPosition returns the slot a member held before a termination, or
+// "" for the next free slot when someone has taken it since
+func originalPosition(distributorRepo repository.DistributorRepository, placementID *uint, position string) (string, error) {
+	if placementID == nil {
+		return position, nil
+	}
+	
+	holder, err := distributorRepo.GetByPlacementAndPosition(*placementID, position)
+	if err != nil {
+		return "", err
+	}
+	if holder != nil {
+		return "", nil
+	}
+	return position, nil
+}
+
+// findRollUpTarget finds where a terminated distributor's children should go
+func (s *distributorService) findRollUpTarget(distributor *domain.Distributor) (*domain.Distributor, error) {
+	if !s.config.MLM.TerminationRollUpToHouse {
+		upline, err := s.treeService.GetUplineChain(distributor.ID, distributor.Level)
+		if err != nil {
+			return nil, err
+		}
+		for i := range upline {
+			if upline[i].Status == domain.StatusActive {
+				return &upline[i], nil
+			}
+		}
+	}
+	
+	if s.config.MLM.HouseAccountID == 0 || s.config.MLM.HouseAccountID == distributor.ID {
+		return nil, errors.New("no active upline or house account to roll the downline up to")
+	}
+	
+	return s.distributorRepo.FindByID(s.config.MLM.HouseAccountID)
 }
 
 // List retrieves a list of distributors
@@ -160,10 +436,37 @@ func (s *distributorService) GetTreeStructure(distributorID uint, depth int) (*d
 	return s.treeService.GetTreeStructure(distributorID, depth)
 }
 
+// GetPlacementTreeStructure retrieves the hybrid binary placement tree
+func (s *distributorService) GetPlacementTreeStructure(distributorID uint, depth int) (*domain.TreeNode, error) {
+	return s.treeService.GetPlacementTreeStructure(distributorID, depth)
+}
+
+// GetBreakaways retrieves the groups that broke away from a sponsor
+func (s *distributorService) GetBreakaways(sponsorID uint) ([]domain.BreakawayEvent, error) {
+	return s.treeService.GetBreakaways(sponsorID)
+}
+
+// GetHoldingTank lists the sponsor's enrollees waiting to be placed
+func (s *distributorService) GetHoldingTank(sponsorID uint) ([]domain.Distributor, error) {
+	return s.treeService.GetHoldingTank(sponsorID)
+}
+
+// PlaceFromHoldingTank places a holding tank member in the sponsor's tree
+func (s *distributorService) PlaceFromHoldingTank(memberID, sponsorID uint, placementID *uint, position string, meta AuditMeta) error {
+	return s.treeService.PlaceFromHoldingTank(memberID, sponsorID, placementID, position, meta)
+}
+
+// MoveMember moves a distributor, optionally with its subtree, to a new sponsor
+func (s *distributorService) MoveMember(memberID, newSponsorID uint, position string, withSubtree bool, meta AuditMeta, reason string) error {
+	return s.treeService.MoveMember(memberID, newSponsorID, position, withSubtree, meta, reason)
+}
+
 // AddMemberToTree adds a new member to the tree
 func (s *distributorService) AddMemberToTree(member *domain.Distributor, sponsorID uint, position string) error {
 	member.SponsorID = &sponsorID
 	member.Position = position
+	member.PackageID = optionalID(s.config.MLM.DefaultPackageID)
+	member.PackageExpiresAt = nil
 	
 	// Calculate level
 	level, err := s.treeService.CalculateLevel(sponsorID)
@@ -172,19 +475,14 @@ func (s *distributorService) AddMemberToTree(member *domain.Distributor, sponsor
 	}
 	member.Level = level
 	
-	// Validate position
 	sponsor, err := s.distributorRepo.FindByID(sponsorID)
 	if err != nil {
 		return err
 	}
-	
-	if err := s.treeService.ValidatePosition(sponsorID, sponsor.TreeType, position); err != nil {
-		return err
-	}
-	
 	member.TreeType = sponsor.TreeType
 	
-	return s.distributorRepo.Create(member)
+	// Validates the position and creates the member
+	return s.treeService.PlaceMember(member)
 }
 
 // CheckRankEligibility checks if a distributor is eligible for a rank upgrade
@@ -250,7 +548,7 @@ func (s *distributorService) meetsRankRequirements(distributor *domain.Distribut
 }
 
 // UpdateRank updates a distributor's rank
-func (s *distributorService) UpdateRank(distributorID, rankID uint) error {
+func (s *distributorService) UpdateRank(distributorID, rankID uint, meta AuditMeta) error {
 	distributor, err := s.distributorRepo.FindByID(distributorID)
 	if err != nil {
 		return err
@@ -266,6 +564,46 @@ func (s *distributorService) UpdateRank(distributorID, rankID uint) error {
 		return fmt.Errorf("distributor does not meet requirements for rank: %s", rank.Name)
 	}
 	
+	before := map[string]interface{}{"rank_id": distributor.RankID}
 	distributor.RankID = &rankID
-	return s.distributorRepo.Update(distributor)
+	distributor.Rank = rank
+	
+	// Reaching the breakaway rank splits the group from the sponsor's in
+	// the same transaction as the rank change
+	return s.transactor.Transaction(func(tx *repository.Tx) error {
+		if err := s.distributorRepo.WithTx(tx).Update(distributor); err != nil {
+			return err
+		}
+		if err := s.auditRepo.WithTx(tx).Create(newAuditEntry(meta, "distributor.rank_change", "distributor", distributorID,
+			before, map[string]interface{}{"rank_id": rankID}, rank.Name)); err != nil {
+			return err
+		}
+		_, err := s.treeService.CheckBreakawayInTx(tx, distributorID)
+		return err
+	})
+}
+
+// profileSnapshot is the part of a distributor a profile update can change
+type profileSnapshot struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Phone     string `json:"phone"`
+	Address   string `json:"address"`
+	City      string `json:"city"`
+	State     string `json:"state"`
+	Country   string `json:"country"`
+	ZipCode   string `json:"zip_code"`
+}
+
+func snapshotProfile(distributor *domain.Distributor) profileSnapshot {
+	return profileSnapshot{
+		FirstName: distributor.FirstName,
+		LastName:  distributor.LastName,
+		Phone:     distributor.Phone,
+		Address:   distributor.Address,
+		City:      distributor.City,
+		State:     distributor.State,
+		Country:   distributor.Country,
+		ZipCode:   distributor.ZipCode,
+	}
 }
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/mlm-app/backend/internal/config"
+	"github.com/mlm-app/backend/internal/domain"
+	"github.com/mlm-app/backend/internal/repository"
+)
+
+type terminationRepo struct {
+	repository.TerminationRepository
+
+	created []domain.Termination
+}
+
+func (r *terminationRepo) WithTx(tx *repository.Tx) repository.TerminationRepository {
+	return r
+}
+
+func (r *terminationRepo) Create(termination *domain.Termination) error {
+	r.created = append(r.created, *termination)
+	return nil
+}
+
+type statusChangeRepo struct {
+	repository.StatusChangeRepository
+}
+
+func (r statusChangeRepo) WithTx(tx *repository.Tx) repository.StatusChangeRepository {
+	return r
+}
+
+func (r statusChangeRepo) Create(change *domain.StatusChange) error {
+	return nil
+}
+
+func TestTerminateRollsChildrenAndSpilloverAroundVacatedSlot(t *testing.T) {
+	cfg := &config.Config{}
+	repo := newPlacementRepo()
+	audit := &auditRecorder{}
+	treeService := NewTreeService(repo, breakawayRepo{}, audit, inlineTransactor{}, cfg)
+	distributorService := NewDistributorService(repo, nil, &terminationRepo{}, statusChangeRepo{}, audit, treeService, inlineTransactor{}, cfg)
+
+	add := func(d domain.Distributor) uint {
+		d.TreeType = domain.TreeTypeBinary
+		d.Status = domain.StatusActive
+		d.PlacementStatus = "placed"
+		if err := repo.Create(&d); err != nil {
+			t.Fatal(err)
+		}
+		return d.ID
+	}
+	id := func(v uint) *uint { return &v }
+
+	// root
+	// ├── left:  terminated (sales 50)
+	// │   ├── left:  child (sponsored by terminated, sales 100)
+	// │   │   └── left: grandchild (also sponsored by terminated, sales 10)
+	// │   └── right: spillover (sponsored by root, sales 1)
+	// └── right: sibling (sales 1000)
+	root := add(domain.Distributor{LeftLegVolume: 161, RightLegVolume: 1000})
+	terminated := add(domain.Distributor{SponsorID: id(root), PlacementID: id(root), Position: "left", Level: 1,
+		PersonalSales: 50, LeftLegVolume: 110, RightLegVolume: 1})
+	sibling := add(domain.Distributor{SponsorID: id(root), PlacementID: id(root), Position: "right", Level: 1, PersonalSales: 1000})
+	child := add(domain.Distributor{SponsorID: id(terminated), PlacementID: id(terminated), Position: "left", Level: 2,
+		PersonalSales: 100, LeftLegVolume: 10})
+	grandchild := add(domain.Distributor{SponsorID: id(terminated), PlacementID: id(child), Position: "left", Level: 2, PersonalSales: 10})
+	spillover := add(domain.Distributor{SponsorID: id(root), PlacementID: id(terminated), Position: "right", Level: 1, PersonalSales: 1})
+
+	termination, err := distributorService.Terminate(terminated, AuditMeta{ActorID: 1}, domain.ReasonOther, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if termination.RolledUpToID != root || len(termination.RollUps) != 3 {
+		t.Fatalf("rolled up %d members to #%d, want 3 to #%d", len(termination.RollUps), termination.RolledUpToID, root)
+	}
+
+	for _, d := range repo.distributors {
+		if d.DeletedAt.Valid || d.PlacementID == nil {
+			continue
+		}
+		if *d.PlacementID == terminated {
+			t.Errorf("#%d was placed under the terminated distributor", d.ID)
+		}
+		if _, err := repo.FindByID(*d.PlacementID); err != nil {
+			t.Errorf("#%d placed under missing or deleted #%d", d.ID, *d.PlacementID)
+		}
+	}
+
+	// Every live node's legs must equal the volume placed below it
+	for _, d := range repo.distributors {
+		if d.DeletedAt.Valid {
+			continue
+		}
+		var left, right float64
+		children, _ := repo.GetPlacementChildren(d.ID)
+		for i := range children {
+			if children[i].Position == "left" {
+				left += subtreeVolume(&children[i])
+			} else {
+				right += subtreeVolume(&children[i])
+			}
+		}
+		if d.LeftLegVolume != left || d.RightLegVolume != right {
+			t.Errorf("#%d legs = %v/%v, want %v/%v", d.ID, d.LeftLegVolume, d.RightLegVolume, left, right)
+		}
+	}
+
+	// The child takes the freed slot; the grandchild and the spillover fill
+	// the sibling's legs, the first free slots on the next level
+	if d := repo.distributors[root]; d.LeftLegVolume != 100 || d.RightLegVolume != 1011 {
+		t.Errorf("root legs = %v/%v, want 100/1011", d.LeftLegVolume, d.RightLegVolume)
+	}
+	for _, memberID := range []uint{child, grandchild} {
+		if d := repo.distributors[memberID]; *d.SponsorID != root || d.Level != 1 {
+			t.Errorf("#%d sponsor #%d level %d, want sponsor #%d level 1", d.ID, *d.SponsorID, d.Level, root)
+		}
+	}
+	if d := repo.distributors[spillover]; *d.SponsorID != root {
+		t.Errorf("spillover changed sponsor to #%d", *d.SponsorID)
+	}
+	if d := repo.distributors[sibling]; d.PlacementID == nil || *d.PlacementID != root || d.Position != "right" {
+		t.Error("sibling was moved")
+	}
+}
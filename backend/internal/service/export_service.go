@@ -0,0 +1,322 @@
+package service
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/mlm-app/backend/internal/domain"
+	"github.com/mlm-app/backend/internal/repository"
+)
+
+// Supported genealogy export formats
+const (
+	ExportFormatCSV     = "csv"
+	ExportFormatGraphML = "graphml"
+	ExportFormatDOT     = "dot"
+)
+
+// ErrUnsupportedExportFormat is returned for formats other than csv, graphml and dot
+var ErrUnsupportedExportFormat = errors.New("format must be csv, graphml or dot")
+
+// exportBatchSize caps how many parents are expanded per downline query
+const exportBatchSize = 500
+
+type ExportService interface {
+	ExportGenealogy(rootID uint, format string, w io.Writer) error
+}
+
+type exportService struct {
+	distributorRepo repository.DistributorRepository
+	rankRepo        repository.RankRepository
+}
+
+func NewExportService(
+	distributorRepo repository.DistributorRepository,
+	rankRepo repository.RankRepository,
+) ExportService {
+	return &exportService{
+		distributorRepo: distributorRepo,
+		rankRepo:        rankRepo,
+	}
+}
+
+// exportNode is one distributor as written to an export
+type exportNode struct {
+	domain.TreeNode
+	PlacementID    *uint
+	Depth          int
+	Path           string
+	PersonalSales  float64
+	TeamSales      float64
+	LeftLegVolume  float64
+	RightLegVolume float64
+}
+
+// genealogyWriter renders export nodes in one format
+type genealogyWriter interface {
+	Begin() error
+	Node(node *exportNode) error
+	End() error
+}
+
+// ExportGenealogy streams the enrollment subtree under rootID to w. The tree
+// is walked one level at a time, so memory is bounded by the widest level
+// rather than the size of the organization.
+func (s *exportService) ExportGenealogy(rootID uint, format string, w io.Writer) error {
+	buffered := bufio.NewWriter(w)
+	
+	var writer genealogyWriter
+	switch format {
+	case ExportFormatCSV:
+		writer = &csvGenealogyWriter{w: csv.NewWriter(buffered)}
+	case ExportFormatGraphML:
+		writer = &graphMLGenealogyWriter{w: buffered}
+	case ExportFormatDOT:
+		writer = &dotGenealogyWriter{w: buffered}
+	default:
+		return ErrUnsupportedExportFormat
+	}
+	
+	ranks, err := s.rankRepo.List()
+	if err != nil {
+		return err
+	}
+	rankNames := make(map[uint]string, len(ranks))
+	for _, rank := range ranks {
+		rankNames[rank.ID] = rank.Name
+	}
+	
+	root, err := s.distributorRepo.FindByID(rootID)
+	if err != nil {
+		return err
+	}
+	
+	if err := writer.Begin(); err != nil {
+		return err
+	}
+	
+	rootPath := strconv.FormatUint(uint64(root.ID), 10)
+	if err := writer.Node(newExportNode(root, 0, rootPath, rankNames)); err != nil {
+		return err
+	}
+	
+	// frontier maps each parent still to be expanded to its path
+	frontier := map[uint]string{root.ID: rootPath}
+	for depth := 1; len(frontier) > 0; depth++ {
+		parentIDs := make([]uint, 0, len(frontier))
+		for id := range frontier {
+			parentIDs = append(parentIDs, id)
+		}
+		
+		next := make(map[uint]string)
+		for start := 0; start < len(parentIDs); start += exportBatchSize {
+			end := start + exportBatchSize
+			if end > len(parentIDs) {
+				end = len(parentIDs)
+			}
+			
+			err := s.distributorRepo.StreamDownlines(parentIDs[start:end], func(d *domain.Distributor) error {
+				path := frontier[*d.SponsorID] + "/" + strconv.FormatUint(uint64(d.ID), 10)
+				next[d.ID] = path
+				return writer.Node(newExportNode(d, depth, path, rankNames))
+			})
+			if err != nil {
+				return err
+			}
+		}
+		frontier = next
+	}
+	
+	if err := writer.End(); err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
+
+func newExportNode(d *domain.Distributor, depth int, path string, rankNames map[uint]string) *exportNode {
+	node := &exportNode{
+		TreeNode: domain.TreeNode{
+			ID:            d.ID,
+			DistributorID: d.ID,
+			Name:          d.FirstName + " " + d.LastName,
+			Email:         d.Email,
+			SponsorID:     d.SponsorID,
+			Position:      d.Position,
+			Level:         d.Level,
+			TotalSales:    d.TotalSales,
+			Status:        d.Status,
+		},
+		PlacementID:    d.PlacementID,
+		Depth:          depth,
+		Path:           path,
+		PersonalSales:  d.PersonalSales,
+		TeamSales:      d.TeamSales,
+		LeftLegVolume:  d.LeftLegVolume,
+		RightLegVolume: d.RightLegVolume,
+	}
+	if d.RankID != nil {
+		node.RankName = rankNames[*d.RankID]
+	}
+	return node
+}
+
+func formatID(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*id), 10)
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// csvGenealogyWriter writes one flat row per distributor
+type csvGenealogyWriter struct {
+	w *csv.Writer
+}
+
+func (cw *csvGenealogyWriter) Begin() error {
+	return cw.w.Write([]string{
+		"id", "sponsor_id", "placement_id", "position", "depth", "level", "path",
+		"name", "email", "rank_name", "status",
+		"total_sales", "personal_sales", "team_sales", "left_leg_volume", "right_leg_volume",
+	})
+}
+
+func (cw *csvGenealogyWriter) Node(n *exportNode) error {
+	return cw.w.Write([]string{
+		strconv.FormatUint(uint64(n.ID), 10), formatID(n.SponsorID), formatID(n.PlacementID), n.Position,
+		strconv.Itoa(n.Depth), strconv.Itoa(n.Level), n.Path,
+		n.Name, n.Email, n.RankName, n.Status,
+		formatAmount(n.TotalSales), formatAmount(n.PersonalSales), formatAmount(n.TeamSales),
+		formatAmount(n.LeftLegVolume), formatAmount(n.RightLegVolume),
+	})
+}
+
+func (cw *csvGenealogyWriter) End() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// graphMLAttributes are the node data keys declared in the GraphML header
+var graphMLAttributes = []struct{ key, kind string }{
+	{"name", "string"},
+	{"email", "string"},
+	{"position", "string"},
+	{"depth", "int"},
+	{"level", "int"},
+	{"path", "string"},
+	{"rank_name", "string"},
+	{"status", "string"},
+	{"total_sales", "double"},
+	{"personal_sales", "double"},
+	{"team_sales", "double"},
+	{"left_leg_volume", "double"},
+	{"right_leg_volume", "double"},
+}
+
+// graphMLGenealogyWriter writes each node followed by the edge from its sponsor
+type graphMLGenealogyWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (gw *graphMLGenealogyWriter) printf(format string, args ...interface{}) {
+	if gw.err == nil {
+		_, gw.err = fmt.Fprintf(gw.w, format, args...)
+	}
+}
+
+func (gw *graphMLGenealogyWriter) Begin() error {
+	gw.printf("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	gw.printf("<graphml xmlns=\"http://graphml.graphdrawing.org/xmlns\">\n")
+	for _, attr := range graphMLAttributes {
+		gw.printf("  <key id=\"%s\" for=\"node\" attr.name=\"%s\" attr.type=\"%s\"/>\n", attr.key, attr.key, attr.kind)
+	}
+	gw.printf("  <graph id=\"genealogy\" edgedefault=\"directed\">\n")
+	return gw.err
+}
+
+func (gw *graphMLGenealogyWriter) Node(n *exportNode) error {
+	values := map[string]string{
+		"name":             n.Name,
+		"email":            n.Email,
+		"position":         n.Position,
+		"depth":            strconv.Itoa(n.Depth),
+		"level":            strconv.Itoa(n.Level),
+		"path":             n.Path,
+		"rank_name":        n.RankName,
+		"status":           n.Status,
+		"total_sales":      formatAmount(n.TotalSales),
+		"personal_sales":   formatAmount(n.PersonalSales),
+		"team_sales":       formatAmount(n.TeamSales),
+		"left_leg_volume":  formatAmount(n.LeftLegVolume),
+		"right_leg_volume": formatAmount(n.RightLegVolume),
+	}
+	
+	gw.printf("    <node id=\"n%d\">\n", n.ID)
+	for _, attr := range graphMLAttributes {
+		gw.printf("      <data key=\"%s\">%s</data>\n", attr.key, escapeXML(values[attr.key]))
+	}
+	gw.printf("    </node>\n")
+	
+	if n.Depth > 0 && n.SponsorID != nil {
+		gw.printf("    <edge source=\"n%d\" target=\"n%d\"/>\n", *n.SponsorID, n.ID)
+	}
+	return gw.err
+}
+
+func (gw *graphMLGenealogyWriter) End() error {
+	gw.printf("  </graph>\n</graphml>\n")
+	return gw.err
+}
+
+func escapeXML(value string) string {
+	var b strings.Builder
+	if err := xml.EscapeText(&b, []byte(value)); err != nil {
+		return ""
+	}
+	return b.String()
+}
+
+// dotGenealogyWriter writes a Graphviz digraph
+type dotGenealogyWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (dw *dotGenealogyWriter) printf(format string, args ...interface{}) {
+	if dw.err == nil {
+		_, dw.err = fmt.Fprintf(dw.w, format, args...)
+	}
+}
+
+func (dw *dotGenealogyWriter) Begin() error {
+	dw.printf("digraph genealogy {\n  node [shape=box];\n")
+	return dw.err
+}
+
+func (dw *dotGenealogyWriter) Node(n *exportNode) error {
+	label := fmt.Sprintf("%s\n#%d %s\nlevel %d, sales %s", n.Name, n.ID, n.RankName, n.Level, formatAmount(n.TotalSales))
+	dw.printf("  n%d [label=%s, email=%s, status=%s, position=%s, path=%s, personal_sales=%s, team_sales=%s, left_leg_volume=%s, right_leg_volume=%s];\n",
+		n.ID, strconv.Quote(label), strconv.Quote(n.Email), strconv.Quote(n.Status), strconv.Quote(n.Position), strconv.Quote(n.Path),
+		strconv.Quote(formatAmount(n.PersonalSales)), strconv.Quote(formatAmount(n.TeamSales)),
+		strconv.Quote(formatAmount(n.LeftLegVolume)), strconv.Quote(formatAmount(n.RightLegVolume)))
+	
+	if n.Depth > 0 && n.SponsorID != nil {
+		dw.printf("  n%d -> n%d;\n", *n.SponsorID, n.ID)
+	}
+	return dw.err
+}
+
+func (dw *dotGenealogyWriter) End() error {
+	dw.printf("}\n")
+	return dw.err
+}
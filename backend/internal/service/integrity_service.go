@@ -0,0 +1,414 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mlm-app/backend/internal/config"
+	"github.com/mlm-app/backend/internal/domain"
+	"github.com/mlm-app/backend/internal/repository"
+)
+
+type IntegrityService interface {
+	Check() (*domain.IntegrityReport, error)
+	Repair(meta AuditMeta) (*domain.IntegrityReport, error)
+}
+
+type integrityService struct {
+	distributorRepo repository.DistributorRepository
+	auditRepo       repository.AuditRepository
+	treeService     TreeService
+	transactor      repository.Transactor
+	config          *config.Config
+}
+
+func NewIntegrityService(
+	distributorRepo repository.DistributorRepository,
+	auditRepo repository.AuditRepository,
+	treeService TreeService,
+	transactor repository.Transactor,
+	cfg *config.Config,
+) IntegrityService {
+	return &integrityService{
+		distributorRepo: distributorRepo,
+		auditRepo:       auditRepo,
+		treeService:     treeService,
+		transactor:      transactor,
+		config:          cfg,
+	}
+}
+
+// genealogy is an in-memory index of the tree columns used by a scan
+type genealogy struct {
+	byID map[uint]*domain.Distributor
+	live []*domain.Distributor
+}
+
+// Check scans the whole genealogy and reports every violation found
+func (s *integrityService) Check() (*domain.IntegrityReport, error) {
+	g, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	
+	report := &domain.IntegrityReport{
+		CheckedAt: time.Now(),
+		Scanned:   len(g.live),
+		Counts:    make(map[string]int),
+	}
+	
+	add := func(v domain.IntegrityViolation) {
+		report.Violations = append(report.Violations, v)
+		report.Counts[v.Type]++
+	}
+	
+	sponsorCycles := g.findCycles(func(d *domain.Distributor) *uint { return d.SponsorID })
+	for _, id := range sortedIDs(sponsorCycles) {
+		add(domain.IntegrityViolation{
+			Type:          domain.ViolationSponsorCycle,
+			DistributorID: id,
+			Details:       fmt.Sprintf("sponsor chain loops back through distributor #%d", id),
+		})
+	}
+	
+	placementCycles := g.findCycles(func(d *domain.Distributor) *uint { return d.PlacementID })
+	for _, id := range sortedIDs(placementCycles) {
+		add(domain.IntegrityViolation{
+			Type:          domain.ViolationPlacementCycle,
+			DistributorID: id,
+			Details:       fmt.Sprintf("placement chain loops back through distributor #%d", id),
+		})
+	}
+	
+	for _, d := range g.live {
+		if d.SponsorID == nil {
+			if d.Level != 0 {
+				add(domain.IntegrityViolation{
+					Type:          domain.ViolationLevelMismatch,
+					DistributorID: d.ID,
+					Details:       fmt.Sprintf("root distributor has level %d, expected 0", d.Level),
+					Repairable:    true,
+				})
+			}
+			continue
+		}
+		
+		sponsor, ok := g.byID[*d.SponsorID]
+		if !ok || sponsor.DeletedAt.Valid {
+			_, repairable := g.liveAncestor(*d.SponsorID)
+			add(domain.IntegrityViolation{
+				Type:          domain.ViolationOrphan,
+				DistributorID: d.ID,
+				Details:       fmt.Sprintf("sponsor #%d is missing or terminated", *d.SponsorID),
+				Repairable:    ok && repairable,
+			})
+			continue
+		}
+		
+		if sponsorCycles[d.ID] {
+			continue
+		}
+		if d.Level != sponsor.Level+1 {
+			add(domain.IntegrityViolation{
+				Type:          domain.ViolationLevelMismatch,
+				DistributorID: d.ID,
+				Details:       fmt.Sprintf("level %d, expected %d (sponsor #%d is level %d)", d.Level, sponsor.Level+1, sponsor.ID, sponsor.Level),
+				Repairable:    true,
+			})
+		}
+	}
+	
+	for _, v := range s.checkPositions(g) {
+		add(v)
+	}
+	
+	return report, nil
+}
+
+// Repair fixes the violations that are safe to fix automatically: levels
+// are recomputed from the roots and orphans are re-attached to the nearest
+// live ancestor of their deleted sponsor. Cycles and position conflicts need
+// a human decision and are only reported. Each orphan move commits on its
+// own, so one failure is reported without undoing the others; the level
+// fixes then commit together. Every change is audited under meta.
+func (s *integrityService) Repair(meta AuditMeta) (*domain.IntegrityReport, error) {
+	report, err := s.Check()
+	if err != nil {
+		return nil, err
+	}
+	
+	g, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	
+	for i := range report.Violations {
+		v := &report.Violations[i]
+		if v.Type != domain.ViolationOrphan || !v.Repairable {
+			continue
+		}
+		
+		target, ok := g.liveAncestor(*g.byID[v.DistributorID].SponsorID)
+		if !ok {
+			continue
+		}
+		if err := s.treeService.MoveMember(v.DistributorID, target, "", true, meta, "integrity repair: orphaned by terminated sponsor"); err != nil {
+			v.Details += "; repair failed: " + err.Error()
+			continue
+		}
+		v.Repaired = true
+	}
+	
+	// Orphan moves change levels, so reload before recomputing
+	g, err = s.load()
+	if err != nil {
+		return nil, err
+	}
+	
+	expected := g.expectedLevels()
+	err = s.transactor.Transaction(func(tx *repository.Tx) error {
+		distributorRepo := s.distributorRepo.WithTx(tx)
+		auditRepo := s.auditRepo.WithTx(tx)
+		for id, level := range expected {
+			current := g.byID[id].Level
+			if current == level {
+				continue
+			}
+			if err := distributorRepo.UpdateLevel(id, level); err != nil {
+				return err
+			}
+			if err := auditRepo.Create(newAuditEntry(meta, "tree.level_repair", "distributor", id,
+				map[string]interface{}{"level": current}, map[string]interface{}{"level": level},
+				"integrity repair: level recomputed from the sponsor chain")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	for i := range report.Violations {
+		v := &report.Violations[i]
+		if _, ok := expected[v.DistributorID]; ok && v.Type == domain.ViolationLevelMismatch {
+			v.Repaired = true
+		}
+	}
+	
+	return report, nil
+}
+
+// checkPositions finds over-full parents and clashing or invalid positions
+// in the constrained tree types
+func (s *integrityService) checkPositions(g *genealogy) []domain.IntegrityViolation {
+	var violations []domain.IntegrityViolation
+	
+	children := make(map[uint][]*domain.Distributor)
+	for _, d := range g.live {
+		if !isConstrained(d.TreeType) {
+			continue
+		}
+		if parentID := placementParent(d); parentID != nil {
+			children[*parentID] = append(children[*parentID], d)
+		}
+	}
+	
+	for _, parentID := range sortedKeys(children) {
+		group := children[parentID]
+		seen := make(map[string]uint)
+		binaryCount, matrixCount := 0, 0
+		
+		for _, d := range group {
+			if d.TreeType == domain.TreeTypeMatrix {
+				matrixCount++
+			} else {
+				binaryCount++
+				if d.Position != "left" && d.Position != "right" {
+					violations = append(violations, domain.IntegrityViolation{
+						Type:          domain.ViolationInvalidPosition,
+						DistributorID: d.ID,
+						Details:       fmt.Sprintf("position %q under #%d is not left or right", d.Position, parentID),
+					})
+				}
+			}
+			
+			if firstID, ok := seen[d.Position]; ok {
+				violations = append(violations, domain.IntegrityViolation{
+					Type:          domain.ViolationDuplicatePosition,
+					DistributorID: d.ID,
+					Details:       fmt.Sprintf("position %q under #%d is also held by #%d", d.Position, parentID, firstID),
+				})
+			} else {
+				seen[d.Position] = d.ID
+			}
+		}
+		
+		if binaryCount > 2 {
+			violations = append(violations, domain.IntegrityViolation{
+				Type:          domain.ViolationOverCapacity,
+				DistributorID: parentID,
+				Details:       fmt.Sprintf("%d binary children, maximum is 2", binaryCount),
+			})
+		}
+		if matrixCount > s.config.MLM.MatrixWidth {
+			violations = append(violations, domain.IntegrityViolation{
+				Type:          domain.ViolationOverCapacity,
+				DistributorID: parentID,
+				Details:       fmt.Sprintf("%d matrix children, maximum is %d", matrixCount, s.config.MLM.MatrixWidth),
+			})
+		}
+	}
+	
+	return violations
+}
+
+// placementParent is the slot parent of a constrained member. Spillover
+// places members under placement_id; binary and matrix rows created before
+// it existed fall back to their sponsor, as in legacyPlacementClause.
+// Holding tank members have no slot yet.
+func placementParent(d *domain.Distributor) *uint {
+	if d.PlacementID != nil {
+		return d.PlacementID
+	}
+	if d.PlacementStatus != "placed" {
+		return nil
+	}
+	switch d.TreeType {
+	case domain.TreeTypeBinary, domain.TreeTypeMatrix:
+		return d.SponsorID
+	}
+	return nil
+}
+
+func (s *integrityService) load() (*genealogy, error) {
+	distributors, err := s.distributorRepo.ListGenealogy()
+	if err != nil {
+		return nil, err
+	}
+	
+	g := &genealogy{byID: make(map[uint]*domain.Distributor, len(distributors))}
+	for i := range distributors {
+		d := &distributors[i]
+		g.byID[d.ID] = d
+		if !d.DeletedAt.Valid {
+			g.live = append(g.live, d)
+		}
+	}
+	return g, nil
+}
+
+// findCycles returns the IDs of every live distributor that sits on a cycle
+// of the given parent link
+func (g *genealogy) findCycles(parentOf func(*domain.Distributor) *uint) map[uint]bool {
+	const (
+		unvisited = iota
+		inPath
+		done
+	)
+	
+	state := make(map[uint]int)
+	onCycle := make(map[uint]bool)
+	
+	for _, start := range g.live {
+		var path []uint
+		current, ok := start, true
+		for ok && state[current.ID] == unvisited {
+			state[current.ID] = inPath
+			path = append(path, current.ID)
+			
+			parentID := parentOf(current)
+			if parentID == nil {
+				break
+			}
+			current, ok = g.byID[*parentID]
+			if ok && state[current.ID] == inPath {
+				// Everything from the repeated node onwards is the cycle
+				for i := len(path) - 1; i >= 0; i-- {
+					onCycle[path[i]] = true
+					if path[i] == current.ID {
+						break
+					}
+				}
+				break
+			}
+		}
+		for _, id := range path {
+			state[id] = done
+		}
+	}
+	
+	return onCycle
+}
+
+// liveAncestor walks up from a (possibly deleted) distributor to the first
+// live one, passing through terminated sponsors
+func (g *genealogy) liveAncestor(id uint) (uint, bool) {
+	visited := make(map[uint]bool)
+	for !visited[id] {
+		visited[id] = true
+		d, ok := g.byID[id]
+		if !ok {
+			return 0, false
+		}
+		if !d.DeletedAt.Valid {
+			return d.ID, true
+		}
+		if d.SponsorID == nil {
+			return 0, false
+		}
+		id = *d.SponsorID
+	}
+	return 0, false
+}
+
+// expectedLevels computes each live distributor's depth from its root along
+// live sponsor links. Distributors on cycles or under missing sponsors are
+// left out.
+func (g *genealogy) expectedLevels() map[uint]int {
+	children := make(map[uint][]uint)
+	var queue []uint
+	levels := make(map[uint]int)
+	
+	for _, d := range g.live {
+		if d.SponsorID == nil {
+			levels[d.ID] = 0
+			queue = append(queue, d.ID)
+			continue
+		}
+		children[*d.SponsorID] = append(children[*d.SponsorID], d.ID)
+	}
+	
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, childID := range children[id] {
+			if _, seen := levels[childID]; seen {
+				continue
+			}
+			levels[childID] = levels[id] + 1
+			queue = append(queue, childID)
+		}
+	}
+	
+	return levels
+}
+
+func sortedIDs(set map[uint]bool) []uint {
+	ids := make([]uint, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func sortedKeys(m map[uint][]*domain.Distributor) []uint {
+	ids := make([]uint, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/mlm-app/backend/internal/config"
+	"github.com/mlm-app/backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+func (r *placementRepo) ListGenealogy() ([]domain.Distributor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	distributors := make([]domain.Distributor, 0, len(r.distributors))
+	for _, distributor := range r.distributors {
+		distributors = append(distributors, distributor)
+	}
+	sort.Slice(distributors, func(i, j int) bool { return distributors[i].ID < distributors[j].ID })
+	return distributors, nil
+}
+
+func TestCheckAcceptsSpilloverTree(t *testing.T) {
+	cfg := &config.Config{MLM: config.MLMConfig{MatrixWidth: 3}}
+
+	for _, treeType := range []domain.TreeType{domain.TreeTypeBinary, domain.TreeTypeMatrix, domain.TreeTypeHybrid} {
+		t.Run(string(treeType), func(t *testing.T) {
+			repo := newPlacementRepo()
+			treeService := NewTreeService(repo, nil, nil, nil, cfg)
+			integrityService := NewIntegrityService(repo, nil, treeService, nil, cfg)
+
+			root := &domain.Distributor{Email: "root@example.com", TreeType: treeType, PlacementStatus: "placed"}
+			if err := repo.Create(root); err != nil {
+				t.Fatal(err)
+			}
+
+			// Every member is enrolled by the root, so all but the first few
+			// spill over below other members
+			for i := 0; i < 12; i++ {
+				member := &domain.Distributor{
+					Email:           fmt.Sprintf("member%d@example.com", i),
+					SponsorID:       &root.ID,
+					TreeType:        treeType,
+					PlacementStatus: "placed",
+					Level:           1,
+				}
+				if err := treeService.PlaceMember(member); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			spilled := 0
+			for _, distributor := range repo.distributors {
+				if distributor.PlacementID != nil && *distributor.PlacementID != root.ID {
+					spilled++
+				}
+			}
+			if spilled == 0 {
+				t.Fatal("expected some members to spill over")
+			}
+
+			report, err := integrityService.Check()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, v := range report.Violations {
+				t.Errorf("unexpected %s on #%d: %s", v.Type, v.DistributorID, v.Details)
+			}
+		})
+	}
+}
+
+func TestCheckReportsDoubleBookedSlot(t *testing.T) {
+	cfg := &config.Config{MLM: config.MLMConfig{MatrixWidth: 3}}
+	repo := newPlacementRepo()
+	integrityService := NewIntegrityService(repo, nil, nil, nil, cfg)
+
+	root := &domain.Distributor{TreeType: domain.TreeTypeBinary, PlacementStatus: "placed"}
+	repo.Create(root)
+
+	// Legacy rows without placement_id are grouped under their sponsor, so
+	// they clash with a spilled member holding the same slot
+	for _, placementID := range []*uint{&root.ID, nil} {
+		repo.Create(&domain.Distributor{
+			SponsorID:       &root.ID,
+			PlacementID:     placementID,
+			TreeType:        domain.TreeTypeBinary,
+			Position:        "left",
+			PlacementStatus: "placed",
+			Level:           1,
+		})
+	}
+
+	report, err := integrityService.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Counts[domain.ViolationDuplicatePosition] != 1 || len(report.Violations) != 1 {
+		t.Errorf("got violations %+v, want one duplicate_position", report.Violations)
+	}
+}
+
+func TestRepairReattachesOrphanUnderTerminatedParent(t *testing.T) {
+	cfg := &config.Config{}
+
+	for _, treeType := range []domain.TreeType{domain.TreeTypeBinary, domain.TreeTypeHybrid} {
+		t.Run(string(treeType), func(t *testing.T) {
+			repo := newPlacementRepo()
+			audit := &auditRecorder{}
+			treeService := NewTreeService(repo, breakawayRepo{}, audit, inlineTransactor{}, cfg)
+			integrityService := NewIntegrityService(repo, audit, treeService, inlineTransactor{}, cfg)
+
+			add := func(d domain.Distributor) uint {
+				d.TreeType = treeType
+				d.PlacementStatus = "placed"
+				if err := repo.Create(&d); err != nil {
+					t.Fatal(err)
+				}
+				return d.ID
+			}
+			id := func(v uint) *uint { return &v }
+
+			// The terminated parent gave up its slot but its child was never
+			// rolled up, and a sibling carries a stale level
+			root := add(domain.Distributor{})
+			terminated := add(domain.Distributor{SponsorID: id(root), Level: 1, LeftLegVolume: 100,
+				DeletedAt: gorm.DeletedAt{Time: time.Now(), Valid: true}})
+			orphan := add(domain.Distributor{SponsorID: id(terminated), PlacementID: id(terminated), Position: "left", Level: 2, PersonalSales: 100})
+			sibling := add(domain.Distributor{SponsorID: id(root), PlacementID: id(root), Position: "right", Level: 5})
+
+			report, err := integrityService.Repair(AuditMeta{ActorID: 7})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Counts[domain.ViolationOrphan] != 1 || report.Counts[domain.ViolationLevelMismatch] != 1 {
+				t.Fatalf("got violations %+v, want one orphan and one level mismatch", report.Violations)
+			}
+			for _, v := range report.Violations {
+				if !v.Repaired {
+					t.Errorf("%s on #%d not repaired: %s", v.Type, v.DistributorID, v.Details)
+				}
+			}
+
+			moved := repo.distributors[orphan]
+			if *moved.SponsorID != root || moved.PlacementID == nil || *moved.PlacementID != root || moved.Level != 1 {
+				t.Errorf("orphan now sponsor #%d placement %v level %d, want #%d under #%d at level 1",
+					*moved.SponsorID, moved.PlacementID, moved.Level, root, root)
+			}
+			if level := repo.distributors[sibling].Level; level != 1 {
+				t.Errorf("sibling level = %d, want 1", level)
+			}
+			if d := repo.distributors[root]; d.LeftLegVolume != 100 || d.RightLegVolume != 0 {
+				t.Errorf("root legs = %v/%v, want 100/0", d.LeftLegVolume, d.RightLegVolume)
+			}
+
+			actions := make(map[string]int)
+			for _, entry := range audit.entries {
+				actions[entry.Action]++
+				if entry.ActorID == nil || *entry.ActorID != 7 {
+					t.Errorf("%s audited without the repairing admin", entry.Action)
+				}
+			}
+			if actions["tree.move"] != 1 || actions["tree.level_repair"] != 1 {
+				t.Errorf("audit actions = %v, want one tree.move and one tree.level_repair", actions)
+			}
+
+			after, err := integrityService.Check()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, v := range after.Violations {
+				t.Errorf("%s on #%d remains after repair: %s", v.Type, v.DistributorID, v.Details)
+			}
+		})
+	}
+}
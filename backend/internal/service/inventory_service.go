@@ -0,0 +1,46 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/mlm-app/backend/internal/domain"
+	"github.com/mlm-app/backend/internal/repository"
+)
+
+type InventoryService interface {
+	List(offset, limit int) ([]domain.Product, int64, error)
+	SetStock(sku string, stock int) (*domain.Product, error)
+}
+
+type inventoryService struct {
+	productRepo repository.ProductRepository
+}
+
+func NewInventoryService(productRepo repository.ProductRepository) InventoryService {
+	return &inventoryService{
+		productRepo: productRepo,
+	}
+}
+
+// List returns products with their stock levels, ordered by SKU
+func (s *inventoryService) List(offset, limit int) ([]domain.Product, int64, error) {
+	return s.productRepo.List(offset, limit)
+}
+
+// SetStock overwrites a product's stock level, e.g. after a warehouse count
+func (s *inventoryService) SetStock(sku string, stock int) (*domain.Product, error) {
+	if stock < 0 {
+		return nil, errors.New("stock cannot be negative")
+	}
+	
+	product, err := s.productRepo.FindBySKU(sku)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.productRepo.UpdateStock(product.ID, stock); err != nil {
+		return nil, err
+	}
+	
+	product.Stock = stock
+	return product, nil
+}
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mlm-app/backend/internal/config"
+	"github.com/mlm-app/backend/internal/domain"
+	"github.com/mlm-app/backend/internal/repository"
+	"github.com/mlm-app/backend/pkg/storage"
+)
+
+// ErrKYCNotApproved blocks commission approvals and payouts
+var ErrKYCNotApproved = errors.New("KYC verification is not approved")
+
+// kycDocumentTypes maps each accepted document type to whether it proves identity
+var kycDocumentTypes = map[string]bool{
+	domain.KYCDocumentPassport:       true,
+	domain.KYCDocumentNationalID:     true,
+	domain.KYCDocumentDriversLicense: true,
+	domain.KYCDocumentProofOfAddress: false,
+	domain.KYCDocumentTaxForm:        false,
+}
+
+// kycContentTypes maps accepted sniffed content types to file extensions
+var kycContentTypes = map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+}
+
+// KYCDetails are the tax details a distributor submits with their documents
+type KYCDetails struct {
+	LegalName  string
+	TaxCountry string
+	TaxIDType  string
+	TaxID      string
+}
+
+type KYCService interface {
+	Current(distributorID uint) (*domain.KYCSubmission, error)
+	UploadDocument(distributorID uint, docType, fileName string, r io.Reader, meta AuditMeta) (*domain.KYCDocument, error)
+	Submit(distributorID uint, details KYCDetails, meta AuditMeta) (*domain.KYCSubmission, error)
+	List(status string, offset, limit int) ([]domain.KYCSubmission, int64, error)
+	GetSubmission(id uint) (*domain.KYCSubmission, error)
+	OpenDocument(submissionID, documentID uint) (*domain.KYCDocument, io.ReadCloser, error)
+	Approve(id uint, meta AuditMeta) error
+	Reject(id uint, reason string, meta AuditMeta) error
+}
+
+type kycService struct {
+	kycRepo         repository.KYCRepository
+	distributorRepo repository.DistributorRepository
+	auditRepo       repository.AuditRepository
+	transactor      repository.Transactor
+	store           storage.Store
+	config          *config.Config
+}
+
+func NewKYCService(
+	kycRepo repository.KYCRepository,
+	distributorRepo repository.DistributorRepository,
+	auditRepo repository.AuditRepository,
+	transactor repository.Transactor,
+	store storage.Store,
+	cfg *config.Config,
+) KYCService {
+	return &kycService{
+		kycRepo:         kycRepo,
+		distributorRepo: distributorRepo,
+		auditRepo:       auditRepo,
+		transactor:      transactor,
+		store:           store,
+		config:          cfg,
+	}
+}
+
+// Current returns the distributor's latest submission, or nil if they have
+// not started one
+func (s *kycService) Current(distributorID uint) (*domain.KYCSubmission, error) {
+	submission, err := s.kycRepo.FindLatestByDistributor(distributorID)
+	if errors.Is(err, repository.ErrKYCSubmissionNotFound) {
+		return nil, nil
+	}
+	return submission, err
+}
+
+// UploadDocument stores a document in the distributor's draft submission,
+// starting one if needed. The type of file is detected from its content;
+// only PDF, JPEG and PNG files within the size limit are kept.
+func (s *kycService) UploadDocument(distributorID uint, docType, fileName string, r io.Reader, meta AuditMeta) (*domain.KYCDocument, error) {
+	if _, ok := kycDocumentTypes[docType]; !ok {
+		return nil, fmt.Errorf("unknown document type %q", docType)
+	}
+	
+	submission, err := s.draftSubmission(distributorID)
+	if err != nil {
+		return nil, err
+	}
+	
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		if err == io.EOF {
+			return nil, errors.New("document is empty")
+		}
+		return nil, err
+	}
+	head = head[:n]
+	
+	contentType := http.DetectContentType(head)
+	ext, ok := kycContentTypes[contentType]
+	if !ok {
+		return nil, errors.New("documents must be PDF, JPEG or PNG files")
+	}
+	
+	suffix, err := randomToken(12)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("kyc/%d/%d/%s%s", distributorID, submission.ID, suffix, ext)
+	
+	// Read one byte past the limit so an oversized file can be told apart
+	hash := sha256.New()
+	var size byteCounter
+	body := io.TeeReader(io.LimitReader(io.MultiReader(bytes.NewReader(head), r), s.config.KYC.MaxDocumentSize+1), io.MultiWriter(hash, &size))
+	if err := s.store.Put(key, body); err != nil {
+		return nil, err
+	}
+	if int64(size) > s.config.KYC.MaxDocumentSize {
+		s.discard(key)
+		return nil, fmt.Errorf("document is larger than the %d byte limit", s.config.KYC.MaxDocumentSize)
+	}
+	
+	document := &domain.KYCDocument{
+		SubmissionID: submission.ID,
+		Type:         docType,
+		FileName:     truncate(filepath.Base(filepath.Clean("/"+fileName)), 255),
+		ContentType:  contentType,
+		Size:         int64(size),
+		SHA256:       hex.EncodeToString(hash.Sum(nil)),
+		StorageKey:   key,
+	}
+	err = s.transactor.Transaction(func(tx *repository.Tx) error {
+		if err := s.kycRepo.WithTx(tx).CreateDocument(document); err != nil {
+			return err
+		}
+		return s.auditRepo.WithTx(tx).Create(newAuditEntry(meta, "kyc.document_upload", "kyc_submission", submission.ID, nil, document, ""))
+	})
+	if err != nil {
+		s.discard(key)
+		return nil, err
+	}
+	
+	return document, nil
+}
+
+// Submit sends the draft submission for review with the distributor's tax
+// details. At least one identity document is required. Only a hash and the
+// last four characters of the tax ID are kept; reviewers check the number
+// against the uploaded documents.
+func (s *kycService) Submit(distributorID uint, details KYCDetails, meta AuditMeta) (*domain.KYCSubmission, error) {
+	submission, err := s.Current(distributorID)
+	if err != nil {
+		return nil, err
+	}
+	if submission == nil || submission.Status != domain.KYCStatusDraft {
+		return nil, errors.New("upload your documents before submitting")
+	}
+	
+	hasIdentity := false
+	for _, document := range submission.Documents {
+		if kycDocumentTypes[document.Type] {
+			hasIdentity = true
+		}
+	}
+	if !hasIdentity {
+		return nil, errors.New("a passport, national ID or driver's license is required")
+	}
+	
+	taxID := strings.ToUpper(strings.NewReplacer(" ", "", "-", "", ".", "").Replace(details.TaxID))
+	if len(taxID) < 4 {
+		return nil, errors.New("tax ID is too short")
+	}
+	
+	before := *submission
+	now := time.Now()
+	submission.LegalName = strings.TrimSpace(details.LegalName)
+	submission.TaxCountry = strings.ToUpper(details.TaxCountry)
+	submission.TaxIDType = strings.ToLower(details.TaxIDType)
+	submission.TaxIDHash = s.hashTaxID(submission.TaxCountry, taxID)
+	submission.TaxIDLast4 = taxID[len(taxID)-4:]
+	submission.Status = domain.KYCStatusPending
+	submission.SubmittedAt = &now
+	
+	err = s.transactor.Transaction(func(tx *repository.Tx) error {
+		if err := s.kycRepo.WithTx(tx).UpdateSubmission(submission); err != nil {
+			return err
+		}
+		if err := s.distributorRepo.WithTx(tx).UpdateKYCStatus(distributorID, domain.KYCStatusPending); err != nil {
+			return err
+		}
+		return s.auditRepo.WithTx(tx).Create(newAuditEntry(meta, "kyc.submit", "kyc_submission", submission.ID, &before, submission, ""))
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	return submission, nil
+}
+
+// List returns submissions for review, oldest first
+func (s *kycService) List(status string, offset, limit int) ([]domain.KYCSubmission, int64, error) {
+	return s.kycRepo.ListSubmissions(status, offset, limit)
+}
+
+func (s *kycService) GetSubmission(id uint) (*domain.KYCSubmission, error) {
+	return s.kycRepo.FindSubmission(id)
+}
+
+// OpenDocument returns a document of a submission with its file; the caller
+// closes the reader
+func (s *kycService) OpenDocument(submissionID, documentID uint) (*domain.KYCDocument, io.ReadCloser, error) {
+	document, err := s.kycRepo.FindDocument(documentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if document.SubmissionID != submissionID {
+		return nil, nil, errors.New("KYC document not found")
+	}
+	
+	file, err := s.store.Open(document.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return document, file, nil
+}
+
+// Approve verifies the distributor, releasing their commissions
+func (s *kycService) Approve(id uint, meta AuditMeta) error {
+	return s.review(id, domain.KYCStatusApproved, "kyc.approve", "", meta)
+}
+
+// Reject turns a submission down; the distributor can then start a new one
+func (s *kycService) Reject(id uint, reason string, meta AuditMeta) error {
+	return s.review(id, domain.KYCStatusRejected, "kyc.reject", reason, meta)
+}
+
+// review records an admin decision on a pending submission
+func (s *kycService) review(id uint, status, action, reason string, meta AuditMeta) error {
+	submission, err := s.kycRepo.FindSubmission(id)
+	if err != nil {
+		return err
+	}
+	if submission.Status != domain.KYCStatusPending {
+		return errors.New("KYC submission is not waiting for review")
+	}
+	if submission.DistributorID == meta.ActorID {
+		return errors.New("admins cannot review their own KYC submission")
+	}
+	
+	before := *submission
+	now := time.Now()
+	submission.Status = status
+	submission.ReviewedAt = &now
+	submission.ReviewedByID = optionalID(meta.ActorID)
+	submission.RejectionReason = truncate(reason, 500)
+	
+	return s.transactor.Transaction(func(tx *repository.Tx) error {
+		if err := s.kycRepo.WithTx(tx).UpdateSubmission(submission); err != nil {
+			return err
+		}
+		if err := s.distributorRepo.WithTx(tx).UpdateKYCStatus(submission.DistributorID, status); err != nil {
+			return err
+		}
+		return s.auditRepo.WithTx(tx).Create(newAuditEntry(meta, action, "kyc_submission", submission.ID, &before, submission, reason))
+	})
+}
+
+// draftSubmission returns the submission documents go into, starting a new
+// one when the distributor has none or was rejected
+func (s *kycService) draftSubmission(distributorID uint) (*domain.KYCSubmission, error) {
+	submission, err := s.Current(distributorID)
+	if err != nil {
+		return nil, err
+	}
+	if submission != nil {
+		switch submission.Status {
+		case domain.KYCStatusDraft:
+			return submission, nil
+		case domain.KYCStatusPending:
+			return nil, errors.New("your documents are waiting for review")
+		case domain.KYCStatusApproved:
+			return nil, errors.New("your identity is already verified")
+		}
+	}
+	
+	submission = &domain.KYCSubmission{
+		DistributorID: distributorID,
+		Status:        domain.KYCStatusDraft,
+	}
+	if err := s.kycRepo.CreateSubmission(submission); err != nil {
+		return nil, err
+	}
+	return submission, nil
+}
+
+// hashTaxID keys the hash with a server secret: tax IDs are short enough
+// that a plain hash could be reversed by trying every number
+func (s *kycService) hashTaxID(country, taxID string) string {
+	mac := hmac.New(sha256.New, []byte(s.config.KYC.TaxIDHashKey))
+	mac.Write([]byte(country + ":" + taxID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// discard removes a stored file whose upload did not complete
+func (s *kycService) discard(key string) {
+	if err := s.store.Delete(key); err != nil {
+		log.Printf("Failed to remove KYC file %s: %v", key, err)
+	}
+}
+
+// requireKYC refuses to pay a distributor whose identity is not verified
+func requireKYC(cfg *config.Config, distributor *domain.Distributor) error {
+	if cfg.KYC.Required && distributor.KYCStatus != domain.KYCStatusApproved {
+		return fmt.Errorf("distributor #%d: %w", distributor.ID, ErrKYCNotApproved)
+	}
+	return nil
+}
+
+// byteCounter counts the bytes written to it
+type byteCounter int64
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/mlm-app/backend/internal/config"
+	"github.com/mlm-app/backend/internal/domain"
+	"github.com/mlm-app/backend/internal/repository"
+	"github.com/mlm-app/backend/pkg/ratelimit"
+)
+
+// maxDelayDoublings caps the exponent of the progressive delay so the shift
+// cannot overflow before LoginMaxDelay applies
+const maxDelayDoublings = 16
+
+// LoginThrottledError is returned while an account is inside its progressive
+// login delay
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, try again in %d seconds", int(e.RetryAfter.Round(time.Second).Seconds()))
+}
+
+type LoginSecurityService interface {
+	BeginAttempt(email string) error
+	RecordFailure(email, ipAddress, userAgent, result string)
+	RecordSuccess(distributor *domain.Distributor, ipAddress, userAgent, result string)
+	Unlock(distributorID uint, meta AuditMeta) (*domain.Distributor, error)
+	ListAttempts(filter repository.LoginAttemptFilter, offset, limit int) ([]domain.LoginAttempt, int64, error)
+}
+
+type loginSecurityService struct {
+	store            ratelimit.Store
+	distributorRepo  repository.DistributorRepository
+	loginAttemptRepo repository.LoginAttemptRepository
+	auditRepo        repository.AuditRepository
+	transactor       repository.Transactor
+	config           *config.Config
+}
+
+func NewLoginSecurityService(
+	store ratelimit.Store,
+	distributorRepo repository.DistributorRepository,
+	loginAttemptRepo repository.LoginAttemptRepository,
+	auditRepo repository.AuditRepository,
+	transactor repository.Transactor,
+	cfg *config.Config,
+) LoginSecurityService {
+	return &loginSecurityService{
+		store:            store,
+		distributorRepo:  distributorRepo,
+		loginAttemptRepo: loginAttemptRepo,
+		auditRepo:        auditRepo,
+		transactor:       transactor,
+		config:           cfg,
+	}
+}
+
+// BeginAttempt counts a login attempt against the account before the
+// password is checked, so parallel guesses cannot all pass the check before
+// the first failure is recorded, and enforces the progressive delay: once an
+// account has LoginDelayAfter earlier attempts without a success, each
+// further attempt must wait twice as long after the previous one, up to
+// LoginMaxDelay. A successful login clears the counter.
+func (s *loginSecurityService) BeginAttempt(email string) error {
+	entry, err := s.store.Hit(failureKey(email), s.config.Auth.LoginFailureWindow)
+	if err != nil {
+		// Fail open; the per-IP limit and lockout still apply
+		log.Printf("login limiter unavailable: %v", err)
+		return nil
+	}
+	
+	excess := entry.Count - 1 - s.config.Auth.LoginDelayAfter
+	if excess < 0 {
+		return nil
+	}
+	if excess > maxDelayDoublings {
+		excess = maxDelayDoublings
+	}
+	
+	delay := time.Second << uint(excess)
+	if delay > s.config.Auth.LoginMaxDelay {
+		delay = s.config.Auth.LoginMaxDelay
+	}
+	
+	if wait := time.Until(entry.PrevHit.Add(delay)); wait > 0 {
+		return &LoginThrottledError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// RecordFailure logs a failed attempt. Wrong passwords and second-factor
+// codes lock the account once BeginAttempt has counted LockoutThreshold
+// attempts without a success.
+func (s *loginSecurityService) RecordFailure(email, ipAddress, userAgent, result string) {
+	var distributorID *uint
+	distributor, err := s.distributorRepo.FindByEmail(email)
+	if err == nil {
+		distributorID = &distributor.ID
+	}
+	
+	if countsTowardLockout(result) && distributor != nil {
+		entry, err := s.store.Get(failureKey(email))
+		if err != nil {
+			log.Printf("login limiter unavailable: %v", err)
+		} else if entry.Count >= s.config.Auth.LockoutThreshold {
+			until := time.Now().Add(s.config.Auth.LockoutDuration)
+			if err := s.distributorRepo.LockAccount(distributor.ID, until); err != nil {
+				log.Printf("failed to lock distributor %d: %v", distributor.ID, err)
+			}
+		}
+	}
+	
+	s.record(email, distributorID, ipAddress, userAgent, false, result)
+}
+
+// RecordSuccess logs an accepted attempt. The failure counter is only
+// cleared by a completed login, so a password that earned a 2FA challenge
+// does not wipe the failures counted against the second factor.
+func (s *loginSecurityService) RecordSuccess(distributor *domain.Distributor, ipAddress, userAgent, result string) {
+	if result == domain.LoginResultSuccess {
+		if err := s.store.Reset(failureKey(distributor.Email)); err != nil {
+			log.Printf("login limiter unavailable: %v", err)
+		}
+	}
+	
+	s.record(distributor.Email, &distributor.ID, ipAddress, userAgent, true, result)
+}
+
+// Unlock lifts a lockout before it expires and clears the failure counter
+func (s *loginSecurityService) Unlock(distributorID uint, meta AuditMeta) (*domain.Distributor, error) {
+	distributor, err := s.distributorRepo.FindByID(distributorID)
+	if err != nil {
+		return nil, err
+	}
+	
+	err = s.transactor.Transaction(func(tx *repository.Tx) error {
+		if err := s.distributorRepo.WithTx(tx).UnlockAccount(distributorID); err != nil {
+			return err
+		}
+		return s.auditRepo.WithTx(tx).Create(newAuditEntry(meta, "account.unlock", "distributor", distributorID,
+			map[string]interface{}{"locked_until": distributor.LockedUntil},
+			map[string]interface{}{"locked_until": nil}, ""))
+	})
+	if err != nil {
+		return nil, err
+	}
+	distributor.LockedUntil = nil
+	
+	if err := s.store.Reset(failureKey(distributor.Email)); err != nil {
+		log.Printf("login limiter unavailable: %v", err)
+	}
+	
+	return distributor, nil
+}
+
+// ListAttempts returns recorded logins, newest first
+func (s *loginSecurityService) ListAttempts(filter repository.LoginAttemptFilter, offset, limit int) ([]domain.LoginAttempt, int64, error) {
+	return s.loginAttemptRepo.List(filter, offset, limit)
+}
+
+func (s *loginSecurityService) record(email string, distributorID *uint, ipAddress, userAgent string, success bool, result string) {
+	attempt := &domain.LoginAttempt{
+		Email:         normalizeEmail(email),
+		DistributorID: distributorID,
+		IPAddress:     ipAddress,
+		UserAgent:     truncate(userAgent, 255),
+		Success:       success,
+		Result:        result,
+	}
+	// Recording is best effort; a logging failure should not block logins
+	if err := s.loginAttemptRepo.Create(attempt); err != nil {
+		log.Printf("failed to record login attempt: %v", err)
+	}
+}
+
+// countsTowardLockout reports whether a failed attempt guessed a secret
+func countsTowardLockout(result string) bool {
+	return result == domain.LoginResultInvalidCredentials || result == domain.LoginResultInvalidTwoFactor
+}
+
+// failureKey is the limiter key counting failed logins for an account
+func failureKey(email string) string {
+	return "login:account:" + normalizeEmail(email)
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/mlm-app/backend/internal/domain"
+	"github.com/mlm-app/backend/internal/repository"
+)
+
+type OrderService interface {
+	List(offset, limit int) ([]domain.Order, int64, error)
+	GetByID(id uint) (*domain.Order, error)
+	RecordPayment(id uint, paymentStatus, paymentMethod string, meta AuditMeta) (*domain.Order, error)
+}
+
+type orderService struct {
+	orderRepo         repository.OrderRepository
+	auditRepo         repository.AuditRepository
+	packageService    PackageService
+	statusService     StatusService
+	commissionService CommissionService
+	transactor        repository.Transactor
+}
+
+func NewOrderService(
+	orderRepo repository.OrderRepository,
+	auditRepo repository.AuditRepository,
+	packageService PackageService,
+	statusService StatusService,
+	commissionService CommissionService,
+	transactor repository.Transactor,
+) OrderService {
+	return &orderService{
+		orderRepo:         orderRepo,
+		auditRepo:         auditRepo,
+		packageService:    packageService,
+		statusService:     statusService,
+		commissionService: commissionService,
+		transactor:        transactor,
+	}
+}
+
+// List returns all orders, newest first
+func (s *orderService) List(offset, limit int) ([]domain.Order, int64, error) {
+	return s.orderRepo.List(offset, limit)
+}
+
+// GetByID returns an order with its items
+func (s *orderService) GetByID(id uint) (*domain.Order, error) {
+	return s.orderRepo.FindByID(id)
+}
+
+// RecordPayment stores the outcome of a payment. A paid package order
+// activates its package in the same transaction, every paid order pays its
+// commissions and credits its volume there too, and any paid order counts
+// as a purchase for the distributor's activity status.
+func (s *orderService) RecordPayment(id uint, paymentStatus, paymentMethod string, meta AuditMeta) (*domain.Order, error) {
+	order, err := s.orderRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if order.PaymentStatus == domain.PaymentStatusPaid {
+		return nil, errors.New("order is already paid")
+	}
+	if order.Status == domain.OrderStatusCancelled {
+		return nil, errors.New("order has been cancelled")
+	}
+	
+	// Package orders have nothing to ship, so payment completes them
+	status := order.Status
+	if paymentStatus == domain.PaymentStatusPaid {
+		status = domain.OrderStatusProcessing
+		if order.PackageID != nil {
+			status = domain.OrderStatusCompleted
+		}
+	}
+	
+	err = s.transactor.Transaction(func(tx *repository.Tx) error {
+		if err := s.orderRepo.WithTx(tx).UpdatePayment(order.ID, paymentStatus, status, paymentMethod); err != nil {
+			return err
+		}
+		if paymentStatus == domain.PaymentStatusPaid {
+			if err := s.packageService.ApplyPaidOrder(tx, order, meta); err != nil {
+				return err
+			}
+			if err := s.commissionService.CalculateAndCreateCommissions(tx, order); err != nil {
+				return err
+			}
+			if err := s.statusService.RecordPurchase(tx, order.DistributorID, order.SubTotal, meta); err != nil {
+				return err
+			}
+		}
+		
+		return s.auditRepo.WithTx(tx).Create(newAuditEntry(meta, "order.payment", "order", order.ID,
+			map[string]interface{}{"payment_status": order.PaymentStatus, "status": order.Status},
+			map[string]interface{}{"payment_status": paymentStatus, "status": status, "payment_method": paymentMethod}, ""))
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	return s.orderRepo.FindByID(order.ID)
+}
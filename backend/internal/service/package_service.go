@@ -0,0 +1,491 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/mlm-app/backend/internal/config"
+	"github.com/mlm-app/backend/internal/domain"
+	"github.com/mlm-app/backend/internal/repository"
+)
+
+type PackageService interface {
+	ListActive() ([]domain.Package, error)
+	List() ([]domain.Package, error)
+	GetByID(id uint) (*domain.Package, error)
+	Versions(id uint) ([]domain.Package, error)
+	Create(pkg *domain.Package, meta AuditMeta) error
+	Update(id uint, changes *domain.Package, meta AuditMeta) (*domain.Package, error)
+	SetActive(id uint, active bool, meta AuditMeta) (*domain.Package, error)
+	Delete(id uint, meta AuditMeta) error
+	Purchase(distributorID, packageID uint, meta AuditMeta) (*domain.Order, error)
+	Renew(distributorID uint, meta AuditMeta) (*domain.Order, error)
+	ApplyPaidOrder(tx *repository.Tx, order *domain.Order, meta AuditMeta) error
+	ExpireLapsed() (int, error)
+}
+
+type packageService struct {
+	packageRepo     repository.PackageRepository
+	orderRepo       repository.OrderRepository
+	distributorRepo repository.DistributorRepository
+	auditRepo       repository.AuditRepository
+	transactor      repository.Transactor
+	config          *config.Config
+}
+
+func NewPackageService(
+	packageRepo repository.PackageRepository,
+	orderRepo repository.OrderRepository,
+	distributorRepo repository.DistributorRepository,
+	auditRepo repository.AuditRepository,
+	transactor repository.Transactor,
+	cfg *config.Config,
+) PackageService {
+	return &packageService{
+		packageRepo:     packageRepo,
+		orderRepo:       orderRepo,
+		distributorRepo: distributorRepo,
+		auditRepo:       auditRepo,
+		transactor:      transactor,
+		config:          cfg,
+	}
+}
+
+// ListActive returns the packages on sale, cheapest first
+func (s *packageService) ListActive() ([]domain.Package, error) {
+	return s.packageRepo.List(true)
+}
+
+// List returns the current version of every package, including inactive ones
+func (s *packageService) List() ([]domain.Package, error) {
+	return s.packageRepo.List(false)
+}
+
+func (s *packageService) GetByID(id uint) (*domain.Package, error) {
+	return s.packageRepo.FindByID(id)
+}
+
+// Versions returns every version of the package id belongs to, newest first
+func (s *packageService) Versions(id uint) ([]domain.Package, error) {
+	pkg, err := s.packageRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.packageRepo.ListVersions(pkg.Code)
+}
+
+// Create adds the first version of a new package
+func (s *packageService) Create(pkg *domain.Package, meta AuditMeta) error {
+	if err := validatePackage(pkg); err != nil {
+		return err
+	}
+	if _, err := s.packageRepo.FindLatestByCode(pkg.Code); err == nil {
+		return fmt.Errorf("package code %q is already in use", pkg.Code)
+	}
+	
+	pkg.ID = 0
+	pkg.Version = 1
+	pkg.SupersededAt = nil
+	return s.transactor.Transaction(func(tx *repository.Tx) error {
+		if err := s.packageRepo.WithTx(tx).Create(pkg); err != nil {
+			return err
+		}
+		return s.auditRepo.WithTx(tx).Create(newAuditEntry(meta, "package.create", "package", pkg.ID, nil, pkg, ""))
+	})
+}
+
+// Update edits the current version of a package. Name and description are
+// changed in place; changed terms create a new version and retire this one,
+// so members who bought it keep its terms.
+func (s *packageService) Update(id uint, changes *domain.Package, meta AuditMeta) (*domain.Package, error) {
+	current, err := s.packageRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if current.SupersededAt != nil {
+		return nil, errors.New("only the current version of a package can be edited")
+	}
+	
+	updated := *current
+	updated.Name = changes.Name
+	updated.Description = changes.Description
+	updated.Price = changes.Price
+	updated.CommissionRate = changes.CommissionRate
+	updated.MaxLevels = changes.MaxLevels
+	updated.TermMonths = changes.TermMonths
+	updated.Features = changes.Features
+	if err := validatePackage(&updated); err != nil {
+		return nil, err
+	}
+	
+	if !termsChanged(current, &updated) {
+		err = s.transactor.Transaction(func(tx *repository.Tx) error {
+			if err := s.packageRepo.WithTx(tx).Update(&updated); err != nil {
+				return err
+			}
+			return s.auditRepo.WithTx(tx).Create(newAuditEntry(meta, "package.update", "package", updated.ID, current, &updated, ""))
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &updated, nil
+	}
+	
+	next := updated
+	next.ID = 0
+	next.CreatedAt = time.Time{}
+	next.UpdatedAt = time.Time{}
+	next.Version = current.Version + 1
+	err = s.transactor.Transaction(func(tx *repository.Tx) error {
+		packageRepo := s.packageRepo.WithTx(tx)
+		if err := packageRepo.MarkSuperseded(current.ID, time.Now()); err != nil {
+			return err
+		}
+		if err := packageRepo.Create(&next); err != nil {
+			return err
+		}
+		
+		reason := fmt.Sprintf("supersedes version %d (package #%d)", current.Version, current.ID)
+		return s.auditRepo.WithTx(tx).Create(newAuditEntry(meta, "package.version", "package", next.ID, current, &next, reason))
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	return &next, nil
+}
+
+// SetActive puts a package on sale or takes it off. Members who hold it
+// keep it either way.
+func (s *packageService) SetActive(id uint, active bool, meta AuditMeta) (*domain.Package, error) {
+	current, err := s.packageRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if current.SupersededAt != nil {
+		return nil, errors.New("only the current version of a package can be activated or deactivated")
+	}
+	
+	updated := *current
+	updated.IsActive = active
+	err = s.transactor.Transaction(func(tx *repository.Tx) error {
+		if err := s.packageRepo.WithTx(tx).Update(&updated); err != nil {
+			return err
+		}
+		return s.auditRepo.WithTx(tx).Create(newAuditEntry(meta, "package.update", "package", updated.ID,
+			map[string]interface{}{"is_active": current.IsActive},
+			map[string]interface{}{"is_active": active}, ""))
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	return &updated, nil
+}
+
+// Delete removes a package with all its versions. Packages that members or
+// orders refer to can only be deactivated.
+func (s *packageService) Delete(id uint, meta AuditMeta) error {
+	pkg, err := s.packageRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if pkg.ID == s.config.MLM.DefaultPackageID {
+		return errors.New("the default package cannot be deleted")
+	}
+	
+	versions, err := s.packageRepo.ListVersions(pkg.Code)
+	if err != nil {
+		return err
+	}
+	for _, version := range versions {
+		used, err := s.packageRepo.CountUsage(version.ID)
+		if err != nil {
+			return err
+		}
+		if used > 0 {
+			return errors.New("package is in use; deactivate it instead")
+		}
+	}
+	
+	return s.transactor.Transaction(func(tx *repository.Tx) error {
+		for i := range versions {
+			if err := s.packageRepo.WithTx(tx).Delete(versions[i].ID); err != nil {
+				return err
+			}
+			if err := s.auditRepo.WithTx(tx).Create(newAuditEntry(meta, "package.delete", "package", versions[i].ID, &versions[i], nil, "")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Purchase creates the order for enrolling in a package, or for upgrading
+// to it from a cheaper current package at a prorated price. The package
+// only activates once the order is paid.
+func (s *packageService) Purchase(distributorID, packageID uint, meta AuditMeta) (*domain.Order, error) {
+	distributor, err := s.distributorRepo.FindByID(distributorID)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := s.packageRepo.FindByID(packageID)
+	if err != nil {
+		return nil, err
+	}
+	if !pkg.IsActive || pkg.SupersededAt != nil {
+		return nil, errors.New("package is not available")
+	}
+	
+	orderType := domain.OrderTypePackage
+	price := pkg.Price
+	if current := s.currentPackage(distributor); current != nil {
+		if current.Code == pkg.Code {
+			return nil, errors.New("already enrolled in this package")
+		}
+		if pkg.Price <= current.Price {
+			return nil, errors.New("packages can only be upgraded; a cheaper package can be bought once the current one lapses")
+		}
+		
+		orderType = domain.OrderTypePackageUpgrade
+		price = upgradePrice(current, pkg, distributor.PackageExpiresAt, time.Now())
+	}
+	
+	return s.createOrder(distributor, pkg, orderType, price, meta)
+}
+
+// Renew creates the order extending the current package by its term, at the
+// price of the version the member holds even if it has been superseded or
+// taken off sale. It must be paid before the package lapses; afterwards a
+// package has to be purchased again.
+func (s *packageService) Renew(distributorID uint, meta AuditMeta) (*domain.Order, error) {
+	distributor, err := s.distributorRepo.FindByID(distributorID)
+	if err != nil {
+		return nil, err
+	}
+	
+	current := s.currentPackage(distributor)
+	if current == nil {
+		return nil, errors.New("no package to renew")
+	}
+	if current.TermMonths == 0 || distributor.PackageExpiresAt == nil {
+		return nil, errors.New("package does not need renewing")
+	}
+	
+	return s.createOrder(distributor, current, domain.OrderTypePackageRenewal, current.Price, meta)
+}
+
+// ApplyPaidOrder activates the package of a paid package order inside tx
+func (s *packageService) ApplyPaidOrder(tx *repository.Tx, order *domain.Order, meta AuditMeta) error {
+	if order.PackageID == nil {
+		return nil
+	}
+	
+	distributor, err := s.distributorRepo.WithTx(tx).FindByID(order.DistributorID)
+	if err != nil {
+		return err
+	}
+	pkg, err := s.packageRepo.FindByID(*order.PackageID)
+	if err != nil {
+		return err
+	}
+	
+	now := time.Now()
+	var expiresAt *time.Time
+	switch order.Type {
+	case domain.OrderTypePackageRenewal:
+		if distributor.PackageID == nil || *distributor.PackageID != pkg.ID {
+			return errors.New("renewal does not match the current package")
+		}
+		from := now
+		if distributor.PackageExpiresAt != nil && distributor.PackageExpiresAt.After(now) {
+			from = *distributor.PackageExpiresAt
+		}
+		expiresAt = termEnd(from, pkg.TermMonths)
+	case domain.OrderTypePackageUpgrade:
+		// The upgrade was priced for the rest of the current term
+		expiresAt = distributor.PackageExpiresAt
+		if pkg.TermMonths == 0 {
+			expiresAt = nil
+		} else if expiresAt == nil || !expiresAt.After(now) {
+			expiresAt = termEnd(now, pkg.TermMonths)
+		}
+	default:
+		expiresAt = termEnd(now, pkg.TermMonths)
+	}
+	
+	return s.changePackage(tx, distributor, &pkg.ID, expiresAt, meta, fmt.Sprintf("order #%s", order.OrderNumber))
+}
+
+// ExpireLapsed moves distributors whose package term has ended without a
+// renewal to the default package, returning how many were moved
+func (s *packageService) ExpireLapsed() (int, error) {
+	lapsed, err := s.distributorRepo.ListLapsedPackages(time.Now())
+	if err != nil {
+		return 0, err
+	}
+	
+	defaultPackageID := optionalID(s.config.MLM.DefaultPackageID)
+	for i := range lapsed {
+		distributor := &lapsed[i]
+		err := s.transactor.Transaction(func(tx *repository.Tx) error {
+			return s.changePackage(tx, distributor, defaultPackageID, nil, AuditMeta{}, "package lapsed without renewal")
+		})
+		if err != nil {
+			return i, err
+		}
+	}
+	
+	return len(lapsed), nil
+}
+
+// currentPackage returns the paid package a distributor holds, or nil when
+// they only have the default package or theirs has lapsed
+func (s *packageService) currentPackage(distributor *domain.Distributor) *domain.Package {
+	if distributor.Package == nil || distributor.Package.ID == s.config.MLM.DefaultPackageID {
+		return nil
+	}
+	if distributor.PackageExpiresAt != nil && !distributor.PackageExpiresAt.After(time.Now()) {
+		return nil
+	}
+	return distributor.Package
+}
+
+// createOrder replaces any unpaid package order with a new one. Orders with
+// nothing to pay, such as a free package, are paid and applied at once.
+func (s *packageService) createOrder(distributor *domain.Distributor, pkg *domain.Package, orderType string, price float64, meta AuditMeta) (*domain.Order, error) {
+	orderNumber, err := newOrderNumber()
+	if err != nil {
+		return nil, err
+	}
+	
+	order := &domain.Order{
+		OrderNumber:   orderNumber,
+		DistributorID: distributor.ID,
+		Type:          orderType,
+		PackageID:     &pkg.ID,
+		SubTotal:      price,
+		Total:         price,
+		Status:        domain.OrderStatusPending,
+		PaymentStatus: domain.PaymentStatusPending,
+	}
+	if price <= 0 {
+		order.Status = domain.OrderStatusCompleted
+		order.PaymentStatus = domain.PaymentStatusPaid
+	}
+	
+	err = s.transactor.Transaction(func(tx *repository.Tx) error {
+		orderRepo := s.orderRepo.WithTx(tx)
+		if err := orderRepo.CancelPendingPackageOrders(distributor.ID); err != nil {
+			return err
+		}
+		if err := orderRepo.Create(order); err != nil {
+			return err
+		}
+		if err := s.auditRepo.WithTx(tx).Create(newAuditEntry(meta, "order.create", "order", order.ID, nil, order, "")); err != nil {
+			return err
+		}
+		
+		if order.PaymentStatus == domain.PaymentStatusPaid {
+			return s.ApplyPaidOrder(tx, order, meta)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	order.Package = pkg
+	return order, nil
+}
+
+// changePackage sets a distributor's package and term inside tx and audits it
+func (s *packageService) changePackage(tx *repository.Tx, distributor *domain.Distributor, packageID *uint, expiresAt *time.Time, meta AuditMeta, reason string) error {
+	if err := s.distributorRepo.WithTx(tx).UpdatePackage(distributor.ID, packageID, expiresAt); err != nil {
+		return err
+	}
+	
+	return s.auditRepo.WithTx(tx).Create(newAuditEntry(meta, "distributor.package_change", "distributor", distributor.ID,
+		map[string]interface{}{"package_id": distributor.PackageID, "package_expires_at": distributor.PackageExpiresAt},
+		map[string]interface{}{"package_id": packageID, "package_expires_at": expiresAt}, reason))
+}
+
+// validatePackage checks the fields an admin supplies
+func validatePackage(pkg *domain.Package) error {
+	if pkg.Price < 0 {
+		return errors.New("price cannot be negative")
+	}
+	if pkg.CommissionRate < 0 || pkg.CommissionRate > 100 {
+		return errors.New("commission rate must be between 0 and 100")
+	}
+	if pkg.MaxLevels < 1 {
+		return errors.New("max levels must be at least 1")
+	}
+	if pkg.TermMonths < 0 {
+		return errors.New("term months cannot be negative")
+	}
+	
+	seen := make(map[string]bool)
+	for _, feature := range pkg.Features {
+		if seen[feature.Code] {
+			return fmt.Errorf("feature %q is listed twice", feature.Code)
+		}
+		seen[feature.Code] = true
+	}
+	return nil
+}
+
+// termsChanged reports whether an edit affects what members get or pay,
+// which requires a new version
+func termsChanged(current, updated *domain.Package) bool {
+	return current.Price != updated.Price ||
+		current.CommissionRate != updated.CommissionRate ||
+		current.MaxLevels != updated.MaxLevels ||
+		current.TermMonths != updated.TermMonths ||
+		(len(current.Features) > 0 || len(updated.Features) > 0) && !reflect.DeepEqual(current.Features, updated.Features)
+}
+
+// upgradePrice charges for the time left on the current term, which the
+// upgrade keeps: that time is valued at the target's price per term minus
+// the current package's, so renewals paid beyond one term are charged too.
+// A target without a term, or a current package without a running term, is
+// bought for the full price difference.
+func upgradePrice(current, target *domain.Package, expiresAt *time.Time, now time.Time) float64 {
+	price := target.Price - current.Price
+	if expiresAt != nil && expiresAt.After(now) && current.TermMonths > 0 && target.TermMonths > 0 {
+		remaining := expiresAt.Sub(now).Seconds()
+		price = remaining/termLength(*expiresAt, target.TermMonths).Seconds()*target.Price -
+			remaining/termLength(*expiresAt, current.TermMonths).Seconds()*current.Price
+	}
+	return math.Round(math.Max(price, 0)*100) / 100
+}
+
+// termLength is the duration of a term of the given months ending at end
+func termLength(end time.Time, months int) time.Duration {
+	return end.Sub(end.AddDate(0, -months, 0))
+}
+
+// termEnd returns when a term starting at from ends, or nil for packages
+// without a term
+func termEnd(from time.Time, months int) *time.Time {
+	if months <= 0 {
+		return nil
+	}
+	end := from.AddDate(0, months, 0)
+	return &end
+}
+
+// newOrderNumber returns a unique, human-readable order number
+func newOrderNumber() (string, error) {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("ORD-%s-%s", time.Now().Format("20060102"), strings.ToUpper(hex.EncodeToString(buf))), nil
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mlm-app/backend/internal/domain"
+)
+
+func TestUpgradePrice(t *testing.T) {
+	yearly := &domain.Package{Price: 100, TermMonths: 12}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(year int, month time.Month, day, hour int) *time.Time {
+		d := time.Date(year, month, day, hour, 0, 0, 0, time.UTC)
+		return &d
+	}
+
+	tests := []struct {
+		name      string
+		target    *domain.Package
+		expiresAt *time.Time
+		now       time.Time
+		want      float64
+	}{
+		{"full term left", &domain.Package{Price: 300, TermMonths: 12}, at(2026, 1, 1, 0), now, 200},
+		{"half term left", &domain.Package{Price: 300, TermMonths: 12}, at(2026, 1, 1, 0), time.Date(2025, 7, 2, 12, 0, 0, 0, time.UTC), 100},
+		{"renewed a term ahead", &domain.Package{Price: 300, TermMonths: 12}, at(2027, 1, 1, 0), now, 400},
+		{"target without term", &domain.Package{Price: 300}, at(2025, 7, 1, 0), now, 200},
+		{"no expiry", &domain.Package{Price: 300, TermMonths: 12}, nil, now, 200},
+		{"already expired", &domain.Package{Price: 300, TermMonths: 12}, at(2024, 12, 31, 0), now, 200},
+		{"target cheaper per month", &domain.Package{Price: 150, TermMonths: 24}, at(2026, 1, 1, 0), now, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := upgradePrice(yearly, tt.target, tt.expiresAt, tt.now); got != tt.want {
+				t.Errorf("upgradePrice() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTermsChanged(t *testing.T) {
+	current := &domain.Package{
+		Name:           "Starter",
+		Price:          99.99,
+		CommissionRate: 10,
+		MaxLevels:      5,
+		TermMonths:     12,
+		Features:       []domain.PackageFeature{{Code: "training", Label: "Basic training"}},
+	}
+
+	tests := []struct {
+		name string
+		edit func(p *domain.Package)
+		want bool
+	}{
+		{"nothing", func(p *domain.Package) {}, false},
+		{"name and description", func(p *domain.Package) { p.Name = "Starter Plus"; p.Description = "New copy" }, false},
+		{"price", func(p *domain.Package) { p.Price = 89.99 }, true},
+		{"commission rate", func(p *domain.Package) { p.CommissionRate = 12 }, true},
+		{"max levels", func(p *domain.Package) { p.MaxLevels = 6 }, true},
+		{"term", func(p *domain.Package) { p.TermMonths = 0 }, true},
+		{"feature value", func(p *domain.Package) {
+			p.Features = []domain.PackageFeature{{Code: "training", Label: "Basic training", Value: "2h"}}
+		}, true},
+		{"feature removed", func(p *domain.Package) { p.Features = nil }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := *current
+			updated.Features = append([]domain.PackageFeature(nil), current.Features...)
+			tt.edit(&updated)
+			if got := termsChanged(current, &updated); got != tt.want {
+				t.Errorf("termsChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
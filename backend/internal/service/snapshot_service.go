@@ -0,0 +1,264 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mlm-app/backend/internal/domain"
+	"github.com/mlm-app/backend/internal/repository"
+)
+
+// snapshotPeriodLayout formats commission periods, which are calendar months in UTC
+const snapshotPeriodLayout = "2006-01"
+
+type SnapshotService interface {
+	ClosePeriod(period string, actorID uint) (*domain.GenealogySnapshot, error)
+	CloseDuePeriod() (*domain.GenealogySnapshot, error)
+	List() ([]domain.GenealogySnapshot, error)
+	GetTreeAsOf(distributorID uint, depth int, placement bool, asOf time.Time) (*domain.TreeNode, error)
+	GetStats(distributorID uint) (*domain.DistributorStats, error)
+	GetStatsAsOf(distributorID uint, asOf time.Time) (*domain.DistributorStats, error)
+}
+
+type snapshotService struct {
+	snapshotRepo    repository.SnapshotRepository
+	distributorRepo repository.DistributorRepository
+}
+
+func NewSnapshotService(
+	snapshotRepo repository.SnapshotRepository,
+	distributorRepo repository.DistributorRepository,
+) SnapshotService {
+	return &snapshotService{
+		snapshotRepo:    snapshotRepo,
+		distributorRepo: distributorRepo,
+	}
+}
+
+// ClosePeriod snapshots the genealogy for a finished period such as
+// "2026-09". A snapshot captures the tree as it stands now, so only the most
+// recently ended period can be closed, and the time between the period end
+// and the capture is recorded with it. Each period can only be closed once.
+func (s *snapshotService) ClosePeriod(period string, actorID uint) (*domain.GenealogySnapshot, error) {
+	start, err := time.ParseInLocation(snapshotPeriodLayout, period, time.UTC)
+	if err != nil {
+		return nil, errors.New("period must be in YYYY-MM format")
+	}
+	end := start.AddDate(0, 1, 0)
+	
+	now := time.Now()
+	if end.After(now) {
+		return nil, errors.New("period has not ended yet")
+	}
+	if latest := lastEndedPeriod(now); period != latest {
+		return nil, fmt.Errorf("only the most recently ended period (%s) can be closed, since a snapshot captures the current genealogy", latest)
+	}
+	
+	existing, err := s.snapshotRepo.FindByPeriod(period)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.New("period already closed")
+	}
+	
+	snapshot := &domain.GenealogySnapshot{
+		Period:              period,
+		PeriodStart:         start,
+		PeriodEnd:           end,
+		TakenAt:             now,
+		CaptureDelaySeconds: int64(now.Sub(end).Seconds()),
+	}
+	if actorID != 0 {
+		snapshot.TakenBy = &actorID
+	}
+	
+	if err := s.snapshotRepo.Capture(snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// CloseDuePeriod closes the previous month if nobody has yet. It returns nil
+// when there was nothing to close. Months missed while the server was down
+// cannot be captured any more and are logged.
+func (s *snapshotService) CloseDuePeriod() (*domain.GenealogySnapshot, error) {
+	period := lastEndedPeriod(time.Now())
+	
+	existing, err := s.snapshotRepo.FindByPeriod(period)
+	if err != nil || existing != nil {
+		return nil, err
+	}
+	
+	snapshots, err := s.snapshotRepo.List()
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) > 0 {
+		for _, missed := range periodsBetween(snapshots[0].Period, period) {
+			log.Printf("period %s was never snapshotted and can no longer be", missed)
+		}
+	}
+	
+	return s.ClosePeriod(period, 0)
+}
+
+// lastEndedPeriod returns the calendar month before the one containing now
+func lastEndedPeriod(now time.Time) string {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).
+		AddDate(0, -1, 0).
+		Format(snapshotPeriodLayout)
+}
+
+// periodsBetween lists the periods strictly after from and before to
+func periodsBetween(from, to string) []string {
+	start, err := time.ParseInLocation(snapshotPeriodLayout, from, time.UTC)
+	if err != nil {
+		return nil
+	}
+	
+	var periods []string
+	for month := start.AddDate(0, 1, 0); month.Format(snapshotPeriodLayout) < to; month = month.AddDate(0, 1, 0) {
+		periods = append(periods, month.Format(snapshotPeriodLayout))
+	}
+	return periods
+}
+
+// List returns every snapshot, newest period first
+func (s *snapshotService) List() ([]domain.GenealogySnapshot, error) {
+	return s.snapshotRepo.List()
+}
+
+// GetTreeAsOf rebuilds the tree under a distributor from the snapshot of
+// the period containing asOf
+func (s *snapshotService) GetTreeAsOf(distributorID uint, depth int, placement bool, asOf time.Time) (*domain.TreeNode, error) {
+	snapshot, err := s.snapshotRepo.FindCovering(asOf)
+	if err != nil {
+		return nil, err
+	}
+	
+	root, err := s.snapshotRepo.FindEntry(snapshot.ID, distributorID)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Load the subtree one level at a time, then assemble it top-down
+	children := make(map[uint][]domain.GenealogySnapshotEntry)
+	parentIDs := []uint{root.DistributorID}
+	for level := 0; level < depth && len(parentIDs) > 0; level++ {
+		entries, err := s.snapshotRepo.ListChildEntries(snapshot.ID, parentIDs, placement)
+		if err != nil {
+			return nil, err
+		}
+		
+		parentIDs = parentIDs[:0]
+		for _, entry := range entries {
+			parentID := entry.SponsorID
+			if placement {
+				parentID = entry.PlacementID
+			}
+			children[*parentID] = append(children[*parentID], entry)
+			parentIDs = append(parentIDs, entry.DistributorID)
+		}
+	}
+	
+	node := snapshotTreeNode(root, children)
+	return &node, nil
+}
+
+func snapshotTreeNode(entry *domain.GenealogySnapshotEntry, children map[uint][]domain.GenealogySnapshotEntry) domain.TreeNode {
+	node := domain.TreeNode{
+		ID:            entry.DistributorID,
+		DistributorID: entry.DistributorID,
+		Name:          entry.FirstName + " " + entry.LastName,
+		Email:         entry.Email,
+		SponsorID:     entry.SponsorID,
+		PlacementID:   entry.PlacementID,
+		Position:      entry.Position,
+		Level:         entry.Level,
+		TotalSales:    entry.TotalSales,
+		RankName:      entry.RankName,
+		Status:        entry.Status,
+	}
+	
+	for i := range children[entry.DistributorID] {
+		node.Children = append(node.Children, snapshotTreeNode(&children[entry.DistributorID][i], children))
+	}
+	return node
+}
+
+// GetStats returns a distributor's current standing
+func (s *snapshotService) GetStats(distributorID uint) (*domain.DistributorStats, error) {
+	distributor, err := s.distributorRepo.FindByID(distributorID)
+	if err != nil {
+		return nil, err
+	}
+	
+	direct, err := s.distributorRepo.CountDownlines(distributorID)
+	if err != nil {
+		return nil, err
+	}
+	active, err := s.distributorRepo.CountActiveDownlines(distributorID)
+	if err != nil {
+		return nil, err
+	}
+	
+	stats := &domain.DistributorStats{
+		DistributorID:   distributor.ID,
+		Level:           distributor.Level,
+		Status:          distributor.Status,
+		TotalSales:      distributor.TotalSales,
+		PersonalSales:   distributor.PersonalSales,
+		TeamSales:       distributor.TeamSales,
+		LeftLegVolume:   distributor.LeftLegVolume,
+		RightLegVolume:  distributor.RightLegVolume,
+		TotalCommission: distributor.TotalCommission,
+		TotalBonus:      distributor.TotalBonus,
+		DirectDownlines: direct,
+		ActiveDownlines: active,
+	}
+	if distributor.Rank != nil {
+		stats.RankName = distributor.Rank.Name
+	}
+	return stats, nil
+}
+
+// GetStatsAsOf returns a distributor's standing from the snapshot of the
+// period containing asOf
+func (s *snapshotService) GetStatsAsOf(distributorID uint, asOf time.Time) (*domain.DistributorStats, error) {
+	snapshot, err := s.snapshotRepo.FindCovering(asOf)
+	if err != nil {
+		return nil, err
+	}
+	
+	entry, err := s.snapshotRepo.FindEntry(snapshot.ID, distributorID)
+	if err != nil {
+		return nil, err
+	}
+	
+	direct, active, err := s.snapshotRepo.CountChildEntries(snapshot.ID, distributorID)
+	if err != nil {
+		return nil, err
+	}
+	
+	return &domain.DistributorStats{
+		DistributorID:   entry.DistributorID,
+		SnapshotID:      &snapshot.ID,
+		Period:          snapshot.Period,
+		Level:           entry.Level,
+		Status:          entry.Status,
+		RankName:        entry.RankName,
+		TotalSales:      entry.TotalSales,
+		PersonalSales:   entry.PersonalSales,
+		TeamSales:       entry.TeamSales,
+		LeftLegVolume:   entry.LeftLegVolume,
+		RightLegVolume:  entry.RightLegVolume,
+		TotalCommission: entry.TotalCommission,
+		TotalBonus:      entry.TotalBonus,
+		DirectDownlines: direct,
+		ActiveDownlines: active,
+	}, nil
+}
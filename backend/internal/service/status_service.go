@@ -0,0 +1,198 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mlm-app/backend/internal/config"
+	"github.com/mlm-app/backend/internal/domain"
+	"github.com/mlm-app/backend/internal/repository"
+)
+
+// statusTransitions lists where each status may move through StatusService.
+// Termination and reinstatement go through DistributorService because they
+// also move the downline.
+var statusTransitions = map[string]map[string]bool{
+	domain.StatusActive:    {domain.StatusInactive: true, domain.StatusSuspended: true},
+	domain.StatusInactive:  {domain.StatusActive: true, domain.StatusSuspended: true},
+	domain.StatusSuspended: {domain.StatusActive: true},
+}
+
+// adminReasonCodes are the reason codes an admin may give for a suspension
+// or termination
+var adminReasonCodes = map[string]bool{
+	domain.ReasonPolicyViolation: true,
+	domain.ReasonFraud:           true,
+	domain.ReasonChargeback:      true,
+	domain.ReasonCompliance:      true,
+	domain.ReasonRequested:       true,
+	domain.ReasonOther:           true,
+}
+
+type StatusService interface {
+	Suspend(distributorID uint, reasonCode, note string, meta AuditMeta) error
+	Unsuspend(distributorID uint, note string, meta AuditMeta) error
+	RecordPurchase(tx *repository.Tx, distributorID uint, volume float64, meta AuditMeta) error
+	ApplyInactivity() (int, error)
+	History(distributorID uint, offset, limit int) ([]domain.StatusChange, int64, error)
+}
+
+type statusService struct {
+	distributorRepo  repository.DistributorRepository
+	statusChangeRepo repository.StatusChangeRepository
+	auditRepo        repository.AuditRepository
+	transactor       repository.Transactor
+	config           *config.Config
+}
+
+func NewStatusService(
+	distributorRepo repository.DistributorRepository,
+	statusChangeRepo repository.StatusChangeRepository,
+	auditRepo repository.AuditRepository,
+	transactor repository.Transactor,
+	cfg *config.Config,
+) StatusService {
+	return &statusService{
+		distributorRepo:  distributorRepo,
+		statusChangeRepo: statusChangeRepo,
+		auditRepo:        auditRepo,
+		transactor:       transactor,
+		config:           cfg,
+	}
+}
+
+// Suspend blocks an active or inactive distributor. Their tokens stop
+// working on the next request because every request re-checks the status.
+func (s *statusService) Suspend(distributorID uint, reasonCode, note string, meta AuditMeta) error {
+	if !adminReasonCodes[reasonCode] {
+		return fmt.Errorf("unknown reason code %q", reasonCode)
+	}
+	
+	distributor, err := s.distributorRepo.FindByID(distributorID)
+	if err != nil {
+		return err
+	}
+	if distributor.Role == "admin" {
+		return errors.New("admin accounts cannot be suspended")
+	}
+	
+	return s.transactor.Transaction(func(tx *repository.Tx) error {
+		return s.transition(tx, distributor, domain.StatusSuspended, reasonCode, note, meta)
+	})
+}
+
+// Unsuspend lifts a suspension. The distributor comes back as active and
+// the inactivity rule judges them again on its next run.
+func (s *statusService) Unsuspend(distributorID uint, note string, meta AuditMeta) error {
+	distributor, err := s.distributorRepo.FindByID(distributorID)
+	if err != nil {
+		return err
+	}
+	if distributor.Status != domain.StatusSuspended {
+		return errors.New("distributor is not suspended")
+	}
+	
+	return s.transactor.Transaction(func(tx *repository.Tx) error {
+		return s.transition(tx, distributor, domain.StatusActive, domain.ReasonSuspensionLifted, note, meta)
+	})
+}
+
+// RecordPurchase counts a paid purchase towards the activity rule inside
+// the payment's transaction. One with at least the qualifying volume
+// restarts the inactivity window and reactivates an inactive distributor;
+// a suspension is left in place.
+func (s *statusService) RecordPurchase(tx *repository.Tx, distributorID uint, volume float64, meta AuditMeta) error {
+	if volume < s.config.MLM.QualifyingPurchaseVolume {
+		return nil
+	}
+	
+	distributorRepo := s.distributorRepo.WithTx(tx)
+	distributor, err := distributorRepo.FindByID(distributorID)
+	if err != nil {
+		return err
+	}
+	
+	if err := distributorRepo.MarkQualified(distributor.ID, time.Now()); err != nil {
+		return err
+	}
+	if distributor.Status != domain.StatusInactive {
+		return nil
+	}
+	
+	note := fmt.Sprintf("qualifying purchase of %.2f volume", volume)
+	return s.transition(tx, distributor, domain.StatusActive, domain.ReasonPurchase, note, meta)
+}
+
+// ApplyInactivity makes active distributors without a qualifying purchase
+// in the configured window inactive, returning how many were changed. A
+// distributor that fails is skipped so the rest are still processed.
+func (s *statusService) ApplyInactivity() (int, error) {
+	days := s.config.MLM.InactivityDays
+	if days <= 0 {
+		return 0, nil
+	}
+	
+	candidates, err := s.distributorRepo.ListInactiveSince(time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		return 0, err
+	}
+	
+	note := fmt.Sprintf("no qualifying purchase in %d days", days)
+	changed := 0
+	var errs []error
+	for i := range candidates {
+		distributor := &candidates[i]
+		if distributor.ID == s.config.MLM.HouseAccountID {
+			continue
+		}
+		
+		err := s.transactor.Transaction(func(tx *repository.Tx) error {
+			return s.transition(tx, distributor, domain.StatusInactive, domain.ReasonInactivity, note, AuditMeta{})
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("marking distributor %d inactive: %w", distributor.ID, err))
+			continue
+		}
+		changed++
+	}
+	
+	return changed, errors.Join(errs...)
+}
+
+// History returns a distributor's status changes, newest first
+func (s *statusService) History(distributorID uint, offset, limit int) ([]domain.StatusChange, int64, error) {
+	return s.statusChangeRepo.ListByDistributor(distributorID, offset, limit)
+}
+
+// transition moves distributor to status inside tx, recording the history
+// row and audit entry with it
+func (s *statusService) transition(tx *repository.Tx, distributor *domain.Distributor, status, reasonCode, note string, meta AuditMeta) error {
+	if !statusTransitions[distributor.Status][status] {
+		return fmt.Errorf("cannot change status from %s to %s", distributor.Status, status)
+	}
+	
+	if err := s.distributorRepo.WithTx(tx).UpdateStatus(distributor.ID, status, reasonCode); err != nil {
+		return err
+	}
+	if err := s.statusChangeRepo.WithTx(tx).Create(newStatusChange(meta, distributor.ID, distributor.Status, status, reasonCode, note)); err != nil {
+		return err
+	}
+	
+	return s.auditRepo.WithTx(tx).Create(newAuditEntry(meta, "distributor.status_change", "distributor", distributor.ID,
+		map[string]interface{}{"status": distributor.Status},
+		map[string]interface{}{"status": status, "status_reason": reasonCode}, note))
+}
+
+// newStatusChange builds a status history row; the zero meta marks a change
+// made by an automatic rule
+func newStatusChange(meta AuditMeta, distributorID uint, from, to, reasonCode, note string) *domain.StatusChange {
+	return &domain.StatusChange{
+		DistributorID: distributorID,
+		FromStatus:    from,
+		ToStatus:      to,
+		ReasonCode:    reasonCode,
+		Note:          truncate(note, 500),
+		ActorID:       optionalID(meta.ActorID),
+	}
+}
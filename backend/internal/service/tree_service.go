@@ -3,7 +3,10 @@ package service
 import (
 	"errors"
 	"fmt"
+	"math/rand"
+	"time"
 
+	"github.com/mlm-app/backend/internal/config"
 	"github.com/mlm-app/backend/internal/domain"
 	"github.com/mlm-app/backend/internal/repository"
 )
@@ -12,141 +15,361 @@ type TreeService interface {
 	FindAvailablePosition(sponsorID uint, treeType domain.TreeType) (string, error)
 	ValidatePosition(sponsorID uint, treeType domain.TreeType, position string) error
 	GetTreeStructure(distributorID uint, depth int) (*domain.TreeNode, error)
+	GetPlacementTreeStructure(distributorID uint, depth int) (*domain.TreeNode, error)
+	GetChildren(parentID uint, placement bool, cursor uint, limit int) ([]domain.TreeNode, *uint, error)
+	GetPathToNode(rootID, targetID uint, placement bool) ([]domain.TreeNode, error)
+	FindPlacement(sponsorID uint, treeType domain.TreeType) (*uint, string, error)
+	PlaceMember(member *domain.Distributor) error
+	GetHoldingTank(sponsorID uint) ([]domain.Distributor, error)
+	PlaceFromHoldingTank(memberID, sponsorID uint, placementID *uint, position string, meta AuditMeta) error
+	AutoPlaceExpired() (int, error)
+	IsInDownline(distributorID, ancestorID uint) (bool, error)
+	SearchDownline(rootID uint, filter domain.DownlineFilter) ([]domain.DownlineMember, int64, error)
+	AddLegVolume(distributorID uint, amount float64) error
+	AddLegVolumeInTx(tx *repository.Tx, distributorID uint, amount float64) error
 	CalculateLevel(sponsorID uint) (int, error)
 	GetUplineChain(distributorID uint, levels int) ([]domain.Distributor, error)
+	CalculateGroupVolume(distributorID uint) (float64, error)
+	CheckBreakaway(distributorID uint) (*domain.BreakawayEvent, error)
+	CheckBreakawayInTx(tx *repository.Tx, distributorID uint) (*domain.BreakawayEvent, error)
+	GetBreakaways(sponsorID uint) ([]domain.BreakawayEvent, error)
+	MoveMember(memberID, newSponsorID uint, position string, withSubtree bool, meta AuditMeta, reason string) error
+	MoveMemberInTx(tx *repository.Tx, memberID, newSponsorID uint, position string, withSubtree bool, meta AuditMeta, reason string) error
+	MoveToSlotInTx(tx *repository.Tx, memberID, sponsorID uint, placementID *uint, position string, meta AuditMeta, reason string) error
+	VacateSlotInTx(tx *repository.Tx, memberID uint) error
+	ReclaimSlotInTx(tx *repository.Tx, memberID, placementID uint, position string) error
 }
 
 type treeService struct {
 	distributorRepo repository.DistributorRepository
+	breakawayRepo   repository.BreakawayRepository
+	auditRepo       repository.AuditRepository
+	transactor      repository.Transactor
+	config          *config.Config
 }
 
-func NewTreeService(distributorRepo repository.DistributorRepository) TreeService {
+func NewTreeService(
+	distributorRepo repository.DistributorRepository,
+	breakawayRepo repository.BreakawayRepository,
+	auditRepo repository.AuditRepository,
+	transactor repository.Transactor,
+	cfg *config.Config,
+) TreeService {
 	return &treeService{
 		distributorRepo: distributorRepo,
+		breakawayRepo:   breakawayRepo,
+		auditRepo:       auditRepo,
+		transactor:      transactor,
+		config:          cfg,
 	}
 }
 
+// withTx returns a copy of the service whose reads and writes all go
+// through tx, so a multi-step tree change commits or rolls back as a whole
+func (s *treeService) withTx(tx *repository.Tx) *treeService {
+	return &treeService{
+		distributorRepo: s.distributorRepo.WithTx(tx),
+		breakawayRepo:   s.breakawayRepo.WithTx(tx),
+		auditRepo:       s.auditRepo.WithTx(tx),
+		transactor:      s.transactor,
+		config:          s.config,
+	}
+}
+
+// placementSnapshot is the part of a distributor a tree move can change
+type placementSnapshot struct {
+	SponsorID      *uint   `json:"sponsor_id"`
+	PlacementID    *uint   `json:"placement_id"`
+	Position       string  `json:"position"`
+	Level          int     `json:"level"`
+	LeftLegVolume  float64 `json:"left_leg_volume"`
+	RightLegVolume float64 `json:"right_leg_volume"`
+}
+
+// maxPlacementAttempts bounds the retries when a concurrent registration
+// claims the slot we picked
+const maxPlacementAttempts = 10
+
 // FindAvailablePosition finds the next available position in the tree
 func (s *treeService) FindAvailablePosition(sponsorID uint, treeType domain.TreeType) (string, error) {
+	_, position, err := s.FindPlacement(sponsorID, treeType)
+	return position, err
+}
+
+// FindPlacement finds the next available slot for a new member of sponsorID.
+// Binary, matrix and hybrid trees return the placement parent the slot
+// belongs to, which differs from the sponsor after spillover; unilevel and
+// breakaway trees place directly under the sponsor and return nil.
+func (s *treeService) FindPlacement(sponsorID uint, treeType domain.TreeType) (*uint, string, error) {
 	switch treeType {
-	case domain.TreeTypeBinary:
-		return s.findBinaryPosition(sponsorID)
+	case domain.TreeTypeBinary, domain.TreeTypeHybrid:
+		return s.findSlot(sponsorID, []string{"left", "right"})
 	case domain.TreeTypeMatrix:
-		return s.findMatrixPosition(sponsorID)
+		return s.findSlot(sponsorID, s.matrixPositions())
 	case domain.TreeTypeUnilevel:
-		return "direct", nil // Unilevel has no position restrictions
+		return nil, "direct", nil // Unilevel has no position restrictions
 	case domain.TreeTypeBreakaway:
-		return s.findBreakawayPosition(sponsorID)
-	case domain.TreeTypeHybrid:
-		return s.findHybridPosition(sponsorID)
+		position, err := s.findBreakawayPosition(sponsorID)
+		return nil, position, err
 	default:
-		return "", errors.New("invalid tree type")
+		return nil, "", errors.New("invalid tree type")
+	}
+}
+
+// findSlot searches the placement tree under rootID breadth-first, so
+// spillover fills the shallowest level first, and returns the first free
+// position
+func (s *treeService) findSlot(rootID uint, positions []string) (*uint, string, error) {
+	queue := []uint{rootID}
+	
+	for len(queue) > 0 {
+		parentID := queue[0]
+		queue = queue[1:]
+		
+		children, err := s.distributorRepo.GetPlacementChildren(parentID)
+		if err != nil {
+			return nil, "", err
+		}
+		
+		taken := make(map[string]bool)
+		for _, child := range children {
+			taken[child.Position] = true
+		}
+		
+		for _, position := range positions {
+			if !taken[position] {
+				return &parentID, position, nil
+			}
+		}
+		
+		for _, child := range children {
+			queue = append(queue, child.ID)
+		}
+	}
+	
+	return nil, "", errors.New("no available positions in placement tree")
+}
+
+// matrixPositions lists the slot names of a matrix level, "pos_1" to "pos_<width>"
+func (s *treeService) matrixPositions() []string {
+	positions := make([]string, s.config.MLM.MatrixWidth)
+	for i := range positions {
+		positions[i] = fmt.Sprintf("pos_%d", i+1)
 	}
+	return positions
 }
 
-// findBinaryPosition finds available position in binary tree (left or right)
-func (s *treeService) findBinaryPosition(sponsorID uint) (string, error) {
-	// Check if left position is available
-	leftDist, err := s.distributorRepo.GetByTreeTypeAndPosition(sponsorID, domain.TreeTypeBinary, "left")
+// findBreakawayPosition finds position in breakaway tree
+func (s *treeService) findBreakawayPosition(sponsorID uint) (string, error) {
+	// Breakaway is similar to unilevel until breakaway occurs
+	downlines, err := s.distributorRepo.GetDownlines(sponsorID)
 	if err != nil {
 		return "", err
 	}
-	if leftDist == nil {
-		return "left", nil
+	
+	return fmt.Sprintf("pos_%d", len(downlines)+1), nil
+}
+
+// ValidatePosition validates if a position is valid for the tree type.
+// For binary, matrix and hybrid trees parentID is the placement parent.
+func (s *treeService) ValidatePosition(parentID uint, treeType domain.TreeType, position string) error {
+	switch treeType {
+	case domain.TreeTypeBinary, domain.TreeTypeHybrid:
+		if position != "left" && position != "right" {
+			return fmt.Errorf("%s tree only supports 'left' or 'right' positions", treeType)
+		}
+	case domain.TreeTypeMatrix:
+		// Position should be like "pos_1", "pos_2", etc.
+		valid := false
+		for _, allowed := range s.matrixPositions() {
+			valid = valid || position == allowed
+		}
+		if !valid {
+			return fmt.Errorf("matrix tree only supports positions pos_1 to pos_%d", s.config.MLM.MatrixWidth)
+		}
+	case domain.TreeTypeUnilevel:
+		// Unilevel has no position restrictions
+		return nil
+	case domain.TreeTypeBreakaway:
+		// Similar to unilevel
+		return nil
 	}
 	
-	// Check if right position is available
-	rightDist, err := s.distributorRepo.GetByTreeTypeAndPosition(sponsorID, domain.TreeTypeBinary, "right")
+	// Check if position is already taken
+	existing, err := s.distributorRepo.GetByPlacementAndPosition(parentID, position)
 	if err != nil {
-		return "", err
+		return err
 	}
-	if rightDist == nil {
-		return "right", nil
+	if existing != nil {
+		return errors.New("position already occupied")
 	}
 	
-	// Both positions filled, need to find position in downline (spillover)
-	// Try left leg first
-	position, err := s.findBinaryPosition(leftDist.ID)
-	if err == nil {
-		return position, nil
+	return nil
+}
+
+// PlaceMember assigns a slot to a new member under member.SponsorID and
+// creates them. The unique (placement_id, position) index is the real
+// guard: if a concurrent registration takes the slot between the lookup and
+// the insert, an auto-placed member is retried on the next free slot, while
+// an explicitly requested position fails as occupied.
+func (s *treeService) PlaceMember(member *domain.Distributor) error {
+	// Roots and holding tank members have no slot yet
+	if member.SponsorID == nil || member.PlacementStatus == "pending" {
+		member.PlacementID = nil
+		member.Position = ""
+		return s.distributorRepo.Create(member)
 	}
 	
-	// Try right leg
-	return s.findBinaryPosition(rightDist.ID)
+	requested := member.Position
+	for attempt := 0; attempt < maxPlacementAttempts; attempt++ {
+		if requested == "" {
+			placementID, position, err := s.FindPlacement(*member.SponsorID, member.TreeType)
+			if err != nil {
+				return err
+			}
+			member.PlacementID = placementID
+			member.Position = position
+		} else {
+			if isConstrained(member.TreeType) {
+				member.PlacementID = member.SponsorID
+			}
+			if err := s.ValidatePosition(*member.SponsorID, member.TreeType, requested); err != nil {
+				return err
+			}
+		}
+		
+		err := s.distributorRepo.Create(member)
+		if !errors.Is(err, repository.ErrPositionTaken) {
+			return err
+		}
+		if requested != "" {
+			return errors.New("position already occupied")
+		}
+		
+		// Back off with jitter so racing registrations spread out
+		time.Sleep(time.Duration(rand.Intn(10*(attempt+1))) * time.Millisecond)
+	}
+	
+	return errors.New("could not find a free position, please retry")
 }
 
-// findMatrixPosition finds available position in matrix tree
-func (s *treeService) findMatrixPosition(sponsorID uint) (string, error) {
-	// Get all direct downlines
-	downlines, err := s.distributorRepo.GetDownlines(sponsorID)
+// GetHoldingTank lists a sponsor's enrollees still waiting to be placed
+func (s *treeService) GetHoldingTank(sponsorID uint) ([]domain.Distributor, error) {
+	return s.distributorRepo.ListHoldingTank(sponsorID)
+}
+
+// PlaceFromHoldingTank lets a sponsor place one of their holding tank
+// members anywhere in their own placement tree. A nil placementID places
+// under the sponsor; an empty position takes the next free slot below it.
+func (s *treeService) PlaceFromHoldingTank(memberID, sponsorID uint, placementID *uint, position string, meta AuditMeta) error {
+	member, err := s.distributorRepo.FindByID(memberID)
 	if err != nil {
-		return "", err
+		return err
 	}
 	
-	// Matrix typically has a width limit (e.g., 3x9 means 3 width)
-	// For simplicity, we'll use position as "pos_1", "pos_2", "pos_3"
-	matrixWidth := 3 // This should come from config
-	
-	if len(downlines) < matrixWidth {
-		return fmt.Sprintf("pos_%d", len(downlines)+1), nil
+	if member.PlacementStatus != "pending" {
+		return errors.New("distributor is not in a holding tank")
+	}
+	if member.SponsorID == nil || *member.SponsorID != sponsorID {
+		return errors.New("distributor is not in your holding tank")
 	}
 	
-	// All positions filled at this level, find in downline
-	for _, downline := range downlines {
-		position, err := s.findMatrixPosition(downline.ID)
-		if err == nil {
-			return position, nil
+	parentID := sponsorID
+	if placementID != nil && *placementID != sponsorID {
+		inDownline, err := s.isDescendant(*placementID, sponsorID)
+		if err != nil {
+			return err
+		}
+		if !inDownline {
+			return errors.New("placement parent must be in your own downline")
 		}
+		parentID = *placementID
 	}
 	
-	return "", errors.New("no available positions in matrix")
+	return s.placeExisting(member, parentID, position, meta)
 }
 
-// findBreakawayPosition finds position in breakaway tree
-func (s *treeService) findBreakawayPosition(sponsorID uint) (string, error) {
-	// Breakaway is similar to unilevel until breakaway occurs
-	downlines, err := s.distributorRepo.GetDownlines(sponsorID)
+// AutoPlaceExpired places every holding tank member whose window has passed
+// using the default FindPlacement strategy under their sponsor. A member
+// that cannot be placed does not hold up the rest; the failures are
+// returned together with the number placed.
+func (s *treeService) AutoPlaceExpired() (int, error) {
+	expired, err := s.distributorRepo.ListExpiredHoldingTank(time.Now())
 	if err != nil {
-		return "", err
+		return 0, err
 	}
 	
-	return fmt.Sprintf("pos_%d", len(downlines)+1), nil
-}
-
-// findHybridPosition finds position in hybrid tree
-func (s *treeService) findHybridPosition(sponsorID uint) (string, error) {
-	// Hybrid combines multiple tree types
-	// For this implementation, we'll use binary as primary
-	return s.findBinaryPosition(sponsorID)
+	placed := 0
+	var errs []error
+	for i := range expired {
+		member := &expired[i]
+		if member.SponsorID == nil {
+			continue
+		}
+		if err := s.placeExisting(member, *member.SponsorID, "", AuditMeta{}); err != nil {
+			errs = append(errs, fmt.Errorf("auto-placing distributor %d: %w", member.ID, err))
+			continue
+		}
+		placed++
+	}
+	
+	return placed, errors.Join(errs...)
 }
 
-// ValidatePosition validates if a position is valid for the tree type
-func (s *treeService) ValidatePosition(sponsorID uint, treeType domain.TreeType, position string) error {
-	switch treeType {
-	case domain.TreeTypeBinary:
-		if position != "left" && position != "right" {
-			return errors.New("binary tree only supports 'left' or 'right' positions")
+// placeExisting moves an already-created holding tank member into a slot
+// under parentID, retrying auto-placement if a concurrent placement wins.
+// Each attempt writes the slot, the upline leg volume and the audit entry
+// in one transaction; the zero meta marks an automatic placement.
+func (s *treeService) placeExisting(member *domain.Distributor, parentID uint, position string, meta AuditMeta) error {
+	for attempt := 0; attempt < maxPlacementAttempts; attempt++ {
+		placementID, slot := &parentID, position
+		if position == "" {
+			var err error
+			placementID, slot, err = s.FindPlacement(parentID, member.TreeType)
+			if err != nil {
+				return err
+			}
+		} else if err := s.ValidatePosition(parentID, member.TreeType, position); err != nil {
+			return err
 		}
-		// Check if position is already taken
-		existing, err := s.distributorRepo.GetByTreeTypeAndPosition(sponsorID, treeType, position)
-		if err != nil {
+		
+		err := s.transactor.Transaction(func(tx *repository.Tx) error {
+			t := s.withTx(tx)
+			if err := t.distributorRepo.UpdatePlacement(member.ID, member.SponsorID, placementID, slot, member.Level); err != nil {
+				return err
+			}
+			
+			placed, err := t.distributorRepo.FindByID(member.ID)
+			if err != nil {
+				return err
+			}
+			if err := t.AddLegVolume(placed.ID, subtreeVolume(placed)); err != nil {
+				return err
+			}
+			return t.auditRepo.Create(newAuditEntry(meta, "tree.place", "distributor", member.ID,
+				snapshotPlacement(member), snapshotPlacement(placed), ""))
+		})
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, repository.ErrPositionTaken) {
 			return err
 		}
-		if existing != nil {
+		if position != "" {
 			return errors.New("position already occupied")
 		}
-	case domain.TreeTypeMatrix:
-		// Validate matrix position format
-		// Position should be like "pos_1", "pos_2", etc.
-	case domain.TreeTypeUnilevel:
-		// Unilevel has no position restrictions
-	case domain.TreeTypeBreakaway:
-		// Similar to unilevel
-	case domain.TreeTypeHybrid:
-		// Validate based on primary tree type
 	}
 	
-	return nil
+	return errors.New("could not find a free position, please retry")
+}
+
+// isConstrained reports whether a tree type limits the slots under a parent
+func isConstrained(treeType domain.TreeType) bool {
+	switch treeType {
+	case domain.TreeTypeBinary, domain.TreeTypeMatrix, domain.TreeTypeHybrid:
+		return true
+	}
+	return false
 }
 
 // GetTreeStructure retrieves the tree structure starting from a distributor
@@ -154,6 +377,95 @@ func (s *treeService) GetTreeStructure(distributorID uint, depth int) (*domain.T
 	return s.distributorRepo.GetTreeStructure(distributorID, depth)
 }
 
+// GetPlacementTreeStructure retrieves the binary placement tree used by hybrid plans
+func (s *treeService) GetPlacementTreeStructure(distributorID uint, depth int) (*domain.TreeNode, error) {
+	return s.distributorRepo.GetPlacementTreeStructure(distributorID, depth)
+}
+
+// maxChildrenPageSize caps how many children one lazy-loading page returns
+const maxChildrenPageSize = 200
+
+// GetChildren returns one page of a node's direct children. cursor is the
+// ID of the last child already seen (0 for the first page); the returned
+// cursor is nil once there are no more children.
+func (s *treeService) GetChildren(parentID uint, placement bool, cursor uint, limit int) ([]domain.TreeNode, *uint, error) {
+	if limit <= 0 || limit > maxChildrenPageSize {
+		limit = maxChildrenPageSize
+	}
+	
+	// Fetch one extra row to learn whether another page follows
+	children, err := s.distributorRepo.GetChildrenPage(parentID, placement, cursor, limit+1)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(children) <= limit {
+		return children, nil, nil
+	}
+	
+	children = children[:limit]
+	next := children[limit-1].ID
+	return children, &next, nil
+}
+
+// GetPathToNode returns the nodes from rootID down to targetID so a client
+// can expand straight to a deep member. rootID 0 starts at the top of the tree.
+func (s *treeService) GetPathToNode(rootID, targetID uint, placement bool) ([]domain.TreeNode, error) {
+	return s.distributorRepo.GetPathToNode(rootID, targetID, placement)
+}
+
+// AddLegVolume credits volume to the left or right leg of every ancestor in
+// the binary placement tree. Binary rows created before placement_id existed
+// fall back to their sponsor. Matrix and unilevel parents have no legs, so
+// the walk stops at the first link that is not a binary left or right slot;
+// that keeps each node's legs equal to the volume placed below it.
+// Terminated parents are walked through, so a member still placed under one
+// can be moved out.
+func (s *treeService) AddLegVolume(distributorID uint, amount float64) error {
+	current, err := s.distributorRepo.FindByID(distributorID)
+	if err != nil {
+		return err
+	}
+	
+	for {
+		// Holding tank members are outside the placement tree until placed
+		if current.PlacementStatus == "pending" {
+			return nil
+		}
+		if current.Position != "left" && current.Position != "right" {
+			return nil
+		}
+		
+		parentID := current.PlacementID
+		if parentID == nil {
+			if current.TreeType != domain.TreeTypeBinary {
+				return nil
+			}
+			parentID = current.SponsorID
+		}
+		if parentID == nil {
+			return nil
+		}
+		
+		parent, err := s.distributorRepo.FindByIDWithDeleted(*parentID)
+		if err != nil {
+			return err
+		}
+		if parent.TreeType != domain.TreeTypeBinary && parent.TreeType != domain.TreeTypeHybrid {
+			return nil
+		}
+		
+		if err := s.distributorRepo.UpdateLegVolume(parent.ID, current.Position, amount); err != nil {
+			return err
+		}
+		current = parent
+	}
+}
+
+// AddLegVolumeInTx credits leg volume inside the caller's transaction
+func (s *treeService) AddLegVolumeInTx(tx *repository.Tx, distributorID uint, amount float64) error {
+	return s.withTx(tx).AddLegVolume(distributorID, amount)
+}
+
 // CalculateLevel calculates the level of a distributor in the tree
 func (s *treeService) CalculateLevel(sponsorID uint) (int, error) {
 	if sponsorID == 0 {
@@ -172,25 +484,502 @@ func (s *treeService) CalculateLevel(sponsorID uint) (int, error) {
 func (s *treeService) GetUplineChain(distributorID uint, levels int) ([]domain.Distributor, error) {
 	var upline []domain.Distributor
 	currentID := distributorID
+	visited := map[uint]bool{distributorID: true}
 	
-	for i := 0; i < levels; i++ {
-		distributor, err := s.distributorRepo.FindByID(currentID)
+	for len(upline) < levels {
+		distributor, err := s.distributorRepo.FindByIDWithDeleted(currentID)
 		if err != nil {
 			break
 		}
 		
-		if distributor.SponsorID == nil {
+		if distributor.SponsorID == nil || visited[*distributor.SponsorID] {
 			break
 		}
 		
-		sponsor, err := s.distributorRepo.FindByID(*distributor.SponsorID)
+		sponsor, err := s.distributorRepo.FindByIDWithDeleted(*distributor.SponsorID)
 		if err != nil {
 			break
 		}
+		visited[sponsor.ID] = true
+		currentID = sponsor.ID
+		
+		// Terminated sponsors are passed through rather than ending the chain
+		if sponsor.DeletedAt.Valid {
+			continue
+		}
 		
 		upline = append(upline, *sponsor)
-		currentID = sponsor.ID
 	}
 	
 	return upline, nil
 }
+
+// CalculateGroupVolume sums personal sales across a distributor's group.
+// Downlines that have broken away take their whole group with them.
+func (s *treeService) CalculateGroupVolume(distributorID uint) (float64, error) {
+	distributor, err := s.distributorRepo.FindByID(distributorID)
+	if err != nil {
+		return 0, err
+	}
+	
+	return s.groupVolume(distributor.ID, distributor.PersonalSales)
+}
+
+func (s *treeService) groupVolume(distributorID uint, personalSales float64) (float64, error) {
+	total := personalSales
+	
+	downlines, err := s.distributorRepo.GetDownlines(distributorID)
+	if err != nil {
+		return 0, err
+	}
+	
+	for _, downline := range downlines {
+		if downline.BrokeAwayAt != nil {
+			continue
+		}
+		
+		volume, err := s.groupVolume(downline.ID, downline.PersonalSales)
+		if err != nil {
+			return 0, err
+		}
+		total += volume
+	}
+	
+	return total, nil
+}
+
+// CheckBreakaway splits a distributor's group off from their sponsor's group
+// once they reach the configured breakaway rank. It returns the recorded
+// event, or nil if no breakaway happened.
+func (s *treeService) CheckBreakaway(distributorID uint) (*domain.BreakawayEvent, error) {
+	var event *domain.BreakawayEvent
+	err := s.transactor.Transaction(func(tx *repository.Tx) error {
+		var err error
+		event, err = s.CheckBreakawayInTx(tx, distributorID)
+		return err
+	})
+	return event, err
+}
+
+// CheckBreakawayInTx is CheckBreakaway inside a caller's transaction, so a
+// rank change and the breakaway it triggers commit together
+func (s *treeService) CheckBreakawayInTx(tx *repository.Tx, distributorID uint) (*domain.BreakawayEvent, error) {
+	return s.withTx(tx).checkBreakaway(distributorID)
+}
+
+func (s *treeService) checkBreakaway(distributorID uint) (*domain.BreakawayEvent, error) {
+	distributor, err := s.distributorRepo.FindByID(distributorID)
+	if err != nil {
+		return nil, err
+	}
+	
+	if distributor.TreeType != domain.TreeTypeBreakaway || distributor.BrokeAwayAt != nil {
+		return nil, nil
+	}
+	if distributor.SponsorID == nil || distributor.Rank == nil {
+		return nil, nil
+	}
+	if distributor.Rank.Level < s.config.MLM.BreakawayRankLevel {
+		return nil, nil
+	}
+	
+	groupVolume, err := s.CalculateGroupVolume(distributor.ID)
+	if err != nil {
+		return nil, err
+	}
+	
+	now := time.Now()
+	distributor.BrokeAwayAt = &now
+	if err := s.distributorRepo.Update(distributor); err != nil {
+		return nil, err
+	}
+	
+	event := &domain.BreakawayEvent{
+		DistributorID: distributor.ID,
+		SponsorID:     *distributor.SponsorID,
+		RankID:        distributor.Rank.ID,
+		GroupVolume:   groupVolume,
+		BrokeAwayAt:   now,
+	}
+	if err := s.breakawayRepo.Create(event); err != nil {
+		return nil, err
+	}
+	
+	// No upline's group volume includes the broken-away group any more. An
+	// upline that broke away itself already left the groups above it, so
+	// the walk stops there.
+	upline, err := s.GetUplineChain(distributor.ID, distributor.Level)
+	if err != nil {
+		return nil, err
+	}
+	for i := range upline {
+		ancestor := &upline[i]
+		ancestorVolume, err := s.groupVolume(ancestor.ID, ancestor.PersonalSales)
+		if err != nil {
+			return nil, err
+		}
+		ancestor.TeamSales = ancestorVolume - ancestor.PersonalSales
+		if err := s.distributorRepo.Update(ancestor); err != nil {
+			return nil, err
+		}
+		if ancestor.BrokeAwayAt != nil {
+			break
+		}
+	}
+	
+	return event, nil
+}
+
+// GetBreakaways lists the groups that have broken away from a sponsor
+func (s *treeService) GetBreakaways(sponsorID uint) ([]domain.BreakawayEvent, error) {
+	return s.breakawayRepo.ListBySponsor(sponsorID)
+}
+
+// MoveMember moves a distributor to a new sponsor and position. With
+// withSubtree the whole downline moves along; otherwise the member's direct
+// children roll up to the member's old sponsor. Levels and leg volumes are
+// recomputed and the before/after placement is written to the audit log,
+// all in one transaction.
+func (s *treeService) MoveMember(memberID, newSponsorID uint, position string, withSubtree bool, meta AuditMeta, reason string) error {
+	return s.transactor.Transaction(func(tx *repository.Tx) error {
+		return s.MoveMemberInTx(tx, memberID, newSponsorID, position, withSubtree, meta, reason)
+	})
+}
+
+// MoveMemberInTx is MoveMember inside a caller's transaction, for changes
+// such as a termination that move several members as one unit
+func (s *treeService) MoveMemberInTx(tx *repository.Tx, memberID, newSponsorID uint, position string, withSubtree bool, meta AuditMeta, reason string) error {
+	return s.withTx(tx).moveMember(memberID, newSponsorID, nil, position, withSubtree, meta, reason)
+}
+
+// MoveToSlotInTx moves a member and its subtree to sponsorID, into position
+// under placementID rather than under the sponsor. An empty position takes
+// the first free slot below placementID; a nil placementID picks the slot
+// under the sponsor as MoveMember does. Terminations use it to re-place
+// spillover children and reinstatements to put members back where they were.
+func (s *treeService) MoveToSlotInTx(tx *repository.Tx, memberID, sponsorID uint, placementID *uint, position string, meta AuditMeta, reason string) error {
+	return s.withTx(tx).moveMember(memberID, sponsorID, placementID, position, true, meta, reason)
+}
+
+func (s *treeService) moveMember(memberID, newSponsorID uint, placementParentID *uint, position string, withSubtree bool, meta AuditMeta, reason string) error {
+	member, err := s.distributorRepo.FindByID(memberID)
+	if err != nil {
+		return err
+	}
+	
+	newSponsor, err := s.distributorRepo.FindByID(newSponsorID)
+	if err != nil {
+		return errors.New("invalid sponsor ID")
+	}
+	
+	if member.ID == newSponsor.ID {
+		return errors.New("a distributor cannot sponsor themselves")
+	}
+	
+	// The target must not sit in the member's own downline, or the move
+	// would create a cycle
+	descendant, err := s.isDescendant(newSponsor.ID, member.ID)
+	if err != nil {
+		return err
+	}
+	if descendant {
+		return errors.New("target sponsor is in the member's downline")
+	}
+	if placementParentID != nil {
+		if *placementParentID == member.ID {
+			return errors.New("a distributor cannot be placed under themselves")
+		}
+		descendant, err := s.isDescendant(*placementParentID, member.ID)
+		if err != nil {
+			return err
+		}
+		if descendant {
+			return errors.New("placement parent is in the member's downline")
+		}
+	}
+	
+	var detached []domain.Distributor
+	if !withSubtree {
+		if member.SponsorID == nil {
+			return errors.New("cannot detach the downline of a root distributor")
+		}
+		if member.TreeType == domain.TreeTypeHybrid {
+			detached, err = s.distributorRepo.GetPlacementChildren(member.ID)
+		} else {
+			detached, err = s.distributorRepo.GetDownlines(member.ID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	
+	before := map[uint]placementSnapshot{member.ID: snapshotPlacement(member)}
+	for i := range detached {
+		before[detached[i].ID] = snapshotPlacement(&detached[i])
+	}
+	
+	// Take the moving volume out of the old placement path. Children go
+	// first so the member's own legs are already reduced when it is pulled.
+	for _, child := range detached {
+		if err := s.AddLegVolume(child.ID, -subtreeVolume(&child)); err != nil {
+			return err
+		}
+	}
+	member, err = s.distributorRepo.FindByID(member.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.AddLegVolume(member.ID, -subtreeVolume(member)); err != nil {
+		return err
+	}
+	
+	// Roll detached children up to the member's old sponsor
+	for _, child := range detached {
+		if err := s.rollUpChild(member, &child); err != nil {
+			return err
+		}
+	}
+	
+	// Place the member under the new sponsor, or the requested placement parent
+	var placementID *uint
+	if placementParentID == nil {
+		placementID, position, err = s.resolvePlacement(newSponsor, position)
+	} else {
+		placementID, position, err = s.resolveSlot(*placementParentID, newSponsor.TreeType, position)
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.distributorRepo.UpdatePlacement(member.ID, &newSponsor.ID, placementID, position, newSponsor.Level+1); err != nil {
+		return err
+	}
+	if err := s.recomputeLevels(member.ID, newSponsor.Level+1); err != nil {
+		return err
+	}
+	
+	member, err = s.distributorRepo.FindByID(member.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.AddLegVolume(member.ID, subtreeVolume(member)); err != nil {
+		return err
+	}
+	
+	after := make(map[uint]placementSnapshot)
+	for id := range before {
+		moved, err := s.distributorRepo.FindByID(id)
+		if err != nil {
+			return err
+		}
+		after[id] = snapshotPlacement(moved)
+	}
+	
+	return s.auditRepo.Create(newAuditEntry(meta, "tree.move", "distributor", member.ID, before, after, reason))
+}
+
+// VacateSlotInTx frees the slot of a member leaving the placement tree, so
+// spillover can reuse it, and takes the member's remaining volume out of the
+// uplines' legs. Members without a placement parent hold no slot.
+func (s *treeService) VacateSlotInTx(tx *repository.Tx, memberID uint) error {
+	t := s.withTx(tx)
+	member, err := t.distributorRepo.FindByID(memberID)
+	if err != nil {
+		return err
+	}
+	if member.PlacementID == nil {
+		return nil
+	}
+	
+	if err := t.AddLegVolume(member.ID, -subtreeVolume(member)); err != nil {
+		return err
+	}
+	return t.distributorRepo.UpdatePlacement(member.ID, member.SponsorID, nil, "", member.Level)
+}
+
+// ReclaimSlotInTx puts a returning member into position under placementID,
+// or the first free slot below it when position is empty, and credits the
+// member's volume to the new uplines
+func (s *treeService) ReclaimSlotInTx(tx *repository.Tx, memberID, placementID uint, position string) error {
+	t := s.withTx(tx)
+	member, err := t.distributorRepo.FindByID(memberID)
+	if err != nil {
+		return err
+	}
+	
+	slotID, position, err := t.resolveSlot(placementID, member.TreeType, position)
+	if err != nil {
+		return err
+	}
+	if err := t.distributorRepo.UpdatePlacement(member.ID, member.SponsorID, slotID, position, member.Level); err != nil {
+		return err
+	}
+	
+	member, err = t.distributorRepo.FindByID(member.ID)
+	if err != nil {
+		return err
+	}
+	return t.AddLegVolume(member.ID, subtreeVolume(member))
+}
+
+// rollUpChild re-attaches a child of a moving member to the member's old
+// sponsor. Hybrid children only change placement; their enrollment is kept.
+func (s *treeService) rollUpChild(member, child *domain.Distributor) error {
+	if member.TreeType == domain.TreeTypeHybrid {
+		parentID := *member.SponsorID
+		if member.PlacementID != nil {
+			parentID = *member.PlacementID
+		}
+		placementID, position, err := s.FindPlacement(parentID, domain.TreeTypeHybrid)
+		if err != nil {
+			return err
+		}
+		if err := s.distributorRepo.UpdatePlacement(child.ID, child.SponsorID, placementID, position, child.Level); err != nil {
+			return err
+		}
+	} else {
+		oldSponsor, err := s.distributorRepo.FindByID(*member.SponsorID)
+		if err != nil {
+			return err
+		}
+		placementID, position, err := s.FindPlacement(oldSponsor.ID, child.TreeType)
+		if err != nil {
+			return err
+		}
+		if err := s.distributorRepo.UpdatePlacement(child.ID, &oldSponsor.ID, placementID, position, oldSponsor.Level+1); err != nil {
+			return err
+		}
+		if err := s.recomputeLevels(child.ID, oldSponsor.Level+1); err != nil {
+			return err
+		}
+	}
+	
+	moved, err := s.distributorRepo.FindByID(child.ID)
+	if err != nil {
+		return err
+	}
+	return s.AddLegVolume(moved.ID, subtreeVolume(moved))
+}
+
+// resolvePlacement picks or validates the slot for a member joining sponsor
+func (s *treeService) resolvePlacement(sponsor *domain.Distributor, position string) (*uint, string, error) {
+	if position == "" {
+		return s.FindPlacement(sponsor.ID, sponsor.TreeType)
+	}
+	
+	var placementID *uint
+	if isConstrained(sponsor.TreeType) {
+		placementID = &sponsor.ID
+	}
+	return placementID, position, s.ValidatePosition(sponsor.ID, sponsor.TreeType, position)
+}
+
+// resolveSlot picks or validates a slot under an explicit placement parent
+func (s *treeService) resolveSlot(parentID uint, treeType domain.TreeType, position string) (*uint, string, error) {
+	if position == "" {
+		return s.FindPlacement(parentID, treeType)
+	}
+	return &parentID, position, s.ValidatePosition(parentID, treeType, position)
+}
+
+// IsInDownline reports whether distributorID is ancestorID or sits anywhere
+// below them, and is used to scope access to a distributor's organization
+func (s *treeService) IsInDownline(distributorID, ancestorID uint) (bool, error) {
+	if distributorID == ancestorID {
+		return true, nil
+	}
+	return s.isDescendant(distributorID, ancestorID)
+}
+
+// maxDownlineSearchLimit caps the page size of a downline search
+const maxDownlineSearchLimit = 100
+
+// SearchDownline filters, sorts and paginates the organization under rootID
+func (s *treeService) SearchDownline(rootID uint, filter domain.DownlineFilter) ([]domain.DownlineMember, int64, error) {
+	if filter.Leg != "" && filter.Leg != "left" && filter.Leg != "right" {
+		return nil, 0, errors.New("leg must be left or right")
+	}
+	if filter.MaxDepth > 0 && filter.MinDepth > filter.MaxDepth {
+		return nil, 0, errors.New("min_depth cannot be greater than max_depth")
+	}
+	if filter.JoinedFrom != nil && filter.JoinedTo != nil && filter.JoinedTo.Before(*filter.JoinedFrom) {
+		return nil, 0, errors.New("joined_to cannot be before joined_from")
+	}
+	if filter.Limit <= 0 || filter.Limit > maxDownlineSearchLimit {
+		filter.Limit = maxDownlineSearchLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	
+	return s.distributorRepo.SearchDownline(rootID, filter)
+}
+
+// isDescendant reports whether candidateID sits below ancestorID in either
+// the enrollment or the placement tree
+func (s *treeService) isDescendant(candidateID, ancestorID uint) (bool, error) {
+	for _, parentOf := range []func(*domain.Distributor) *uint{
+		func(d *domain.Distributor) *uint { return d.SponsorID },
+		func(d *domain.Distributor) *uint { return d.PlacementID },
+	} {
+		visited := make(map[uint]bool)
+		currentID := candidateID
+		for !visited[currentID] {
+			visited[currentID] = true
+			
+			current, err := s.distributorRepo.FindByID(currentID)
+			if err != nil {
+				return false, err
+			}
+			
+			parentID := parentOf(current)
+			if parentID == nil {
+				break
+			}
+			if *parentID == ancestorID {
+				return true, nil
+			}
+			currentID = *parentID
+		}
+	}
+	
+	return false, nil
+}
+
+// recomputeLevels rewrites Level for a distributor and its whole enrollment
+// subtree, starting from the given level
+func (s *treeService) recomputeLevels(distributorID uint, level int) error {
+	if err := s.distributorRepo.UpdateLevel(distributorID, level); err != nil {
+		return err
+	}
+	
+	downlines, err := s.distributorRepo.GetDownlines(distributorID)
+	if err != nil {
+		return err
+	}
+	
+	for _, downline := range downlines {
+		if err := s.recomputeLevels(downline.ID, level+1); err != nil {
+			return err
+		}
+	}
+	
+	return nil
+}
+
+func snapshotPlacement(d *domain.Distributor) placementSnapshot {
+	return placementSnapshot{
+		SponsorID:      d.SponsorID,
+		PlacementID:    d.PlacementID,
+		Position:       d.Position,
+		Level:          d.Level,
+		LeftLegVolume:  d.LeftLegVolume,
+		RightLegVolume: d.RightLegVolume,
+	}
+}
+
+// subtreeVolume is the placement volume a node carries into its parent's
+// leg: its own order volume, recorded in PersonalSales by UpdateSales, plus
+// both of its legs
+func subtreeVolume(d *domain.Distributor) float64 {
+	return d.PersonalSales + d.LeftLegVolume + d.RightLegVolume
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mlm-app/backend/internal/config"
+	"github.com/mlm-app/backend/internal/domain"
+	"github.com/mlm-app/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// placementRepo is an in-memory DistributorRepository that enforces the
+// (placement_id, position) unique index the same way the database does
+type placementRepo struct {
+	repository.DistributorRepository
+
+	mu           sync.Mutex
+	nextID       uint
+	distributors map[uint]domain.Distributor
+}
+
+func newPlacementRepo() *placementRepo {
+	return &placementRepo{distributors: make(map[uint]domain.Distributor)}
+}
+
+func (r *placementRepo) Create(distributor *domain.Distributor) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if distributor.PlacementID != nil {
+		for _, existing := range r.distributors {
+			if existing.PlacementID != nil && *existing.PlacementID == *distributor.PlacementID && existing.Position == distributor.Position {
+				return repository.ErrPositionTaken
+			}
+		}
+	}
+
+	r.nextID++
+	distributor.ID = r.nextID
+	r.distributors[distributor.ID] = *distributor
+	return nil
+}
+
+func (r *placementRepo) WithTx(tx *repository.Tx) repository.DistributorRepository {
+	return r
+}
+
+func (r *placementRepo) FindByID(id uint) (*domain.Distributor, error) {
+	distributor, err := r.FindByIDWithDeleted(id)
+	if err != nil || distributor.DeletedAt.Valid {
+		return nil, errors.New("distributor not found")
+	}
+	return distributor, nil
+}
+
+func (r *placementRepo) FindByIDWithDeleted(id uint) (*domain.Distributor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	distributor, ok := r.distributors[id]
+	if !ok {
+		return nil, errors.New("distributor not found")
+	}
+	return &distributor, nil
+}
+
+func (r *placementRepo) FindByEmail(email string) (*domain.Distributor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, distributor := range r.distributors {
+		if distributor.Email == email {
+			return &distributor, nil
+		}
+	}
+	return nil, errors.New("distributor not found")
+}
+
+func (r *placementRepo) GetPlacementChildren(placementID uint) ([]domain.Distributor, error) {
+	children := r.liveWhere(func(d domain.Distributor) bool {
+		return d.PlacementID != nil && *d.PlacementID == placementID
+	})
+
+	// Widen the gap between the slot lookup and the insert so concurrent
+	// registrations really do pick the same slot
+	time.Sleep(time.Millisecond)
+	return children, nil
+}
+
+func (r *placementRepo) GetDownlines(sponsorID uint) ([]domain.Distributor, error) {
+	return r.liveWhere(func(d domain.Distributor) bool {
+		return d.SponsorID != nil && *d.SponsorID == sponsorID
+	}), nil
+}
+
+// liveWhere returns the live distributors matching match in ID order
+func (r *placementRepo) liveWhere(match func(domain.Distributor) bool) []domain.Distributor {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var found []domain.Distributor
+	for _, distributor := range r.distributors {
+		if !distributor.DeletedAt.Valid && match(distributor) {
+			found = append(found, distributor)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].ID < found[j].ID })
+	return found
+}
+
+func (r *placementRepo) UpdatePlacement(distributorID uint, sponsorID, placementID *uint, position string, level int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if placementID != nil {
+		for _, existing := range r.distributors {
+			if existing.ID != distributorID && existing.PlacementID != nil && *existing.PlacementID == *placementID && existing.Position == position {
+				return repository.ErrPositionTaken
+			}
+		}
+	}
+
+	distributor := r.distributors[distributorID]
+	distributor.SponsorID = sponsorID
+	distributor.PlacementID = placementID
+	distributor.Position = position
+	distributor.Level = level
+	distributor.PlacementStatus = "placed"
+	r.distributors[distributorID] = distributor
+	return nil
+}
+
+func (r *placementRepo) UpdateLegVolume(distributorID uint, position string, amount float64) error {
+	return r.update(distributorID, func(d *domain.Distributor) {
+		if position == "left" {
+			d.LeftLegVolume += amount
+		} else {
+			d.RightLegVolume += amount
+		}
+	})
+}
+
+func (r *placementRepo) UpdateLevel(distributorID uint, level int) error {
+	return r.update(distributorID, func(d *domain.Distributor) { d.Level = level })
+}
+
+func (r *placementRepo) UpdateStatus(distributorID uint, status, reasonCode string) error {
+	return r.update(distributorID, func(d *domain.Distributor) { d.Status = status })
+}
+
+func (r *placementRepo) Delete(id uint) error {
+	return r.update(id, func(d *domain.Distributor) {
+		d.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	})
+}
+
+func (r *placementRepo) update(id uint, change func(*domain.Distributor)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	distributor, ok := r.distributors[id]
+	if !ok {
+		return errors.New("distributor not found")
+	}
+	change(&distributor)
+	r.distributors[id] = distributor
+	return nil
+}
+
+// inlineTransactor runs fn straight away; the fakes ignore the transaction
+type inlineTransactor struct{}
+
+func (inlineTransactor) Transaction(fn func(tx *repository.Tx) error) error {
+	return fn(&repository.Tx{})
+}
+
+// auditRecorder is an AuditRepository that keeps every entry in memory
+type auditRecorder struct {
+	repository.AuditRepository
+
+	entries []domain.AuditLog
+}
+
+func (r *auditRecorder) WithTx(tx *repository.Tx) repository.AuditRepository {
+	return r
+}
+
+func (r *auditRecorder) Create(entry *domain.AuditLog) error {
+	r.entries = append(r.entries, *entry)
+	return nil
+}
+
+type breakawayRepo struct {
+	repository.BreakawayRepository
+}
+
+func (r breakawayRepo) WithTx(tx *repository.Tx) repository.BreakawayRepository {
+	return r
+}
+
+func TestPlaceMemberConcurrentPlacementNeverDoubleBooksSlot(t *testing.T) {
+	cfg := &config.Config{MLM: config.MLMConfig{MatrixWidth: 3}}
+
+	for _, treeType := range []domain.TreeType{domain.TreeTypeBinary, domain.TreeTypeMatrix, domain.TreeTypeHybrid} {
+		t.Run(string(treeType), func(t *testing.T) {
+			repo := newPlacementRepo()
+			treeService := NewTreeService(repo, nil, nil, nil, cfg)
+
+			root := &domain.Distributor{Email: "root@example.com", TreeType: treeType}
+			if err := repo.Create(root); err != nil {
+				t.Fatal(err)
+			}
+
+			// A member only loses a slot to another member that gets placed,
+			// so with no more signups than attempts none can run out of retries
+			const signups = maxPlacementAttempts
+			var wg sync.WaitGroup
+			errs := make(chan error, signups)
+			for i := 0; i < signups; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					member := &domain.Distributor{
+						FirstName: "Member",
+						LastName:  fmt.Sprint(i),
+						Email:     fmt.Sprintf("member%d@example.com", i),
+						SponsorID: &root.ID,
+						TreeType:  treeType,
+						Level:     1,
+					}
+					errs <- treeService.PlaceMember(member)
+				}(i)
+			}
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				if err != nil {
+					t.Errorf("placement failed: %v", err)
+				}
+			}
+
+			slots := make(map[string]uint)
+			for _, distributor := range repo.distributors {
+				if distributor.ID == root.ID {
+					continue
+				}
+				if distributor.PlacementID == nil {
+					t.Fatalf("distributor %d has no placement parent", distributor.ID)
+				}
+				slot := fmt.Sprintf("%d/%s", *distributor.PlacementID, distributor.Position)
+				if holder, taken := slots[slot]; taken {
+					t.Errorf("slot %s held by both %d and %d", slot, holder, distributor.ID)
+				}
+				slots[slot] = distributor.ID
+			}
+			if len(slots) != signups {
+				t.Errorf("placed %d members, want %d", len(slots), signups)
+			}
+		})
+	}
+}
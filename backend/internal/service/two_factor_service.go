@@ -0,0 +1,293 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/mlm-app/backend/internal/config"
+	"github.com/mlm-app/backend/internal/domain"
+	"github.com/mlm-app/backend/internal/middleware"
+	"github.com/mlm-app/backend/internal/repository"
+	"github.com/mlm-app/backend/pkg/jwtkeys"
+	"github.com/mlm-app/backend/pkg/ratelimit"
+	"github.com/mlm-app/backend/pkg/totp"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// recoveryCodeCount is how many recovery codes each enrollment hands out
+	recoveryCodeCount = 10
+	
+	// totpSkew accepts codes from one 30-second step either side of now
+	totpSkew = 1
+	
+	// maxChallengeAttempts is how many codes one login challenge accepts
+	// before the distributor has to enter their password again
+	maxChallengeAttempts = 5
+)
+
+var (
+	errInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	errInvalidChallenge     = errors.New("invalid or expired challenge")
+)
+
+type TwoFactorService interface {
+	Setup(distributorID uint) (*domain.TwoFactorSetup, error)
+	Enable(distributorID uint, code string) ([]string, error)
+	Disable(distributorID uint, password, code string) error
+	RegenerateRecoveryCodes(distributorID uint, code string) ([]string, error)
+	CompleteLogin(challengeToken, code, recoveryCode, userAgent, ipAddress string) (*domain.TokenPair, error)
+}
+
+type twoFactorService struct {
+	distributorRepo  repository.DistributorRepository
+	recoveryCodeRepo repository.RecoveryCodeRepository
+	authService      AuthService
+	loginSecurity    LoginSecurityService
+	store            ratelimit.Store
+	keys             *jwtkeys.KeySet
+	config           *config.Config
+}
+
+func NewTwoFactorService(
+	distributorRepo repository.DistributorRepository,
+	recoveryCodeRepo repository.RecoveryCodeRepository,
+	authService AuthService,
+	loginSecurity LoginSecurityService,
+	store ratelimit.Store,
+	keys *jwtkeys.KeySet,
+	cfg *config.Config,
+) TwoFactorService {
+	return &twoFactorService{
+		distributorRepo:  distributorRepo,
+		recoveryCodeRepo: recoveryCodeRepo,
+		authService:      authService,
+		loginSecurity:    loginSecurity,
+		store:            store,
+		keys:             keys,
+		config:           cfg,
+	}
+}
+
+// Setup starts enrollment by generating a secret for the authenticator app.
+// 2FA is not active until Enable confirms a code from it.
+func (s *twoFactorService) Setup(distributorID uint) (*domain.TwoFactorSetup, error) {
+	distributor, err := s.distributorRepo.FindByID(distributorID)
+	if err != nil {
+		return nil, err
+	}
+	if distributor.TwoFactorEnabledAt != nil {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.distributorRepo.SetTwoFactorSecret(distributorID, secret); err != nil {
+		return nil, err
+	}
+	
+	return &domain.TwoFactorSetup{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(s.config.Auth.TwoFactorIssuer, distributor.Email, secret),
+	}, nil
+}
+
+// Enable turns on 2FA once the distributor proves their app produces valid
+// codes, and returns their recovery codes. These are shown only once.
+func (s *twoFactorService) Enable(distributorID uint, code string) ([]string, error) {
+	distributor, err := s.distributorRepo.FindByID(distributorID)
+	if err != nil {
+		return nil, err
+	}
+	if distributor.TwoFactorEnabledAt != nil {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	if distributor.TwoFactorSecret == "" {
+		return nil, errors.New("start two-factor setup first")
+	}
+	
+	if err := s.verifyCode(distributor, code); err != nil {
+		return nil, err
+	}
+	if err := s.distributorRepo.EnableTwoFactor(distributorID, time.Now()); err != nil {
+		return nil, err
+	}
+	return s.newRecoveryCodes(distributorID)
+}
+
+// Disable turns 2FA off after checking the password and a current code.
+// Admins cannot disable it.
+func (s *twoFactorService) Disable(distributorID uint, password, code string) error {
+	distributor, err := s.distributorRepo.FindByID(distributorID)
+	if err != nil {
+		return err
+	}
+	if distributor.Role == "admin" {
+		return errors.New("administrators must keep two-factor authentication enabled")
+	}
+	if distributor.TwoFactorEnabledAt == nil {
+		return errors.New("two-factor authentication is not enabled")
+	}
+	
+	if err := bcrypt.CompareHashAndPassword([]byte(distributor.PasswordHash), []byte(password)); err != nil {
+		return errors.New("password is incorrect")
+	}
+	if err := s.verifyCode(distributor, code); err != nil {
+		return err
+	}
+	
+	if err := s.distributorRepo.DisableTwoFactor(distributorID); err != nil {
+		return err
+	}
+	return s.recoveryCodeRepo.DeleteAll(distributorID)
+}
+
+// RegenerateRecoveryCodes replaces every recovery code, used or not
+func (s *twoFactorService) RegenerateRecoveryCodes(distributorID uint, code string) ([]string, error) {
+	distributor, err := s.distributorRepo.FindByID(distributorID)
+	if err != nil {
+		return nil, err
+	}
+	if distributor.TwoFactorEnabledAt == nil {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+	
+	if err := s.verifyCode(distributor, code); err != nil {
+		return nil, err
+	}
+	return s.newRecoveryCodes(distributorID)
+}
+
+// CompleteLogin finishes a two-step login with either a TOTP code or a
+// recovery code and issues the real tokens. Codes go through the same
+// per-account counter and lockout as passwords; a challenge accepts at most
+// maxChallengeAttempts codes and cannot be used again once it has succeeded.
+func (s *twoFactorService) CompleteLogin(challengeToken, code, recoveryCode, userAgent, ipAddress string) (*domain.TokenPair, error) {
+	claims, err := middleware.ParseChallengeToken(challengeToken, s.keys)
+	if err != nil {
+		return nil, err
+	}
+	
+	distributor, err := s.distributorRepo.FindByID(claims.DistributorID)
+	if err != nil {
+		return nil, errInvalidChallenge
+	}
+	if distributor.TokenVersion != claims.TokenVersion || distributor.TwoFactorEnabledAt == nil {
+		return nil, errInvalidChallenge
+	}
+	if distributor.LockedUntil != nil && distributor.LockedUntil.After(time.Now()) {
+		s.loginSecurity.RecordFailure(distributor.Email, ipAddress, userAgent, domain.LoginResultLocked)
+		return nil, ErrAccountLocked
+	}
+	if err := checkAccountStatus(distributor); err != nil {
+		return nil, err
+	}
+	
+	key := challengeKey(challengeToken)
+	if used, err := s.store.Get(key + ":used"); err == nil && used.Count > 0 {
+		return nil, errInvalidChallenge
+	}
+	attempts, err := s.store.Hit(key, s.config.Auth.LoginChallengeExpiry)
+	if err != nil {
+		// Fail open; the account counter and lockout still apply
+		log.Printf("login limiter unavailable: %v", err)
+	} else if attempts.Count > maxChallengeAttempts {
+		s.loginSecurity.RecordFailure(distributor.Email, ipAddress, userAgent, domain.LoginResultThrottled)
+		return nil, errors.New("too many attempts for this challenge, please log in again")
+	}
+	if err := s.loginSecurity.BeginAttempt(distributor.Email); err != nil {
+		s.loginSecurity.RecordFailure(distributor.Email, ipAddress, userAgent, domain.LoginResultThrottled)
+		return nil, err
+	}
+	
+	switch {
+	case code != "":
+		err = s.verifyCode(distributor, code)
+	case recoveryCode != "":
+		err = s.useRecoveryCode(distributor.ID, recoveryCode)
+	default:
+		err = errors.New("a two-factor code or recovery code is required")
+	}
+	if err != nil {
+		s.loginSecurity.RecordFailure(distributor.Email, ipAddress, userAgent, domain.LoginResultInvalidTwoFactor)
+		return nil, err
+	}
+	
+	// Two requests racing with different valid codes both get here; only
+	// the first to mark the challenge used is let through
+	if used, err := s.store.Hit(key+":used", s.config.Auth.LoginChallengeExpiry); err == nil && used.Count > 1 {
+		return nil, errInvalidChallenge
+	}
+	
+	tokens, err := s.authService.IssueTokens(distributor, true, userAgent, ipAddress)
+	if err != nil {
+		return nil, err
+	}
+	s.loginSecurity.RecordSuccess(distributor, ipAddress, userAgent, domain.LoginResultSuccess)
+	return tokens, nil
+}
+
+// verifyCode checks a TOTP code and refuses a code that was already used
+func (s *twoFactorService) verifyCode(distributor *domain.Distributor, code string) error {
+	step, ok := totp.Validate(distributor.TwoFactorSecret, strings.TrimSpace(code), time.Now(), totpSkew)
+	if !ok {
+		return errInvalidTwoFactorCode
+	}
+	
+	fresh, err := s.distributorRepo.ConsumeTwoFactorStep(distributor.ID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return errors.New("two-factor code already used, wait for the next one")
+	}
+	return nil
+}
+
+func (s *twoFactorService) useRecoveryCode(distributorID uint, code string) error {
+	used, err := s.recoveryCodeRepo.Consume(distributorID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return errors.New("invalid recovery code")
+	}
+	return nil
+}
+
+// newRecoveryCodes generates and stores a fresh set of codes formatted as
+// xxxxx-xxxxx
+func (s *twoFactorService) newRecoveryCodes(distributorID uint) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		buf := make([]byte, 7)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf))[:10]
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = hashToken(raw)
+	}
+	
+	if err := s.recoveryCodeRepo.ReplaceAll(distributorID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// challengeKey is the limiter key counting the codes tried on a challenge
+func challengeKey(challengeToken string) string {
+	return "login:challenge:" + hashToken(challengeToken)
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mlm-app/backend/internal/domain"
+	"github.com/mlm-app/backend/pkg/totp"
+)
+
+// totpCode computes the RFC 6238 code for secret at the given step
+func totpCode(t *testing.T, secret string, step int64) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1000000)
+}
+
+func TestVerifyCodeRefusesReplay(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	distributor := domain.Distributor{ID: 1, TwoFactorSecret: secret}
+	repo := newAuthRepo(distributor)
+	s := &twoFactorService{distributorRepo: repo}
+
+	step := time.Now().Unix() / 30
+	current := totpCode(t, secret, step)
+	previous := totpCode(t, secret, step-1)
+
+	if err := s.verifyCode(&distributor, current); err != nil {
+		t.Fatalf("first use of a valid code: %v", err)
+	}
+	if err := s.verifyCode(&distributor, current); err == nil {
+		t.Error("the same code was accepted twice")
+	}
+
+	// A code from an earlier step is still within the skew window, but
+	// accepting it would let an older intercepted code through
+	if err := s.verifyCode(&distributor, previous); err == nil {
+		t.Error("a code older than the last accepted one was accepted")
+	}
+
+	wrong := totpCode(t, secret, step+100)
+	if err := s.verifyCode(&distributor, wrong); !errors.Is(err, errInvalidTwoFactorCode) {
+		t.Errorf("code outside the window: err = %v, want errInvalidTwoFactorCode", err)
+	}
+}
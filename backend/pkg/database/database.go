@@ -1,8 +1,10 @@
 package database
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/mlm-app/backend/internal/config"
 	"github.com/mlm-app/backend/internal/domain"
@@ -35,6 +37,15 @@ func InitDB(cfg *config.Config) (*gorm.DB, error) {
 func AutoMigrate(db *gorm.DB) error {
 	log.Println("Running database migrations...")
 	
+	if err := migrateLegacyPackages(db); err != nil {
+		return fmt.Errorf("failed to migrate packages: %w", err)
+	}
+	
+	// Accounts from before email verification existed are treated as
+	// verified, so requiring verification does not lock them out
+	backfillVerification := db.Migrator().HasTable(&domain.Distributor{}) &&
+		!db.Migrator().HasColumn(&domain.Distributor{}, "EmailVerifiedAt")
+	
 	err := db.AutoMigrate(
 		&domain.Distributor{},
 		&domain.Rank{},
@@ -46,16 +57,91 @@ func AutoMigrate(db *gorm.DB) error {
 		&domain.Commission{},
 		&domain.RankAchievement{},
 		&domain.Payout{},
+		&domain.BreakawayEvent{},
+		&domain.AuditLog{},
+		&domain.Termination{},
+		&domain.TerminationRollUp{},
+		&domain.StatusChange{},
+		&domain.KYCSubmission{},
+		&domain.KYCDocument{},
+		&domain.GenealogySnapshot{},
+		&domain.GenealogySnapshotEntry{},
+		&domain.RefreshToken{},
+		&domain.AccountToken{},
+		&domain.RecoveryCode{},
+		&domain.LoginAttempt{},
+		&domain.APIKey{},
 	)
 	
 	if err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
 	
+	if backfillVerification {
+		err := db.Exec("UPDATE distributors SET email_verified_at = created_at WHERE email_verified_at IS NULL").Error
+		if err != nil {
+			return fmt.Errorf("failed to mark existing accounts verified: %w", err)
+		}
+	}
+	
 	log.Println("Database migrations completed")
 	return nil
 }
 
+// migrateLegacyPackages prepares packages created before versioning: each
+// gets a code derived from its name, the unique index on name is dropped so
+// versions can share a name, and plain-string feature lists become objects
+func migrateLegacyPackages(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&domain.Package{}) {
+		return nil
+	}
+	
+	if migrator.HasIndex(&domain.Package{}, "idx_packages_name") {
+		if err := migrator.DropIndex(&domain.Package{}, "idx_packages_name"); err != nil {
+			return err
+		}
+	}
+	
+	if !migrator.HasColumn(&domain.Package{}, "Code") {
+		if err := migrator.AddColumn(&domain.Package{}, "Code"); err != nil {
+			return err
+		}
+		if err := migrator.AddColumn(&domain.Package{}, "Version"); err != nil {
+			return err
+		}
+		if err := db.Exec("UPDATE packages SET code = LOWER(REPLACE(name, ' ', '_')), version = 1").Error; err != nil {
+			return err
+		}
+	}
+	
+	var rows []struct {
+		ID       uint
+		Features string
+	}
+	if err := db.Table("packages").Select("id, features").Scan(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		var labels []string
+		if json.Unmarshal([]byte(row.Features), &labels) != nil {
+			continue
+		}
+		
+		features := make([]domain.PackageFeature, 0, len(labels))
+		for _, label := range labels {
+			code := strings.ToLower(strings.ReplaceAll(label, " ", "_"))
+			features = append(features, domain.PackageFeature{Code: code, Label: label})
+		}
+		data, _ := json.Marshal(features)
+		if err := db.Table("packages").Where("id = ?", row.ID).Update("features", string(data)).Error; err != nil {
+			return err
+		}
+	}
+	
+	return nil
+}
+
 func SeedData(db *gorm.DB) error {
 	log.Println("Seeding initial data...")
 	
@@ -140,37 +226,61 @@ func SeedData(db *gorm.DB) error {
 	// Seed Packages
 	packages := []domain.Package{
 		{
+			Code:           "starter",
+			Version:        1,
 			Name:           "Starter",
 			Description:    "Perfect for beginners",
 			Price:          99.99,
 			CommissionRate: 10,
 			MaxLevels:      5,
-			Features:       `["Basic training", "5 level commission", "Email support"]`,
-			IsActive:       true,
+			TermMonths:     12,
+			Features: []domain.PackageFeature{
+				{Code: "training", Label: "Basic training"},
+				{Code: "commission_levels", Label: "Level commission", Value: "5 levels"},
+				{Code: "support", Label: "Email support"},
+			},
+			IsActive: true,
 		},
 		{
+			Code:           "professional",
+			Version:        1,
 			Name:           "Professional",
 			Description:    "For serious distributors",
 			Price:          299.99,
 			CommissionRate: 15,
 			MaxLevels:      10,
-			Features:       `["Advanced training", "10 level commission", "Priority support", "Marketing materials"]`,
-			IsActive:       true,
+			TermMonths:     12,
+			Features: []domain.PackageFeature{
+				{Code: "training", Label: "Advanced training"},
+				{Code: "commission_levels", Label: "Level commission", Value: "10 levels"},
+				{Code: "support", Label: "Priority support"},
+				{Code: "marketing_materials", Label: "Marketing materials"},
+			},
+			IsActive: true,
 		},
 		{
+			Code:           "elite",
+			Version:        1,
 			Name:           "Elite",
 			Description:    "Maximum earning potential",
 			Price:          999.99,
 			CommissionRate: 20,
 			MaxLevels:      15,
-			Features:       `["Premium training", "15 level commission", "24/7 support", "Marketing materials", "Personal mentor"]`,
-			IsActive:       true,
+			TermMonths:     12,
+			Features: []domain.PackageFeature{
+				{Code: "training", Label: "Premium training"},
+				{Code: "commission_levels", Label: "Level commission", Value: "15 levels"},
+				{Code: "support", Label: "Support", Value: "24/7"},
+				{Code: "marketing_materials", Label: "Marketing materials"},
+				{Code: "mentor", Label: "Personal mentor"},
+			},
+			IsActive: true,
 		},
 	}
 	
 	for _, pkg := range packages {
 		var existing domain.Package
-		if err := db.Where("name = ?", pkg.Name).First(&existing).Error; err == gorm.ErrRecordNotFound {
+		if err := db.Where("code = ?", pkg.Code).First(&existing).Error; err == gorm.ErrRecordNotFound {
 			if err := db.Create(&pkg).Error; err != nil {
 				return err
 			}
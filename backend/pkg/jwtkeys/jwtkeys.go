@@ -0,0 +1,246 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mlm-app/backend/internal/config"
+)
+
+// insecureSecrets are the placeholder HMAC secrets shipped in the defaults
+// and .env.example; they must never sign tokens in production
+var insecureSecrets = map[string]bool{
+	"":                true,
+	"your-secret-key": true,
+	"your-super-secret-jwt-key-change-this-in-production": true,
+}
+
+// Key is one key of the set. Verification-only keys have no Signer.
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+	Signer interface{} // Private key or HMAC secret
+	Public interface{} // Public key or HMAC secret
+}
+
+// KeySet signs tokens with its active key and verifies tokens signed by any
+// of its keys, so a retired key keeps validating tokens until they expire.
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+	ordered []*Key // Signing key first, then verification keys as configured
+	methods []string
+}
+
+// Load builds the key set from JWT_SIGNING_KEY_FILE and
+// JWT_VERIFICATION_KEY_FILES. Without a signing key it falls back to HS256
+// with JWT_SECRET, which is refused in release mode when the secret is a
+// placeholder. Once a signing key is configured HMAC tokens are no longer
+// accepted at all.
+func Load(cfg *config.Config) (*KeySet, error) {
+	if cfg.JWT.SigningKeyFile == "" {
+		if insecureSecrets[cfg.JWT.Secret] && cfg.Server.GinMode == "release" {
+			return nil, errors.New("refusing to start in release mode with the default JWT secret; set JWT_SIGNING_KEY_FILE or JWT_SECRET")
+		}
+		
+		secret := []byte(cfg.JWT.Secret)
+		key := &Key{Method: jwt.SigningMethodHS256, Signer: secret, Public: secret}
+		set := &KeySet{signing: key, keys: make(map[string]*Key)}
+		set.add(key)
+		return set, nil
+	}
+	
+	signing, err := loadKeyFile(cfg.JWT.SigningKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if signing.Signer == nil {
+		return nil, fmt.Errorf("%s does not contain a private key", cfg.JWT.SigningKeyFile)
+	}
+	
+	set := &KeySet{signing: signing, keys: make(map[string]*Key)}
+	set.add(signing)
+	for _, path := range cfg.JWT.VerificationKeyFiles {
+		key, err := loadKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		set.add(key)
+	}
+	return set, nil
+}
+
+func (s *KeySet) add(key *Key) {
+	if _, ok := s.keys[key.ID]; ok {
+		return
+	}
+	s.keys[key.ID] = key
+	s.ordered = append(s.ordered, key)
+	
+	for _, alg := range s.methods {
+		if alg == key.Method.Alg() {
+			return
+		}
+	}
+	s.methods = append(s.methods, key.Method.Alg())
+}
+
+// Sign signs claims with the active key, naming it in the kid header
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.signing.Method, claims)
+	if s.signing.ID != "" {
+		token.Header["kid"] = s.signing.ID
+	}
+	return token.SignedString(s.signing.Signer)
+}
+
+// Parse verifies tokenString against the key named by its kid header and
+// decodes it into claims. The algorithm must be the one that key is used
+// with, so an RSA public key can never be used as an HMAC secret.
+func (s *KeySet) Parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := s.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return key.Public, nil
+	}, jwt.WithValidMethods(s.methods))
+}
+
+// JWK is a public key in JSON Web Key form
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // OKP curve
+	X   string `json:"x,omitempty"`   // OKP public key
+}
+
+// JWKSet is the document served at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public verification keys. HMAC secrets are never
+// published, so the set is empty in the HS256 fallback.
+func (s *KeySet) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range s.ordered {
+		if jwk, ok := toJWK(key); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+func toJWK(key *Key) (JWK, bool) {
+	jwk := JWK{Kid: key.ID, Alg: key.Method.Alg(), Use: "sig"}
+	switch pub := key.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = b64(pub.N.Bytes())
+		jwk.E = b64(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = b64(pub)
+	default:
+		return JWK{}, false
+	}
+	return jwk, true
+}
+
+// loadKeyFile reads a PEM RSA or Ed25519 key, private or public, and names
+// it by its RFC 7638 thumbprint so kids need no separate configuration
+func loadKeyFile(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", path)
+	}
+	
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	
+	key := &Key{}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.Signer, key.Public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.Signer, key.Public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("%s: only RSA and Ed25519 keys are supported", path)
+	}
+	
+	if rsaKey, ok := key.Public.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("%s: RSA keys must be at least 2048 bits", path)
+	}
+	
+	jwk, _ := toJWK(key)
+	key.ID = thumbprint(jwk)
+	return key, nil
+}
+
+// thumbprint hashes the required members of a JWK in lexicographic order
+func thumbprint(jwk JWK) string {
+	var members interface{}
+	if jwk.Kty == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+	
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+	return b64(sum[:])
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
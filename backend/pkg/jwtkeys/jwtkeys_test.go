@@ -0,0 +1,208 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mlm-app/backend/internal/config"
+)
+
+func claims() jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		Subject:   "42",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+}
+
+// writeKey stores a PEM private key in dir and returns its path
+func writeKey(t *testing.T, dir, name string, key interface{}) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writePublicKey stores a PEM public key in dir and returns its path and bytes
+func writePublicKey(t *testing.T, dir, name string, key interface{}) (string, []byte) {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path, data
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims())
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestLoadRefusesDefaultSecretInRelease(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Server.GinMode = "release"
+	cfg.JWT.Secret = "your-secret-key"
+
+	if _, err := Load(cfg); err == nil {
+		t.Error("Load accepted the placeholder secret in release mode")
+	}
+}
+
+func TestParseHMACFallback(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.JWT.Secret = "test-secret"
+	keys, err := Load(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := keys.Sign(claims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keys.Parse(signed, &jwt.RegisteredClaims{}); err != nil {
+		t.Errorf("own token rejected: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"other HMAC algorithm", sign(t, jwt.SigningMethodHS512, "", []byte("test-secret"))},
+		{"other secret", sign(t, jwt.SigningMethodHS256, "", []byte("other-secret"))},
+		{"unsigned", sign(t, jwt.SigningMethodNone, "", jwt.UnsafeAllowNoneSignatureType)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := keys.Parse(tt.token, &jwt.RegisteredClaims{}); err == nil {
+				t.Error("token accepted")
+			}
+		})
+	}
+}
+
+func TestParsePinsAlgorithmToKey(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, rsaPublicPEM := writePublicKey(t, dir, "rsa.pub", &rsaKey.PublicKey)
+	edPublicPath, _ := writePublicKey(t, dir, "ed.pub", edKey.Public())
+
+	cfg := &config.Config{}
+	cfg.JWT.Secret = "test-secret"
+	cfg.JWT.SigningKeyFile = writeKey(t, dir, "rsa.pem", rsaKey)
+	cfg.JWT.VerificationKeyFiles = []string{edPublicPath}
+	keys, err := Load(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKID := keys.signing.ID
+	edKID := keys.ordered[1].ID
+
+	signed, err := keys.Sign(claims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keys.Parse(signed, &jwt.RegisteredClaims{}); err != nil {
+		t.Errorf("own RS256 token rejected: %v", err)
+	}
+	if _, err := keys.Parse(sign(t, jwt.SigningMethodEdDSA, edKID, edKey), &jwt.RegisteredClaims{}); err != nil {
+		t.Errorf("token from the retired Ed25519 key rejected: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		// The classic key confusion attack: HMAC keyed with the public key
+		{"HS256 keyed with the RSA public key", sign(t, jwt.SigningMethodHS256, rsaKID, rsaPublicPEM)},
+		{"HS256 with the old fallback secret", sign(t, jwt.SigningMethodHS256, "", []byte("test-secret"))},
+		{"RS256 under the Ed25519 kid", sign(t, jwt.SigningMethodRS256, edKID, rsaKey)},
+		{"EdDSA under the RSA kid", sign(t, jwt.SigningMethodEdDSA, rsaKID, edKey)},
+		{"RS512 under the RSA kid", sign(t, jwt.SigningMethodRS512, rsaKID, rsaKey)},
+		{"unknown kid", sign(t, jwt.SigningMethodRS256, "unknown", rsaKey)},
+		{"missing kid", sign(t, jwt.SigningMethodRS256, "", rsaKey)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := keys.Parse(tt.token, &jwt.RegisteredClaims{}); err == nil {
+				t.Error("token accepted")
+			}
+		})
+	}
+}
+
+func TestLoadRejectsShortRSAKeys(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{}
+	cfg.JWT.SigningKeyFile = writeKey(t, t.TempDir(), "short.pem", key)
+	if _, err := Load(cfg); err == nil {
+		t.Error("1024-bit RSA signing key accepted")
+	}
+}
+
+func TestJWKSPublishesOnlyPublicKeys(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.JWT.Secret = "test-secret"
+	keys, err := Load(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(keys.JWKS().Keys); n != 0 {
+		t.Errorf("HMAC fallback published %d keys", n)
+	}
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.JWT.SigningKeyFile = writeKey(t, t.TempDir(), "ed.pem", edKey)
+	keys, err = Load(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := keys.JWKS()
+	if len(set.Keys) != 1 {
+		t.Fatalf("published %d keys, want 1", len(set.Keys))
+	}
+	if jwk := set.Keys[0]; jwk.Kty != "OKP" || jwk.Alg != "EdDSA" || jwk.Kid != keys.signing.ID {
+		t.Errorf("unexpected JWK %+v", jwk)
+	}
+}
@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Entry is a counter within a fixed window that starts at its first hit
+type Entry struct {
+	Count   int
+	LastHit time.Time
+	PrevHit time.Time // Hit before LastHit, zero for the first hit of a window
+	ResetAt time.Time
+}
+
+// Store keeps rate limiting counters. The in-memory store only limits a
+// single server; a shared store such as Redis can implement the same
+// interface for multi-instance deployments.
+type Store interface {
+	// Hit increments the counter for key, starting a new window of the given
+	// length if the previous one has expired
+	Hit(key string, window time.Duration) (Entry, error)
+	// Get returns the counter for key, or a zero Entry if there is none
+	Get(key string) (Entry, error)
+	Reset(key string) error
+}
+
+// sweepInterval is how often MemoryStore drops expired counters
+const sweepInterval = time.Minute
+
+// MemoryStore is a Store held in process memory
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]Entry
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]Entry)}
+}
+
+func (s *MemoryStore) Hit(key string, window time.Duration) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	now := time.Now()
+	s.sweep(now)
+	
+	entry, ok := s.entries[key]
+	if !ok || !now.Before(entry.ResetAt) {
+		entry = Entry{ResetAt: now.Add(window)}
+	}
+	entry.Count++
+	entry.PrevHit = entry.LastHit
+	entry.LastHit = now
+	s.entries[key] = entry
+	return entry, nil
+}
+
+func (s *MemoryStore) Get(key string) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	entry, ok := s.entries[key]
+	if !ok || !time.Now().Before(entry.ResetAt) {
+		return Entry{}, nil
+	}
+	return entry, nil
+}
+
+func (s *MemoryStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	delete(s.entries, key)
+	return nil
+}
+
+// sweep drops expired entries so abandoned keys do not pile up. The caller
+// must hold the lock.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	
+	for key, entry := range s.entries {
+		if !now.Before(entry.ResetAt) {
+			delete(s.entries, key)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStorePath(t *testing.T) {
+	root := t.TempDir()
+	store := &LocalStore{root: root}
+
+	tests := []struct {
+		key  string
+		want string // Relative to root; empty means the key is refused
+	}{
+		{"kyc/1/id_front.png", "kyc/1/id_front.png"},
+		{"kyc/1/../2/selfie.jpg", "kyc/2/selfie.jpg"},
+		{"./kyc/file", "kyc/file"},
+		{"", ""},
+		{"..", ""},
+		{"../secret", ""},
+		{"kyc/../../secret", ""},
+		{"kyc/../../../etc/passwd", ""},
+		{"/etc/passwd", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, err := store.path(tt.key)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("path(%q) = %q, want it refused", tt.key, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("path(%q) refused: %v", tt.key, err)
+			}
+			if want := filepath.Join(root, filepath.FromSlash(tt.want)); got != want {
+				t.Errorf("path(%q) = %q, want %q", tt.key, got, want)
+			}
+		})
+	}
+}
+
+func TestLocalStoreRoundTrip(t *testing.T) {
+	store, err := NewLocalStore(filepath.Join(t.TempDir(), "uploads"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Put("kyc/1/doc.txt", strings.NewReader("passport")); err != nil {
+		t.Fatal(err)
+	}
+	file, err := store.Open("kyc/1/doc.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "passport" {
+		t.Errorf("read %q, want %q", data, "passport")
+	}
+
+	if err := store.Delete("kyc/1/doc.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Open("kyc/1/doc.txt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open after Delete: err = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete("kyc/1/doc.txt"); err != nil {
+		t.Errorf("deleting a missing file: %v", err)
+	}
+}
+
+func TestLocalStoreRefusesEscape(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocalStore(filepath.Join(dir, "uploads"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Put("../outside.txt", strings.NewReader("x")); err == nil {
+		t.Error("Put outside the root succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "outside.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("file written outside the root: %v", err)
+	}
+	if _, err := store.Open("../../etc/passwd"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Open outside the root: err = %v, want the key refused", err)
+	}
+}
@@ -0,0 +1,119 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed from RFC 6238 appendix B, base32 encoded
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateRFCVectors(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+		step int64
+	}{
+		{59, "287082", 1},
+		{1111111109, "081804", 37037036},
+		{1234567890, "005924", 41152263},
+		{2000000000, "279037", 66666666},
+	}
+
+	for _, tt := range tests {
+		step, ok := Validate(rfcSecret, tt.code, time.Unix(tt.unix, 0), 0)
+		if !ok {
+			t.Errorf("Validate(%q) at %d rejected a valid code", tt.code, tt.unix)
+			continue
+		}
+		if step != tt.step {
+			t.Errorf("Validate(%q) at %d returned step %d, want %d", tt.code, tt.unix, step, tt.step)
+		}
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	// 287082 belongs to step 1 (30s to 59s)
+	next := time.Unix(60, 0)
+
+	if _, ok := Validate(rfcSecret, "287082", next, 0); ok {
+		t.Error("code from the previous step accepted without skew")
+	}
+	step, ok := Validate(rfcSecret, "287082", next, 1)
+	if !ok {
+		t.Fatal("code from the previous step rejected with a skew of 1")
+	}
+	if step != 1 {
+		t.Errorf("step = %d, want the code's own step 1", step)
+	}
+	if _, ok := Validate(rfcSecret, "287082", time.Unix(90, 0), 1); ok {
+		t.Error("code two steps old accepted with a skew of 1")
+	}
+}
+
+// The step Validate reports must be the code's own, whatever the time
+// within the skew window, so a caller storing the last accepted step
+// refuses the same code a second time
+func TestValidateReplayReportsSameStep(t *testing.T) {
+	first, ok := Validate(rfcSecret, "287082", time.Unix(45, 0), 1)
+	if !ok {
+		t.Fatal("valid code rejected")
+	}
+	again, ok := Validate(rfcSecret, "287082", time.Unix(75, 0), 1)
+	if !ok {
+		t.Fatal("valid code rejected within the skew window")
+	}
+	if again != first {
+		t.Errorf("replayed code reported step %d, first use reported %d", again, first)
+	}
+}
+
+func TestValidateRejectsMalformedInput(t *testing.T) {
+	now := time.Unix(59, 0)
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+	}{
+		{"wrong code", rfcSecret, "287083"},
+		{"short code", rfcSecret, "28708"},
+		{"long code", rfcSecret, "2870820"},
+		{"empty code", rfcSecret, ""},
+		{"invalid secret", "not base32!", "287082"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := Validate(tt.secret, tt.code, now, 1); ok {
+				t.Errorf("Validate(%q, %q) accepted", tt.secret, tt.code)
+			}
+		})
+	}
+}
+
+func TestValidateNormalizesSecret(t *testing.T) {
+	if _, ok := Validate(" gezdgnbvgy3tqojqgezdgnbvgy3tqojq ", "287082", time.Unix(59, 0), 0); !ok {
+		t.Error("lowercase secret with surrounding spaces rejected")
+	}
+}
+
+func TestGenerateSecretRoundTrips(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := encoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret %q is not base32: %v", secret, err)
+	}
+	if len(key) != 20 {
+		t.Errorf("secret decodes to %d bytes, want 20", len(key))
+	}
+
+	now := time.Now()
+	code := generate(key, now.Unix()/period)
+	if _, ok := Validate(secret, code, now, 0); !ok {
+		t.Error("code generated from a new secret rejected")
+	}
+}